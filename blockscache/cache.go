@@ -0,0 +1,244 @@
+package blockscache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/streamingfast/dstore"
+	"go.uber.org/zap"
+)
+
+// cache is the on disk index of the cached objects, shared by the clones of a Store. Objects read once are kept in
+// the "by age" segment and evicted oldest first, objects read again are promoted to the "recent" segment and evicted
+// least recently used first, so that a long scan of history never evicts the hot ranges.
+type cache struct {
+	// store holds the cached objects on local disk
+	store  dstore.Store
+	logger *zap.Logger
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	recent  *segment
+	byAge   *segment
+	// fills are the objects being downloaded, concurrent reads of the same object waiting for the first one
+	fills map[string]*fill
+}
+
+type segment struct {
+	name     string
+	maxBytes int64
+	bytes    int64
+	// entries are ordered from the most recently added or used to the least
+	entries *list.List
+}
+
+type entry struct {
+	name    string
+	size    int64
+	segment *segment
+}
+
+type fill struct {
+	done chan struct{}
+	err  error
+}
+
+func newCache(store dstore.Store, maxRecentBytes, maxByAgeBytes int64, logger *zap.Logger) *cache {
+	return &cache{
+		store:   store,
+		logger:  logger,
+		entries: map[string]*list.Element{},
+		recent:  &segment{name: "recent", maxBytes: maxRecentBytes, entries: list.New()},
+		byAge:   &segment{name: "by_age", maxBytes: maxByAgeBytes, entries: list.New()},
+		fills:   map[string]*fill{},
+	}
+}
+
+// load indexes the objects cached by a previous run in the "by age" segment, oldest evicted first
+func (c *cache) load(ctx context.Context, dir string) error {
+	// objects whose download was interrupted
+	leftovers, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	if err != nil {
+		return err
+	}
+	for _, leftover := range leftovers {
+		os.Remove(leftover)
+	}
+
+	type cached struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+
+	var objects []cached
+	err = c.store.Walk(ctx, "", func(filename string) error {
+		attributes, err := c.store.ObjectAttributes(ctx, filename)
+		if err != nil {
+			return fmt.Errorf("cached object %q attributes: %w", filename, err)
+		}
+
+		objects = append(objects, cached{name: filename, size: attributes.Size, modTime: attributes.LastModified})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].modTime.Before(objects[j].modTime) })
+	for _, object := range objects {
+		c.add(object.name, object.size)
+	}
+
+	c.logger.Info("loaded blocks cache", zap.Int("object_count", len(c.entries)), zap.Int64("size_bytes", c.byAge.bytes))
+	return nil
+}
+
+// lookup returns true if the object is cached, marking it as used
+func (c *cache) lookup(name string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, found := c.entries[name]
+	if !found {
+		return false
+	}
+
+	e := element.Value.(*entry)
+	if e.segment == c.recent {
+		c.recent.entries.MoveToFront(element)
+		return true
+	}
+
+	c.byAge.remove(element)
+	c.entries[name] = c.recent.push(e)
+	c.evict(c.recent)
+	c.updateSizeMetrics()
+	return true
+}
+
+func (c *cache) contains(name string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_, found := c.entries[name]
+	return found
+}
+
+func (c *cache) add(name string, size int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, found := c.entries[name]; found {
+		return
+	}
+
+	c.entries[name] = c.byAge.push(&entry{name: name, size: size})
+	c.evict(c.byAge)
+	c.updateSizeMetrics()
+}
+
+// remove forgets the object, deleting it from disk
+func (c *cache) remove(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if element, found := c.entries[name]; found {
+		c.delete(element)
+		c.updateSizeMetrics()
+	}
+}
+
+// evict deletes the least recently used objects of the segment until it fits its max size, must be called with the
+// lock held
+func (c *cache) evict(s *segment) {
+	for s.bytes > s.maxBytes && s.entries.Len() != 0 {
+		c.delete(s.entries.Back())
+		Evictions.Inc(s.name)
+	}
+}
+
+// delete must be called with the lock held
+func (c *cache) delete(element *list.Element) {
+	e := element.Value.(*entry)
+	e.segment.remove(element)
+	delete(c.entries, e.name)
+
+	if err := c.store.DeleteObject(context.Background(), e.name); err != nil && !errors.Is(err, dstore.ErrNotFound) {
+		c.logger.Warn("unable to delete cached object", zap.String("name", e.name), zap.Error(err))
+	}
+}
+
+// download writes the object read from `open` to the cache, concurrent downloads of the same object waiting for the
+// first one. It returns true for the call that downloaded the object.
+func (c *cache) download(ctx context.Context, name string, open func(ctx context.Context, name string) (io.ReadCloser, error)) (bool, error) {
+	c.lock.Lock()
+	if f, found := c.fills[name]; found {
+		c.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-f.done:
+			return false, f.err
+		}
+	}
+
+	f := &fill{done: make(chan struct{})}
+	c.fills[name] = f
+	c.lock.Unlock()
+
+	f.err = c.write(ctx, name, open)
+
+	c.lock.Lock()
+	delete(c.fills, name)
+	c.lock.Unlock()
+	close(f.done)
+
+	return true, f.err
+}
+
+func (c *cache) write(ctx context.Context, name string, open func(ctx context.Context, name string) (io.ReadCloser, error)) error {
+	reader, err := open(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if err := c.store.WriteObject(ctx, name, reader); err != nil {
+		return fmt.Errorf("write cached object: %w", err)
+	}
+
+	attributes, err := c.store.ObjectAttributes(ctx, name)
+	if err != nil {
+		return fmt.Errorf("cached object attributes: %w", err)
+	}
+
+	c.add(name, attributes.Size)
+	return nil
+}
+
+// updateSizeMetrics must be called with the lock held
+func (c *cache) updateSizeMetrics() {
+	SizeBytes.SetInt64(c.recent.bytes, c.recent.name)
+	SizeBytes.SetInt64(c.byAge.bytes, c.byAge.name)
+}
+
+func (s *segment) push(e *entry) *list.Element {
+	e.segment = s
+	s.bytes += e.size
+	return s.entries.PushFront(e)
+}
+
+func (s *segment) remove(element *list.Element) {
+	s.bytes -= element.Value.(*entry).size
+	s.entries.Remove(element)
+}
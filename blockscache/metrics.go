@@ -0,0 +1,10 @@
+package blockscache
+
+import "github.com/streamingfast/dmetrics"
+
+var MetricSet = dmetrics.NewSet()
+
+var Hits = MetricSet.NewCounter("blocks_cache_hits", "Number of merged blocks bundles read from the blocks cache")
+var Misses = MetricSet.NewCounter("blocks_cache_misses", "Number of merged blocks bundles not found in the blocks cache and downloaded from the merged blocks store")
+var Evictions = MetricSet.NewCounterVec("blocks_cache_evictions", []string{"segment"}, "Number of merged blocks bundles evicted from the blocks cache")
+var SizeBytes = MetricSet.NewGaugeVec("blocks_cache_size_bytes", []string{"segment"}, "Size in bytes of the merged blocks bundles held by the blocks cache")
@@ -0,0 +1,168 @@
+// Package blockscache caches the merged blocks bundles read from a remote merged blocks store on local disk.
+package blockscache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/encryptedstore"
+	"github.com/streamingfast/firehose-core/metering"
+	"go.uber.org/zap"
+)
+
+// MeteringTier is the merged blocks store tier the bytes read from the cache are metered as
+const MeteringTier = "blocks_cache"
+
+// Store is a merged blocks store reading bundles through a local disk cache. Bundles are immutable so they are never
+// invalidated, only evicted when the cache is full or when they turn out to be corrupted (see [Store.Evict]). Other
+// objects (store metadata, head pointer) are always read from the wrapped store.
+type Store struct {
+	dstore.Store
+
+	cache *cache
+	// cachedStore reads the cached bundles, with the same options as the wrapped store so that reads are metered the
+	// same way whether they hit the cache or not
+	cachedStore dstore.Store
+}
+
+// NewStore returns `mergedBlocksStore` reading bundles through a cache in the local directory `dir`. Bundles read once
+// are kept up to `maxByAgeBytes`, the oldest being evicted first, bundles read again up to `maxRecentBytes`, the least
+// recently used being evicted first. The bundles cached by a previous run are reused.
+//
+// Cached bundles are encrypted with `keys` when not nil, which must be set for an encrypted merged blocks store (see
+// [encryptedstore.KeyringOf]) so that its bundles are not stored in plaintext. Cached bundles that cannot be decrypted
+// (written before encryption was enabled or with a key removed since) are downloaded again.
+func NewStore(ctx context.Context, mergedBlocksStore dstore.Store, dir string, keys *encryptedstore.Keyring, maxRecentBytes, maxByAgeBytes int64, logger *zap.Logger) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create blocks cache directory %q: %w", dir, err)
+	}
+
+	cacheStore, err := newCacheStore(dir, keys)
+	if err != nil {
+		return nil, fmt.Errorf("blocks cache store: %w", err)
+	}
+
+	c := newCache(cacheStore, maxRecentBytes, maxByAgeBytes, logger)
+	if err := c.load(ctx, dir); err != nil {
+		return nil, fmt.Errorf("load blocks cache %q: %w", dir, err)
+	}
+
+	return &Store{
+		Store:       mergedBlocksStore,
+		cache:       c,
+		cachedStore: cacheStore,
+	}, nil
+}
+
+func newCacheStore(dir string, keys *encryptedstore.Keyring) (dstore.Store, error) {
+	if keys == nil {
+		return dstore.NewDBinStore(dir)
+	}
+
+	// encrypted objects do not compress, they are compressed before being encrypted
+	store, err := dstore.NewStore(dir, "dbin.zst", "", false)
+	if err != nil {
+		return nil, err
+	}
+	return encryptedstore.Wrap(store, "zstd", keys), nil
+}
+
+func (s *Store) OpenObject(ctx context.Context, name string) (io.ReadCloser, error) {
+	if !isBundle(name) {
+		return s.Store.OpenObject(ctx, name)
+	}
+
+	if s.cache.lookup(name) {
+		reader, err := s.cachedStore.OpenObject(metering.WithMergedBlocksTier(ctx, MeteringTier), name)
+		if err == nil {
+			Hits.Inc()
+			return reader, nil
+		}
+
+		s.cache.logger.Warn("unable to open cached bundle, downloading it again", zap.String("name", name), zap.Error(err))
+		s.cache.remove(name)
+	}
+
+	Misses.Inc()
+	downloaded, err := s.cache.download(ctx, name, s.Store.OpenObject)
+	if err != nil {
+		if errors.Is(err, dstore.ErrNotFound) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		s.cache.logger.Warn("unable to cache bundle, reading it from the merged blocks store", zap.String("name", name), zap.Error(err))
+		return s.Store.OpenObject(ctx, name)
+	}
+
+	// the bundle was metered while being downloaded, reading it back must not count it twice
+	cachedStore := s.cachedStore
+	if downloaded {
+		cachedStore = s.cache.store
+	}
+
+	reader, err := cachedStore.OpenObject(metering.WithMergedBlocksTier(ctx, MeteringTier), name)
+	if err != nil {
+		// evicted right away, for example when the bundle is bigger than the cache
+		return s.Store.OpenObject(ctx, name)
+	}
+	return reader, nil
+}
+
+// Evict removes bundle `name` from the cache, the next read downloading it again. Used when a cached bundle turns out
+// to be corrupted.
+func (s *Store) Evict(name string) {
+	s.cache.remove(name)
+}
+
+// Unwrap returns the wrapped merged blocks store
+func (s *Store) Unwrap() dstore.Store {
+	return s.Store
+}
+
+func (s *Store) FileExists(ctx context.Context, base string) (bool, error) {
+	if isBundle(base) && s.cache.contains(base) {
+		return true, nil
+	}
+
+	return s.Store.FileExists(ctx, base)
+}
+
+func (s *Store) DeleteObject(ctx context.Context, base string) error {
+	s.cache.remove(base)
+	return s.Store.DeleteObject(ctx, base)
+}
+
+// Clone clones the wrapped store if it's clonable, the clone sharing the cache and reading cached bundles with the
+// same options
+func (s *Store) Clone(ctx context.Context, opts ...dstore.Option) (dstore.Store, error) {
+	clonable, ok := s.Store.(dstore.Clonable)
+	if !ok {
+		return s, nil
+	}
+
+	store, err := clonable.Clone(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	cachedStore, err := s.cache.store.(dstore.Clonable).Clone(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("clone blocks cache store: %w", err)
+	}
+
+	return &Store{
+		Store:       store,
+		cache:       s.cache,
+		cachedStore: cachedStore,
+	}, nil
+}
+
+func isBundle(name string) bool {
+	_, err := strconv.ParseUint(name, 10, 64)
+	return err == nil
+}
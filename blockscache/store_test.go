@@ -0,0 +1,178 @@
+package blockscache
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/encryptedstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// remoteStore is a merged blocks store counting the reads of each object, `release` blocking them when set
+type remoteStore struct {
+	*dstore.MockStore
+
+	lock    sync.Mutex
+	reads   map[string]int
+	release chan struct{}
+}
+
+func newRemoteStore(t *testing.T, names ...string) *remoteStore {
+	s := &remoteStore{MockStore: dstore.NewMockStore(nil), reads: map[string]int{}}
+	for _, name := range names {
+		// incompressible content so that each bundle takes about 1KiB in the cache
+		content := make([]byte, 1000)
+		_, err := rand.Read(content)
+		require.NoError(t, err)
+		s.SetFile(name, content)
+	}
+
+	s.OpenObjectFunc = func(ctx context.Context, name string) (io.ReadCloser, error) {
+		s.lock.Lock()
+		s.reads[name]++
+		release := s.release
+		s.lock.Unlock()
+
+		if release != nil {
+			<-release
+		}
+
+		content, found := s.Files[name]
+		if !found {
+			return nil, dstore.ErrNotFound
+		}
+		return io.NopCloser(bytes.NewReader(content)), nil
+	}
+	return s
+}
+
+func (s *remoteStore) readCount(name string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.reads[name]
+}
+
+func readAll(t *testing.T, store dstore.Store, name string) []byte {
+	t.Helper()
+
+	reader, err := store.OpenObject(context.Background(), name)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return content
+}
+
+func TestStore_CachesBundles(t *testing.T) {
+	remote := newRemoteStore(t, "0000000100", ".head.json")
+	dir := t.TempDir()
+
+	store, err := NewStore(context.Background(), remote, dir, nil, 1<<20, 1<<20, zap.NewNop())
+	require.NoError(t, err)
+
+	assert.Equal(t, remote.Files["0000000100"], readAll(t, store, "0000000100"))
+	assert.Equal(t, remote.Files["0000000100"], readAll(t, store, "0000000100"))
+	assert.Equal(t, 1, remote.readCount("0000000100"))
+
+	// only bundles are cached
+	readAll(t, store, ".head.json")
+	readAll(t, store, ".head.json")
+	assert.Equal(t, 2, remote.readCount(".head.json"))
+
+	_, err = store.OpenObject(context.Background(), "0000000200")
+	assert.ErrorIs(t, err, dstore.ErrNotFound)
+
+	exists, err := store.FileExists(context.Background(), "0000000100")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// bundles cached by a previous run are reused
+	restarted, err := NewStore(context.Background(), remote, dir, nil, 1<<20, 1<<20, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, remote.Files["0000000100"], readAll(t, restarted, "0000000100"))
+	assert.Equal(t, 1, remote.readCount("0000000100"))
+}
+
+func TestStore_EncryptsCachedBundles(t *testing.T) {
+	secret := make([]byte, encryptedstore.KeySize)
+	_, err := rand.Read(secret)
+	require.NoError(t, err)
+	keys, err := encryptedstore.ParseKeyring(hex.EncodeToString(secret))
+	require.NoError(t, err)
+
+	remote := newRemoteStore(t, "0000000100")
+	dir := t.TempDir()
+
+	// a bundle cached before encryption was enabled is downloaded again
+	plain, err := NewStore(context.Background(), remote, dir, nil, 1<<20, 1<<20, zap.NewNop())
+	require.NoError(t, err)
+	readAll(t, plain, "0000000100")
+
+	store, err := NewStore(context.Background(), remote, dir, keys, 1<<20, 1<<20, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, remote.Files["0000000100"], readAll(t, store, "0000000100"))
+	assert.Equal(t, remote.Files["0000000100"], readAll(t, store, "0000000100"))
+	assert.Equal(t, 2, remote.readCount("0000000100"))
+
+	rawStore, err := dstore.NewStore(dir, "dbin.zst", "", false)
+	require.NoError(t, err)
+	assert.NotContains(t, string(readAll(t, rawStore, "0000000100")), string(remote.Files["0000000100"]))
+}
+
+func TestStore_DedupesConcurrentDownloads(t *testing.T) {
+	remote := newRemoteStore(t, "0000000100")
+	remote.release = make(chan struct{})
+
+	store, err := NewStore(context.Background(), remote, t.TempDir(), nil, 1<<20, 1<<20, zap.NewNop())
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, remote.Files["0000000100"], readAll(t, store, "0000000100"))
+		}()
+	}
+
+	require.Eventually(t, func() bool { return remote.readCount("0000000100") == 1 }, time.Second, time.Millisecond)
+	close(remote.release)
+	wg.Wait()
+
+	assert.Equal(t, 1, remote.readCount("0000000100"))
+}
+
+func TestStore_EvictsScannedBundlesFirst(t *testing.T) {
+	remote := newRemoteStore(t, "0000000100", "0000000200", "0000000300", "0000000400", "0000000500")
+
+	// room for two bundles read once and one bundle read again
+	store, err := NewStore(context.Background(), remote, t.TempDir(), nil, 1500, 2500, zap.NewNop())
+	require.NoError(t, err)
+
+	readAll(t, store, "0000000100")
+	readAll(t, store, "0000000100")
+
+	// scanning history does not evict the bundle read again
+	for _, name := range []string{"0000000200", "0000000300", "0000000400", "0000000500"} {
+		readAll(t, store, name)
+	}
+
+	readAll(t, store, "0000000100")
+	assert.Equal(t, 1, remote.readCount("0000000100"))
+
+	readAll(t, store, "0000000500")
+	readAll(t, store, "0000000200")
+	assert.Equal(t, 1, remote.readCount("0000000500"))
+	assert.Equal(t, 2, remote.readCount("0000000200"), "oldest bundle read once evicted")
+}
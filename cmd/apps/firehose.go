@@ -50,6 +50,8 @@ func RegisterFirehoseApp[B firecore.Block](chain *firecore.Chain[B], rootLog *za
 				return nil, err
 			}
 
+			mergedBlocksStoreTierURLs := firecore.GetMergedBlocksStoreTierURLs(runtime.AbsDataDir)
+
 			rawServiceDiscoveryURL := viper.GetString("firehose-discovery-service-url")
 			var serviceDiscoveryURL *url.URL
 			if rawServiceDiscoveryURL != "" {
@@ -78,6 +80,11 @@ func RegisterFirehoseApp[B firecore.Block](chain *firecore.Chain[B], rootLog *za
 				registry.Register(transformer)
 			}
 
+			blocksCacheDir, err := firecore.GetBlocksCacheDir(runtime.AbsDataDir)
+			if err != nil {
+				return nil, err
+			}
+
 			var serverOptions []server.Option
 
 			limiterSize := viper.GetInt("firehose-rate-limit-bucket-size")
@@ -87,14 +94,22 @@ func RegisterFirehoseApp[B firecore.Block](chain *firecore.Chain[B], rootLog *za
 			}
 
 			return firehose.New(appLogger, appTracer, &firehose.Config{
-				MergedBlocksStoreURL:    mergedBlocksStoreURL,
-				OneBlocksStoreURL:       oneBlocksStoreURL,
-				ForkedBlocksStoreURL:    forkedBlocksStoreURL,
-				BlockStreamAddr:         viper.GetString("common-live-blocks-addr"),
-				GRPCListenAddr:          viper.GetString("firehose-grpc-listen-addr"),
-				GRPCShutdownGracePeriod: 1 * time.Second,
-				ServiceDiscoveryURL:     serviceDiscoveryURL,
-				ServerOptions:           serverOptions,
+				MergedBlocksStoreURL:             mergedBlocksStoreURL,
+				MergedBlocksBundleSize:           viper.GetUint64("common-merged-blocks-bundle-size"),
+				OneBlocksStoreURL:                oneBlocksStoreURL,
+				ForkedBlocksStoreURL:             forkedBlocksStoreURL,
+				ForkedBlocksArchiveStoreURL:      firecore.GetForkedBlocksArchiveStoreURL(runtime.AbsDataDir),
+				MergedBlocksSidecarStoreURL:      firecore.GetMergedBlocksSidecarStoreURL(runtime.AbsDataDir),
+				MergedBlocksChecksumVerification: viper.GetString("common-merged-blocks-checksum-verification"),
+				MergedBlocksStoreTierURLs:        mergedBlocksStoreTierURLs,
+				BlocksCacheDir:                   blocksCacheDir,
+				BlocksCacheMaxRecentEntryBytes:   viper.GetInt64("common-blocks-cache-max-recent-entry-bytes"),
+				BlocksCacheMaxEntryByAgeBytes:    viper.GetInt64("common-blocks-cache-max-entry-by-age-bytes"),
+				BlockStreamAddr:                  viper.GetString("common-live-blocks-addr"),
+				GRPCListenAddr:                   viper.GetString("firehose-grpc-listen-addr"),
+				GRPCShutdownGracePeriod:          1 * time.Second,
+				ServiceDiscoveryURL:              serviceDiscoveryURL,
+				ServerOptions:                    serverOptions,
 			}, &firehose.Modules{
 				Authenticator:         authenticator,
 				HeadTimeDriftMetric:   headTimeDriftmetric,
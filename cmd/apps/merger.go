@@ -1,12 +1,14 @@
 package apps
 
 import (
+	"os"
 	"time"
 
 	firecore "github.com/streamingfast/firehose-core"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/streamingfast/cli"
 	"github.com/streamingfast/firehose-core/launcher"
 	"github.com/streamingfast/firehose-core/merger/app/merger"
 	"go.uber.org/zap"
@@ -19,11 +21,48 @@ func RegisterMergerApp(rootLog *zap.Logger) {
 		Description: "Produces merged block files from single-block files",
 		RegisterFlags: func(cmd *cobra.Command) error {
 			cmd.Flags().String("merger-grpc-listen-addr", firecore.MergerServingAddr, "Address to listen for incoming gRPC requests")
+			cmd.Flags().String("merger-http-listen-addr", "", "Address to listen for the status HTTP API (GET /v1/status, POST /v1/prune), disabled when empty. The API is not authenticated, bind it to a private interface (e.g. 127.0.0.1:10013). The same API is served over gRPC on 'merger-grpc-listen-addr' (service sf.firehose.merger.v1.MergerStatus)")
 			cmd.Flags().Uint64("merger-prune-forked-blocks-after", 50000, "Number of blocks that must pass before we delete old forks (one-block-files lingering)")
 			cmd.Flags().Uint64("merger-stop-block", 0, "If non-zero, merger will trigger shutdown when blocks have been merged up to this block")
 			cmd.Flags().Duration("merger-time-between-store-lookups", 1*time.Second, "Delay between source store polling (should be higher for remote storage)")
 			cmd.Flags().Duration("merger-time-between-store-pruning", time.Minute, "Delay between source store pruning loops")
 			cmd.Flags().Int("merger-delete-threads", 8, "Number of threads for deleting files in parallel (increase this in case the merger isn't able to keep up with deleting one-block files).")
+			cmd.Flags().Int("merger-catch-up-parallelism", 1, cli.FlagDescription(`
+				Number of bundles merged concurrently while the merger is far behind head (last block of the bundle older than
+				'merger-catch-up-live-threshold'), for example after an outage. Fork resolution is still done block by block and
+				merged bundles are committed in order (pruning never goes past a bundle not merged yet). 1 disables catch-up merging.
+			`))
+			cmd.Flags().Duration("merger-catch-up-live-threshold", 15*time.Minute, "Block age under which the merger considers it is close to head and goes back to merging one bundle at a time, see 'merger-catch-up-parallelism'")
+			cmd.Flags().StringSlice("merger-mirror-merged-blocks-store-urls", nil, cli.FlagDescription(`
+				Stores receiving a copy of each merged bundle written to 'common-merged-blocks-store-url'. Each mirror has its own
+				queue retrying failed copies in block order, a mirror being down never blocks merging.
+			`))
+			cmd.Flags().String("merger-mirror-deletion-policy", "primary", cli.FlagDescription(`
+				When one-block files of a merged bundle can be deleted: 'primary' once the bundle is in 'common-merged-blocks-store-url',
+				'all' once it has also been copied to all the 'merger-mirror-merged-blocks-store-urls'
+			`))
+			cmd.Flags().Uint64("merger-mirror-reconcile-window", 1_000_000, "Number of blocks before the first bundle to merge checked at startup for bundles missing from the mirror stores (copied from the primary store), 0 to disable")
+			cmd.Flags().Duration("merger-writer-lock-ttl", time.Minute, cli.FlagDescription(`
+				TTL of the lease object the merger must hold to merge bundles, fill holes and prune files, preventing two mergers (for
+				example during a botched rollout) from writing to the same stores. The lease is acquired at startup and renewed every
+				third of its TTL, a merger not holding it waits for it to expire. Set to 0 to disable.
+			`))
+			cmd.Flags().String("merger-writer-lock-store-url", "", "Store URL holding the merger writer lock lease object, must be shared by all the mergers of the same stores. Defaults to '<common-merged-blocks-store-url>-leases'")
+			cmd.Flags().String("merger-writer-lock-holder", "", "Identity of this merger in the merger writer lock, defaults to '<hostname>-<pid>'")
+			cmd.Flags().Duration("merger-hole-detection-timeout", 5*time.Minute, cli.FlagDescription(`
+				Time without progress on the current bundle after which the merger looks for missing one-block files (missing block
+				numbers or blocks whose parent has no one-block file) and reports them, checking again at the same interval while
+				the bundle is stalled. Set to 0 to disable.
+			`))
+			cmd.Flags().String("merger-hole-fill-firehose-endpoint", "", cli.FlagDescription(`
+				Peer Firehose endpoint (must return block metadata) from which blocks found missing by hole detection are fetched.
+				Fetched blocks are validated against the blocks around them and written to the one-block store.
+			`))
+			cmd.Flags().String("merger-hole-fill-firehose-api-key-env-var", "FIREHOSE_API_KEY", "Look for an API key in this environment variable to authenticate against 'merger-hole-fill-firehose-endpoint'")
+			cmd.Flags().String("merger-hole-fill-firehose-api-token-env-var", "FIREHOSE_API_TOKEN", "Look for a JWT in this environment variable to authenticate against 'merger-hole-fill-firehose-endpoint' (alternative to the API key)")
+			cmd.Flags().Bool("merger-hole-fill-firehose-plaintext", false, "Use a plaintext connection to 'merger-hole-fill-firehose-endpoint'")
+			cmd.Flags().Bool("merger-hole-fill-firehose-insecure", false, "Skip TLS certificate verification when connecting to 'merger-hole-fill-firehose-endpoint'")
+			cmd.Flags().String("merger-hole-fill-one-block-store-url", "", "Secondary one-block store from which blocks found missing by hole detection are fetched (tried before 'merger-hole-fill-firehose-endpoint')")
 			return nil
 		},
 		FactoryFunc: func(runtime *launcher.Runtime) (launcher.App, error) {
@@ -32,16 +71,40 @@ func RegisterMergerApp(rootLog *zap.Logger) {
 				return nil, err
 			}
 
+			var mirrorStoreURLs []string
+			for _, storeURL := range viper.GetStringSlice("merger-mirror-merged-blocks-store-urls") {
+				mirrorStoreURLs = append(mirrorStoreURLs, firecore.MustReplaceDataDir(runtime.AbsDataDir, storeURL))
+			}
+
 			return merger.New(&merger.Config{
-				GRPCListenAddr:               viper.GetString("merger-grpc-listen-addr"),
-				PruneForkedBlocksAfter:       viper.GetUint64("merger-prune-forked-blocks-after"),
-				StorageOneBlockFilesPath:     oneBlocksStoreURL,
-				StorageMergedBlocksFilesPath: mergedBlocksStoreURL,
-				StorageForkedBlocksFilesPath: forkedBlocksStoreURL,
-				StopBlock:                    viper.GetUint64("merger-stop-block"),
-				TimeBetweenPruning:           viper.GetDuration("merger-time-between-store-pruning"),
-				TimeBetweenPolling:           viper.GetDuration("merger-time-between-store-lookups"),
-				FilesDeleteThreads:           viper.GetInt("merger-delete-threads"),
+				GRPCListenAddr:                  viper.GetString("merger-grpc-listen-addr"),
+				HTTPListenAddr:                  viper.GetString("merger-http-listen-addr"),
+				PruneForkedBlocksAfter:          viper.GetUint64("merger-prune-forked-blocks-after"),
+				StorageOneBlockFilesPath:        oneBlocksStoreURL,
+				StorageMergedBlocksFilesPath:    mergedBlocksStoreURL,
+				StorageForkedBlocksFilesPath:    forkedBlocksStoreURL,
+				StorageForkedBlocksArchivePath:  firecore.GetForkedBlocksArchiveStoreURL(runtime.AbsDataDir),
+				StorageMergedBlocksSidecarsPath: firecore.GetMergedBlocksSidecarStoreURL(runtime.AbsDataDir),
+				StopBlock:                       viper.GetUint64("merger-stop-block"),
+				TimeBetweenPruning:              viper.GetDuration("merger-time-between-store-pruning"),
+				TimeBetweenPolling:              viper.GetDuration("merger-time-between-store-lookups"),
+				FilesDeleteThreads:              viper.GetInt("merger-delete-threads"),
+				BundleSize:                      viper.GetUint64("common-merged-blocks-bundle-size"),
+				CatchUpParallelism:              viper.GetInt("merger-catch-up-parallelism"),
+				CatchUpLiveThreshold:            viper.GetDuration("merger-catch-up-live-threshold"),
+				MirrorMergedBlocksStoreURLs:     mirrorStoreURLs,
+				MirrorDeletionPolicy:            viper.GetString("merger-mirror-deletion-policy"),
+				MirrorReconcileWindow:           viper.GetUint64("merger-mirror-reconcile-window"),
+				WriterLockTTL:                   viper.GetDuration("merger-writer-lock-ttl"),
+				WriterLockStoreURL:              firecore.MustReplaceDataDir(runtime.AbsDataDir, viper.GetString("merger-writer-lock-store-url")),
+				WriterLockHolder:                viper.GetString("merger-writer-lock-holder"),
+				HoleDetectionTimeout:            viper.GetDuration("merger-hole-detection-timeout"),
+				HoleFillFirehoseEndpoint:        viper.GetString("merger-hole-fill-firehose-endpoint"),
+				HoleFillFirehoseAPIKey:          os.Getenv(viper.GetString("merger-hole-fill-firehose-api-key-env-var")),
+				HoleFillFirehoseAPIToken:        os.Getenv(viper.GetString("merger-hole-fill-firehose-api-token-env-var")),
+				HoleFillFirehosePlaintext:       viper.GetBool("merger-hole-fill-firehose-plaintext"),
+				HoleFillFirehoseInsecure:        viper.GetBool("merger-hole-fill-firehose-insecure"),
+				HoleFillOneBlocksStoreURL:       viper.GetString("merger-hole-fill-one-block-store-url"),
 			}), nil
 		},
 	})
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"time"
 
@@ -13,10 +14,13 @@ import (
 	"github.com/streamingfast/bstream/blockstream"
 	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
 	"github.com/streamingfast/cli"
+	"github.com/streamingfast/dstore"
 	firecore "github.com/streamingfast/firehose-core"
+	"github.com/streamingfast/firehose-core/internal/lease"
 	"github.com/streamingfast/firehose-core/launcher"
 	nodeManager "github.com/streamingfast/firehose-core/node-manager"
 	nodeManagerApp "github.com/streamingfast/firehose-core/node-manager/app/node_manager"
+	logplugin "github.com/streamingfast/firehose-core/node-manager/log_plugin"
 	"github.com/streamingfast/firehose-core/node-manager/metrics"
 	reader "github.com/streamingfast/firehose-core/node-manager/mindreader"
 	"github.com/streamingfast/firehose-core/node-manager/operator"
@@ -42,6 +46,18 @@ func RegisterReaderNodeApp[B firecore.Block](chain *firecore.Chain[B], rootLog *
 			`))
 			cmd.Flags().String("reader-node-data-dir", "{data-dir}/reader/data", "Directory for node data")
 			cmd.Flags().Bool("reader-node-debug-firehose-logs", false, "[DEV] Prints firehose instrumentation logs to standard output, should be use for debugging purposes only")
+			cmd.Flags().String("reader-node-log-format", "console", cli.FlagDescription(`
+				Format of the node logs, 'console' prints node log lines as-is to standard output. The 'json' (one JSON object per line),
+				'logfmt' ('key=value' pairs) and 'regex' (see 'reader-node-log-regex') formats parse each line and log it through our
+				logger with structured fields: the level, message, module and timestamp (as 'node_time') are extracted from the
+				usual keys ('level'/'lvl'/'severity', 'msg'/'message', 'module'/'logger'/'component'/'target', 'time'/'ts'/'timestamp'/'t')
+				and remaining keys are added as extra fields. Lines that cannot be parsed are logged as-is at info level.
+			`))
+			cmd.Flags().String("reader-node-log-regex", "", cli.FlagDescription(`
+				Regular expression used to parse node log lines when 'reader-node-log-format' is 'regex', named groups are extracted as
+				fields, groups named 'level', 'time', 'module' and 'msg' are used for the associated log element.
+				Example: '^(?P<level>[A-Z]+) \[(?P<time>[^\]]+)\] (?P<msg>.*)$'
+			`))
 			cmd.Flags().String("reader-node-manager-api-addr", firecore.ReaderNodeManagerAPIAddr, "Acme node manager API address")
 			cmd.Flags().Duration("reader-node-readiness-max-latency", 30*time.Second, "Determine the maximum head block latency at which the instance will be determined healthy. Some chains have more regular block production than others.")
 			cmd.Flags().String("reader-node-arguments", "", string(cli.Description(`
@@ -55,14 +71,78 @@ func RegisterReaderNodeApp[B firecore.Block](chain *firecore.Chain[B], rootLog *
 
 				Example: 'run blockchain -start {start-block-num} -end {stop-block-num}' may yield 'run blockchain -start 200 -end 500'
 			`)))
+			cmd.Flags().Bool("reader-node-log-file-enabled", false, "Writes the raw node output to a 'node.log' file under '<reader-node-working-dir>/node-logs', rotated and pruned according to the 'reader-node-log-file-*' flags")
+			cmd.Flags().Uint64("reader-node-log-file-max-size", 100*1024*1024, "Size in bytes at which the node log file is rotated, 0 disables size based rotation")
+			cmd.Flags().Duration("reader-node-log-file-rotation-interval", 24*time.Hour, "Time after which the node log file is rotated, 0 disables time based rotation")
+			cmd.Flags().Bool("reader-node-log-file-compress", true, "Compress rotated node log files with gzip")
+			cmd.Flags().Int("reader-node-log-file-max-files", 10, "Maximum number of rotated node log files kept, oldest ones are deleted first, 0 means no limit")
+			cmd.Flags().Duration("reader-node-log-file-max-age", 7*24*time.Hour, "Rotated node log files older than this are deleted, 0 means no limit")
+			cmd.Flags().StringArray("reader-node-log-alert", nil, cli.FlagDescription(`
+				Repeatable, alert rule of the form '<name>:<action>:<regex>' matched against every node log line. Each match increments
+				the 'node_log_pattern_matches' metric (labelled by rule name and action) and performs the action which is one of 'none',
+				'not-ready' (health check reports not ready for 'reader-node-log-alert-not-ready-duration'), 'restart' (restarts the node
+				process) or 'maintenance' (stops the node process until resumed). Example: 'db_corruption:restart:(?i)database corruption'
+			`))
+			cmd.Flags().Duration("reader-node-log-alert-not-ready-duration", 5*time.Minute, "How long the node is reported not ready after a 'not-ready' log alert rule matched, extended by each new match")
+			cmd.Flags().Duration("reader-node-log-alert-action-cooldown", 5*time.Minute, "Minimum time between two actions performed for the same log alert rule, matches are still counted in between")
 			cmd.Flags().StringSlice("reader-node-backups", []string{}, "Repeatable, space-separated key=values definitions for backups. Example: 'type=gke-pvc-snapshot prefix= tag=v1 freq-blocks=1000 freq-time= project=myproj'")
 			cmd.Flags().String("reader-node-grpc-listen-addr", firecore.ReaderNodeGRPCAddr, "The gRPC listening address to use for serving real-time blocks")
 			cmd.Flags().Bool("reader-node-discard-after-stop-num", false, "Ignore remaining blocks being processed after stop num (only useful if we discard the reader data after reprocessing a chunk of blocks)")
-			cmd.Flags().String("reader-node-working-dir", "{data-dir}/reader/work", "Path where reader will stores its files")
+			cmd.Flags().String("reader-node-working-dir", "{data-dir}/reader/work", "Path where reader will stores its files, one-block files and spilled blocks are stored there unencrypted even when the one-block store is encrypted")
 			cmd.Flags().Uint("reader-node-start-block-num", 0, "Blocks that were produced with smaller block number then the given block num are skipped")
 			cmd.Flags().Uint("reader-node-stop-block-num", 0, "Shutdown reader when we the following 'stop-block-num' has been reached, inclusively.")
 			cmd.Flags().Int("reader-node-blocks-chan-capacity", 100, "Capacity of the channel holding blocks read by the reader. Process will shutdown reader-node if the channel gets over 90% of that capacity to prevent horrible consequences. Raise this number when processing tiny blocks very quickly")
+			cmd.Flags().Uint64("reader-node-spill-max-bytes", 0, cli.FlagDescription(`
+				When non-zero, blocks that do not fit in the blocks channel (see 'reader-node-blocks-chan-capacity') are spilled to disk under
+				'<reader-node-working-dir>/spill' instead of applying backpressure on the node, and drained in order once the one-block store
+				catches up. The reader node shuts down only when the spilled blocks would exceed this size in bytes. Spilled blocks left by a
+				previous run are drained first on startup. Spilled blocks are stored unencrypted.
+			`))
+			cmd.Flags().Int("reader-node-one-block-upload-workers", reader.DefaultUploadOptions.Workers, "Number of one-block files uploaded concurrently to the one-block store")
+			cmd.Flags().Int("reader-node-one-block-upload-max-attempts", reader.DefaultUploadOptions.MaxAttempts, "Number of times a one-block file upload is attempted before giving up on it until the next upload pass")
+			cmd.Flags().Duration("reader-node-one-block-upload-retry-backoff", reader.DefaultUploadOptions.RetryBackoff, "Delay before retrying a failed one-block file upload, doubled at each subsequent attempt (capped at 30s)")
+			cmd.Flags().Bool("reader-node-one-block-upload-direct", false, cli.FlagDescription(`
+				When set, one-block files are uploaded straight from memory to the one-block store instead of being written to
+				'<reader-node-working-dir>/uploadable-oneblock' first. Files failing all upload attempts are written to the local
+				directory and uploaded from there.
+			`))
+			cmd.Flags().Bool("reader-node-catch-up-merged-blocks", false, cli.FlagDescription(`
+				When set, blocks below LIB are accumulated into bundles (of the merged blocks store's bundle size) and written straight to 'common-merged-blocks-store-url'
+				instead of one-block files, removing the need for the merger while reprocessing historical blocks. The reader switches
+				to one-block files (and the merger takes over) once blocks are younger than 'reader-node-catch-up-live-threshold'.
+				Blocks before the first bundle boundary are always written as one-block files. Like merged bundles, bundles get their
+				sidecar in 'common-merged-blocks-sidecar-store-url' (if set) and move the merged blocks store head pointer.
+			`))
+			cmd.Flags().Duration("reader-node-catch-up-live-threshold", 15*time.Minute, "Block age under which the reader considers it reached the live segment and switches from merged bundles to one-block files, see 'reader-node-catch-up-merged-blocks'")
+			cmd.Flags().Bool("reader-node-leader-election", false, cli.FlagDescription(`
+				When set, redundant reader nodes elect a leader through a lease object and only the leader uploads one-block files,
+				followers keep serving live blocks over gRPC and take over once the leader's lease expires. Each reader node must use
+				a distinct 'reader-node-one-block-suffix', used as the lease holder identity.
+			`))
+			cmd.Flags().String("reader-node-leader-election-store-url", "", cli.FlagDescription(`
+				Store URL holding the leader election lease object, must be shared by all the reader nodes. Defaults to
+				'<common-one-block-store-url>-leases' (the lease object cannot live among one-block files as the merger and
+				relayer expect every object there to be a one-block file).
+			`))
+			cmd.Flags().Duration("reader-node-leader-election-lease-ttl", 30*time.Second, "Duration of the leader lease, renewed every third of it by the leader, a follower takes over at most 'ttl + ttl/3' after the leader stopped renewing it")
+			cmd.Flags().Uint64("reader-node-leader-election-follower-retained-blocks", 1000, "Number of most recent one-block files followers keep locally to upload them in case they take over, older ones are deleted")
+			cmd.Flags().Bool("reader-node-dry-run", false, cli.FlagDescription(`
+				When set, blocks read from the node are decoded against the chain's block type and checked for continuity but are
+				neither written to stores nor served over gRPC. A summary report (block count, rate, decode errors, continuity issues)
+				is logged periodically, served at '/v1/dry_run_report' on the manager API and written to
+				'<reader-node-working-dir>/dry-run-report.json' on exit. Also applies to 'reader-node-stdin'.
+			`))
 			cmd.Flags().Uint64("reader-node-line-buffer-size", 209715200, "Capacity of the buffer for reading a single line out of the node, in bytes (This is a hard limit. Some future enormouse blocks may require raising this to process them).")
+			cmd.Flags().Duration("reader-node-stall-output-timeout", 0, cli.FlagDescription(`
+				When non-zero, the node process is considered stalled if it did not print any line (stdout or stderr) for this
+				duration, the last log lines are then dumped and the action defined by 'reader-node-stall-action' is performed.
+			`))
+			cmd.Flags().Duration("reader-node-stall-block-timeout", 0, cli.FlagDescription(`
+				When non-zero, the node process is considered stalled if it did not print any 'FIRE BLOCK' line for this duration,
+				the last log lines are then dumped and the action defined by 'reader-node-stall-action' is performed. Keep in mind
+				that a node can take a while to print its first block on startup.
+			`))
+			cmd.Flags().String("reader-node-stall-action", string(operator.StallActionRestart), "Action performed when the node process is detected as stalled, either 'restart' (restarts the node process) or 'shutdown' (shuts down the reader node)")
 			cmd.Flags().String("reader-node-one-block-suffix", "default", cli.FlagDescription(`
 				Unique identifier for reader, so that it can produce 'oneblock files' in the same store as another instance without competing
 				for writes. You should set this flag if you have multiple reader running, each one should get a unique identifier, the
@@ -89,6 +169,20 @@ func RegisterReaderNodeApp[B firecore.Block](chain *firecore.Chain[B], rootLog *
 			httpAddr := viper.GetString("reader-node-manager-api-addr")
 			backupConfigs := viper.GetStringSlice("reader-node-backups")
 
+			var logAlertRules []*logplugin.LogPatternRule
+			for _, in := range viper.GetStringSlice("reader-node-log-alert") {
+				rule, err := logplugin.ParseLogPatternRule(in)
+				if err != nil {
+					return nil, fmt.Errorf("invalid 'reader-node-log-alert' value: %w", err)
+				}
+				logAlertRules = append(logAlertRules, rule)
+			}
+
+			stallAction, err := operator.ParseStallAction(viper.GetString("reader-node-stall-action"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid 'reader-node-stall-action' value: %w", err)
+			}
+
 			backupModules, backupSchedules, err := operator.ParseBackupConfigs(appLogger, backupConfigs, map[string]operator.BackupModuleFactory{
 				"gke-pvc-snapshot": gkeSnapshotterFactory,
 			})
@@ -136,7 +230,11 @@ func RegisterReaderNodeApp[B firecore.Block](chain *firecore.Chain[B], rootLog *
 			lineBufferSize := viper.GetUint64("reader-node-line-buffer-size")
 
 			superviser := sv.SupervisorFactory(chain.ExecutableName, nodePath, nodeArguments, lineBufferSize, appLogger)
-			superviser.RegisterLogPlugin(sv.NewNodeLogPlugin(debugFirehose))
+			nodeLogPlugin, err := newNodeLogPlugin(viper.GetString("reader-node-log-format"), viper.GetString("reader-node-log-regex"), debugFirehose, appLogger)
+			if err != nil {
+				return nil, err
+			}
+			superviser.RegisterLogPlugin(nodeLogPlugin)
 
 			var bootstrapper operator.Bootstrapper
 			if chain.ReaderNodeBootstrapperFactory != nil {
@@ -154,11 +252,25 @@ func RegisterReaderNodeApp[B firecore.Block](chain *firecore.Chain[B], rootLog *
 					ShutdownDelay:              shutdownDelay,
 					EnableSupervisorMonitoring: true,
 					Bootstrapper:               bootstrapper,
+					StallDetection: &operator.StallDetectionOptions{
+						OutputTimeout: viper.GetDuration("reader-node-stall-output-timeout"),
+						BlockTimeout:  viper.GetDuration("reader-node-stall-block-timeout"),
+						Action:        stallAction,
+					},
 				})
 			if err != nil {
 				return nil, fmt.Errorf("unable to create chain operator: %w", err)
 			}
 
+			if len(logAlertRules) > 0 {
+				superviser.RegisterLogPlugin(logplugin.NewLogPatternAlertPlugin(
+					logAlertRules,
+					viper.GetDuration("reader-node-log-alert-action-cooldown"),
+					chainOperator.LogPatternActionHandler(viper.GetDuration("reader-node-log-alert-not-ready-duration")),
+					appLogger,
+				))
+			}
+
 			for name, mod := range backupModules {
 				appLogger.Info("registering backup module", zap.String("name", name), zap.Any("module", mod))
 				err := chainOperator.RegisterBackupModule(name, mod)
@@ -180,6 +292,23 @@ func RegisterReaderNodeApp[B firecore.Block](chain *firecore.Chain[B], rootLog *
 			oneBlockFileSuffix := viper.GetString("reader-node-one-block-suffix")
 			blocksChanCapacity := viper.GetInt("reader-node-blocks-chan-capacity")
 
+			if viper.GetBool("reader-node-log-file-enabled") {
+				fileLogPlugin, err := logplugin.NewToFileLogPlugin(
+					filepath.Join(workingDir, "node-logs"),
+					debugFirehose,
+					appLogger,
+					logplugin.ToFileLogPluginMaxSize(viper.GetUint64("reader-node-log-file-max-size")),
+					logplugin.ToFileLogPluginRotationInterval(viper.GetDuration("reader-node-log-file-rotation-interval")),
+					logplugin.ToFileLogPluginCompress(viper.GetBool("reader-node-log-file-compress")),
+					logplugin.ToFileLogPluginRetention(viper.GetInt("reader-node-log-file-max-files"), viper.GetDuration("reader-node-log-file-max-age")),
+				)
+				if err != nil {
+					return nil, fmt.Errorf("new node log file plugin: %w", err)
+				}
+
+				superviser.RegisterLogPlugin(fileLogPlugin)
+			}
+
 			readerPlugin, err := reader.NewMindReaderPlugin(
 				oneBlocksStoreURL,
 				workingDir,
@@ -202,6 +331,49 @@ func RegisterReaderNodeApp[B firecore.Block](chain *firecore.Chain[B], rootLog *
 				return nil, fmt.Errorf("new reader plugin: %w", err)
 			}
 
+			if spillMaxBytes := viper.GetUint64("reader-node-spill-max-bytes"); spillMaxBytes > 0 {
+				readerPlugin.EnableSpillQueue(spillMaxBytes)
+			}
+
+			if viper.GetBool("reader-node-catch-up-merged-blocks") {
+				mergedBlocksStoreURL, _, _, err := firecore.GetCommonStoresURLs(sfDataDir)
+				if err != nil {
+					return nil, fmt.Errorf("get common stores URLs: %w", err)
+				}
+
+				if err := readerPlugin.EnableMergedBlocksCatchUp(mergedBlocksStoreURL, firecore.GetMergedBlocksSidecarStoreURL(sfDataDir), viper.GetUint64("common-merged-blocks-bundle-size"), viper.GetDuration("reader-node-catch-up-live-threshold")); err != nil {
+					return nil, fmt.Errorf("enable merged blocks catch up: %w", err)
+				}
+			}
+
+			readerPlugin.SetUploadOptions(reader.UploadOptions{
+				Workers:      viper.GetInt("reader-node-one-block-upload-workers"),
+				MaxAttempts:  viper.GetInt("reader-node-one-block-upload-max-attempts"),
+				RetryBackoff: viper.GetDuration("reader-node-one-block-upload-retry-backoff"),
+				Direct:       viper.GetBool("reader-node-one-block-upload-direct"),
+			})
+
+			if viper.GetBool("reader-node-leader-election") {
+				leaseStoreURL := firecore.MustReplaceDataDir(sfDataDir, viper.GetString("reader-node-leader-election-store-url"))
+
+				var leaseStore dstore.Store
+				if leaseStoreURL == "" {
+					leaseStore, err = lease.NewStore(oneBlocksStoreURL, "leases")
+				} else {
+					leaseStore, err = dstore.NewSimpleStore(leaseStoreURL)
+				}
+				if err != nil {
+					return nil, fmt.Errorf("new leader election store: %w", err)
+				}
+
+				lock := lease.NewLock(leaseStore, "reader-node-leader.json", oneBlockFileSuffix, viper.GetDuration("reader-node-leader-election-lease-ttl"))
+				readerPlugin.EnableLeaderElection(lock, viper.GetUint64("reader-node-leader-election-follower-retained-blocks"))
+			}
+
+			if viper.GetBool("reader-node-dry-run") {
+				readerPlugin.EnableDryRun(newDryRunBlockDecoder(chain))
+			}
+
 			superviser.RegisterLogPlugin(readerPlugin)
 
 			return nodeManagerApp.New(&nodeManagerApp.Config{
@@ -258,6 +430,40 @@ func createNodeArgumentsResolver(dataDir, nodeDataDir, hostname string, startBlo
 	}
 }
 
+func newNodeLogPlugin(format, regex string, debugFirehose bool, logger *zap.Logger) (logplugin.LogPlugin, error) {
+	if format == "console" {
+		return sv.NewNodeLogPlugin(debugFirehose), nil
+	}
+
+	parser, err := logplugin.NewLineParser(format, regex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid 'reader-node-log-format' configuration: %w", err)
+	}
+
+	return logplugin.NewStructuredLogPlugin(parser, debugFirehose, logger.Named("node")), nil
+}
+
+// newDryRunBlockDecoder returns a decoder checking that the block's payload decodes into the chain's block and that
+// the decoded block agrees with the block's identity
+func newDryRunBlockDecoder[B firecore.Block](chain *firecore.Chain[B]) reader.BlockDecoder {
+	return func(block *pbbstream.Block) error {
+		if block.Payload == nil {
+			return fmt.Errorf("block has no payload")
+		}
+
+		decoded := chain.BlockFactory()
+		if err := block.Payload.UnmarshalTo(decoded); err != nil {
+			return fmt.Errorf("unmarshal payload: %w", err)
+		}
+
+		if id, num := decoded.GetFirehoseBlockID(), decoded.GetFirehoseBlockNumber(); id != block.Id || num != block.Number {
+			return fmt.Errorf("decoded block #%d (%s) does not match block #%d (%s)", num, id, block.Number, block.Id)
+		}
+
+		return nil
+	}
+}
+
 func gkeSnapshotterFactory(conf operator.BackupModuleConfig) (operator.BackupModule, error) {
 	return snapshotter.NewGKEPVCSnapshotter(conf)
 }
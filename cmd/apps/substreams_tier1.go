@@ -15,6 +15,7 @@
 package apps
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"os"
@@ -25,6 +26,7 @@ import (
 	"github.com/streamingfast/dauth"
 	discoveryservice "github.com/streamingfast/dgrpc/server/discovery-service"
 	firecore "github.com/streamingfast/firehose-core"
+	"github.com/streamingfast/firehose-core/encryptedstore"
 	"github.com/streamingfast/firehose-core/launcher"
 	"github.com/streamingfast/logging"
 	app "github.com/streamingfast/substreams/app"
@@ -70,6 +72,17 @@ func RegisterSubstreamsTier1App[B firecore.Block](chain *firecore.Chain[B], root
 				return nil, err
 			}
 
+			// Substreams builds its own block stores from the URLs
+			for _, storeURL := range []string{mergedBlocksStoreURL, oneBlocksStoreURL, forkedBlocksStoreURL} {
+				if encryptedstore.IsEncrypted(storeURL) {
+					return nil, fmt.Errorf("substreams does not support encrypted block stores, store %q is encrypted", storeURL)
+				}
+			}
+
+			if err := firecore.CheckSubstreamsMergedBlocksStore(context.Background(), mergedBlocksStoreURL); err != nil {
+				return nil, err
+			}
+
 			sfDataDir := runtime.AbsDataDir
 
 			rawServiceDiscoveryURL := viper.GetString("substreams-tier1-discovery-service-url")
@@ -123,6 +123,14 @@ func Main[B firecore.Block](chain *firecore.Chain[B]) {
 		startFlags.String("reader-node-bootstrap-data-url", "", firecore.DefaultReaderNodeBootstrapDataURLFlagDescription())
 	}
 
+	if chain.ReaderNodeBootstrapperFactory != nil && startFlags.Lookup("reader-node-bootstrap-staging-dir") == nil {
+		startFlags.String("reader-node-bootstrap-staging-dir", "{data-dir}/reader/bootstrap", cli.FlagDescription(`
+			Directory where archives referenced by 'reader-node-bootstrap-data-url' are downloaded before being extracted,
+			enabling interrupted downloads to be resumed. Set to an empty string to stream the archive directly into
+			'reader-node-data-dir' without staging it first.
+		`))
+	}
+
 	apps.ConfigureStartCmd(chain, binaryName, rootLog)
 
 	if err := tools.ConfigureToolsCmd(chain, rootLog, rootTracer); err != nil {
@@ -171,7 +179,38 @@ func registerCommonFlags[B firecore.Block](chain *firecore.Chain[B]) {
 		// Common stores configuration flags
 		cmd.Flags().String("common-one-block-store-url", firecore.OneBlockStoreURL, "[COMMON] Store URL to read/write one-block files")
 		cmd.Flags().String("common-merged-blocks-store-url", firecore.MergedBlocksStoreURL, "[COMMON] Store URL where to read/write merged blocks.")
+		cmd.Flags().Uint64("common-merged-blocks-bundle-size", 0, FlagMultilineDescription(`
+			[COMMON] Number of blocks per merged blocks bundle, must be a power of 10. The bundle size is a property of the merged
+			blocks store, recorded in its '.metadata' object by the merger (or the reader node writing bundles) when the store is
+			created with a non-default size. When 0, the store's bundle size is used (100 if the store has no metadata). It's an
+			error to set a value different from the store's bundle size.
+		`))
+		cmd.Flags().String("common-merged-blocks-sidecar-store-url", "", FlagMultilineDescription(`
+			[COMMON] Store URL where to read/write the block index sidecar of each merged blocks bundle, listing the blocks of the
+			bundle with their byte range. When set, the merger writes a sidecar for each bundle it merges and single block fetches
+			decode only the requested block (the bundle is still downloaded and decompressed up to the block). Use
+			'tools sidecar backfill' to write the sidecars of existing bundles. Disabled if empty.
+		`))
+		cmd.Flags().String("common-merged-blocks-checksum-verification", "fail", FlagMultilineDescription(`
+			[COMMON] How Firehose streams and single block fetches handle a merged blocks bundle not matching the checksum recorded
+			in its sidecar (see 'common-merged-blocks-sidecar-store-url', bundles without a sidecar are not verified): 'fail' streams
+			bundles while verifying them and fails the read at the end of a mismatching bundle, 'warn-and-refetch' copies bundles to
+			a temporary file before streaming them, logging a warning and reading a mismatching bundle again (up to 3 times)
+			before failing, 'none' disables verification.
+		`))
+		cmd.Flags().StringSlice("common-merged-blocks-store-tiers", nil, FlagMultilineDescription(`
+			[COMMON] Merged blocks stores read by Firehose streams and single block fetches after 'common-merged-blocks-store-url',
+			in order, a bundle not found in a store being read from the next one. Each tier is a store URL optionally followed by
+			a fragment naming it and bounding the blocks it holds (stop block exclusive), like
+			'gs://bucket/merged-blocks#name=cold&range=0:15000000'. The compressed bytes read from each tier are metered under
+			'file_compressed_read_bytes_tier_<name>', 'common-merged-blocks-store-url' being the 'primary' tier.
+		`))
 		cmd.Flags().String("common-forked-blocks-store-url", firecore.ForkedBlocksStoreURL, "[COMMON] Store URL where to read/write forked block files that we want to keep.")
+		cmd.Flags().String("common-forked-blocks-archive-store-url", "", FlagMultilineDescription(`
+			[COMMON] Store URL where to read/write the forked blocks archive. When set, the merger packs the forked blocks it prunes
+			into compressed forked bundles in this store instead of only deleting them, and Firehose single block requests look for
+			forked blocks in it. Use 'tools check forks --archive-store-url' to inspect archived forks. Disabled if empty.
+		`))
 		cmd.Flags().String("common-live-blocks-addr", firecore.RelayerServingAddr, "[COMMON] gRPC endpoint to get real-time blocks.")
 		cmd.Flags().String("common-tmp-dir", firecore.TmpDir, "[COMMON] Local directory to store temporary files")
 
@@ -200,24 +239,23 @@ func registerCommonFlags[B firecore.Block](chain *firecore.Chain[B]) {
 		cmd.Flags().IntSlice("common-index-block-sizes", []int{100000, 10000, 1000, 100}, "[COMMON] Index bundle sizes that that are considered valid when looking for block indexes")
 
 		cmd.Flags().Bool("common-blocks-cache-enabled", false, cli.FlagDescription(`
-			[COMMON] Use a disk cache to store the blocks data to disk and instead of keeping it in RAM. By enabling this, block's Protobuf content, in bytes,
-			is kept on file system instead of RAM. This is done as soon the block is downloaded from storage. This is a tradeoff between RAM and Disk, if you
-			are going to serve only a handful of concurrent requests, it's suggested to keep is disabled, if you encounter heavy RAM consumption issue, specially
-			by the firehose component, it's definitely a good idea to enable it and configure it properly through the other 'common-blocks-cache-...' flags. The cache is
-			split in two portions, one keeping N total bytes of blocks of the most recently used blocks and the other one keeping the N earliest blocks as
-			requested by the various consumers of the cache.
+			[COMMON] Cache on local disk the merged blocks bundles read by the firehose component (streams and single block fetches) from the merged blocks store,
+			avoiding to download them again from remote storage for hot ranges like the last day of blocks. The cache is split in two portions, one keeping
+			the bundles read once, evicting the oldest first, and one keeping the bundles read again, evicting the least recently used first, so that a long
+			scan of history never evicts the hot ranges. Cached bundles are encrypted with the keys of the merged blocks store (or the first encrypted
+			tier) when it's encrypted, otherwise they are stored in plaintext; clear the cache directory after disabling encryption. Configure it through
+			the other 'common-blocks-cache-...' flags.
 		`))
 		cmd.Flags().String("common-blocks-cache-dir", firecore.BlocksCacheDirectory, cli.FlagDescription(`
-			[COMMON] Blocks cache directory where all the block's bytes will be cached to disk instead of being kept in RAM.
-			This should be a disk that persists across restarts of the Firehose component to reduce the the strain on the disk
-			when restarting and streams reconnects. The size of disk must at least big (with a 10%% buffer) in bytes as the sum of flags'
-			value for  'common-blocks-cache-max-recent-entry-bytes' and 'common-blocks-cache-max-entry-by-age-bytes'.
+			[COMMON] Blocks cache directory where the merged blocks bundles are cached, must be on local disk. This should be a disk that persists across
+			restarts of the Firehose component, the bundles cached by a previous run being reused. The size of disk must at least big (with a 10%% buffer)
+			in bytes as the sum of flags' value for 'common-blocks-cache-max-recent-entry-bytes' and 'common-blocks-cache-max-entry-by-age-bytes'.
 		`))
 		cmd.Flags().Int("common-blocks-cache-max-recent-entry-bytes", 21474836480, cli.FlagDescription(`
-			[COMMON] Blocks cache max size in bytes of the most recently used blocks, after the limit is reached, blocks are evicted from the cache.
+			[COMMON] Blocks cache max size in bytes of the bundles read more than once, after the limit is reached, the least recently used bundles are evicted from the cache.
 		`))
 		cmd.Flags().Int("common-blocks-cache-max-entry-by-age-bytes", 21474836480, cli.FlagDescription(`
-			[COMMON] Blocks cache max size in bytes of the earliest used blocks, after the limit is reached, blocks are evicted from the cache.
+			[COMMON] Blocks cache max size in bytes of the bundles read once, after the limit is reached, the oldest bundles are evicted from the cache.
 		`))
 
 		cmd.Flags().Int("common-first-streamable-block", int(chain.FirstStreamableBlock), "[COMMON] First streamable block of the chain")
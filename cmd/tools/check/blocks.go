@@ -3,6 +3,7 @@ package check
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -15,6 +16,8 @@ import (
 	"github.com/streamingfast/dstore"
 	firecore "github.com/streamingfast/firehose-core"
 	print2 "github.com/streamingfast/firehose-core/cmd/tools/print"
+	"github.com/streamingfast/firehose-core/encryptedstore"
+	"github.com/streamingfast/firehose-core/sidecar"
 	"github.com/streamingfast/firehose-core/types"
 	"go.uber.org/zap"
 )
@@ -29,7 +32,9 @@ const (
 	PrintFull
 )
 
-func CheckMergedBlocks[B firecore.Block](ctx context.Context, chain *firecore.Chain[B], logger *zap.Logger, storeURL string, fileBlockSize uint64, blockRange types.BlockRange, printDetails PrintDetails) error {
+// CheckMergedBlocks checks the merged blocks bundles of `storeURL` for holes, verifying each bundle against the
+// checksum of its sidecar when `sidecarStore` is not nil
+func CheckMergedBlocks[B firecore.Block](ctx context.Context, chain *firecore.Chain[B], logger *zap.Logger, storeURL string, sidecarStore dstore.Store, fileBlockSize uint64, blockRange types.BlockRange, printDetails PrintDetails) error {
 	readAllBlocks := printDetails != PrintNoDetails
 	fmt.Printf("Checking block holes on %s\n", storeURL)
 	if readAllBlocks {
@@ -42,10 +47,11 @@ func CheckMergedBlocks[B firecore.Block](ctx context.Context, chain *firecore.Ch
 	lowestBlockSeen := firecore.MaxUint64
 
 	holeFound := false
+	var verifiedCount, unverifiedCount, checksumMismatchCount int
 	expected = types.RoundToBundleStartBlock(uint64(blockRange.Start), fileBlockSize)
 	currentStartBlk := uint64(blockRange.Start)
 
-	blocksStore, err := dstore.NewDBinStore(storeURL)
+	blocksStore, err := encryptedstore.NewDBinStore(storeURL)
 	if err != nil {
 		return err
 	}
@@ -87,6 +93,19 @@ func CheckMergedBlocks[B firecore.Block](ctx context.Context, chain *firecore.Ch
 		}
 		expected = baseNum + fileBlockSize
 
+		if sidecarStore != nil {
+			verified, err := verifyBundleChecksum(ctx, blocksStore, sidecarStore, baseNum)
+			switch {
+			case err != nil:
+				fmt.Printf("❌ Bundle %s does not match its sidecar checksum: %s\n", filename, err)
+				checksumMismatchCount++
+			case verified:
+				verifiedCount++
+			default:
+				unverifiedCount++
+			}
+		}
+
 		if readAllBlocks {
 			lowestBlockSegment, highestBlockSegment := validateBlockSegment(ctx, chain, blocksStore, filename, fileBlockSize, blockRange, printDetails, tfdb)
 			if lowestBlockSegment < lowestBlockSeen {
@@ -147,9 +166,50 @@ func CheckMergedBlocks[B firecore.Block](ctx context.Context, chain *firecore.Ch
 		fmt.Printf("> 🆗 No hole found\n")
 	}
 
+	if sidecarStore != nil {
+		if checksumMismatchCount > 0 {
+			fmt.Printf("> 🆘 %d bundles do not match their sidecar checksum!\n", checksumMismatchCount)
+		} else {
+			fmt.Printf("> 🆗 %d bundles match their sidecar checksum\n", verifiedCount)
+		}
+
+		if unverifiedCount > 0 {
+			fmt.Printf("> 🔶 %d bundles have no sidecar checksum and were not verified\n", unverifiedCount)
+		}
+	}
+
 	return nil
 }
 
+// verifyBundleChecksum verifies the bundle at `baseNum` against the checksum of its sidecar, returning false
+// when there is no sidecar checksum to verify it against
+func verifyBundleChecksum(ctx context.Context, blocksStore, sidecarStore dstore.Store, baseNum uint64) (bool, error) {
+	bundleSidecar, err := sidecar.Read(ctx, sidecarStore, baseNum)
+	if err != nil {
+		if errors.Is(err, dstore.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if bundleSidecar.Checksum == "" {
+		return false, nil
+	}
+
+	reader, err := blocksStore.OpenObject(ctx, sidecar.Filename(baseNum))
+	if err != nil {
+		return false, err
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return false, err
+	}
+
+	return true, sidecar.Verify(bundleSidecar, content)
+}
+
 type trackedForkDB struct {
 	fdb                    *forkable.ForkDB
 	firstUnlinkableBlock   *pbbstream.Block
@@ -15,6 +15,7 @@
 package check
 
 import (
+	"context"
 	"fmt"
 	"slices"
 	"strings"
@@ -22,10 +23,14 @@ import (
 	"github.com/dustin/go-humanize"
 	"github.com/spf13/cobra"
 	"github.com/streamingfast/bstream"
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
 	"github.com/streamingfast/cli"
 	"github.com/streamingfast/cli/sflags"
 	"github.com/streamingfast/dstore"
 	firecore "github.com/streamingfast/firehose-core"
+	"github.com/streamingfast/firehose-core/encryptedstore"
+	"github.com/streamingfast/firehose-core/forkarchive"
+	"github.com/streamingfast/firehose-core/sidecar"
 	"github.com/streamingfast/firehose-core/types"
 	"go.uber.org/zap"
 	"golang.org/x/exp/maps"
@@ -36,9 +41,14 @@ func NewCheckCommand[B firecore.Block](chain *firecore.Chain[B], rootLog *zap.Lo
 	toolsCheckCmd := &cobra.Command{Use: "check", Short: "Various checks for deployment, data integrity & debugging"}
 
 	toolsCheckForksCmd := &cobra.Command{
-		Use:   "forks <forked-blocks-store-url>",
+		Use:   "forks [<forked-blocks-store-url>]",
 		Short: "Reads all forked blocks you have and print longest linkable segments for each fork",
-		Args:  cobra.ExactArgs(1),
+		Long: cli.Dedent(`
+			Reads all forked blocks you have and print longest linkable segments for each fork. Forked blocks
+			are read from the forked blocks store and/or from the forked blocks archive written by the merger
+			(see --archive-store-url), at least one of them must be provided.
+		`),
+		Args: cobra.MaximumNArgs(1),
 	}
 
 	var (
@@ -59,10 +69,12 @@ func NewCheckCommand[B firecore.Block](chain *firecore.Chain[B], rootLog *zap.Lo
 	toolsCheckCmd.PersistentFlags().StringP("range", "r", "", "Block range to use for the check")
 
 	toolsCheckMergedBlocksCmd.Flags().BoolP("print-stats", "s", false, "Natively decode each block in the segment and print statistics about it, ensuring it contains the required blocks")
+	toolsCheckMergedBlocksCmd.Flags().String("sidecar-store-url", "", "Sidecar store URL (see --common-merged-blocks-sidecar-store-url) to verify each bundle against the checksum recorded in its sidecar")
 	toolsCheckMergedBlocksCmd.Flags().BoolP("print-full", "f", false, "Natively decode each block and print the full JSON representation of the block, should be used with a small range only if you don't want to be overwhelmed")
 
 	toolsCheckForksCmd.Flags().Uint64("min-depth", 1, "Only show forks that are at least this deep")
 	toolsCheckForksCmd.Flags().Uint64("after-block", 0, "Only show forks that happened after this block number, if value is not 0")
+	toolsCheckForksCmd.Flags().String("archive-store-url", "", "Forked blocks archive store URL (see --common-forked-blocks-archive-store-url) to also read archived forked blocks from")
 
 	toolsCheckMergedBlocksCmd.RunE = createToolsCheckMergedBlocksE(chain, rootLog)
 	toolsCheckMergedBlocksCmd.Example = firecore.ExamplePrefixed(chain, "tools check merged-blocks", `
@@ -71,6 +83,7 @@ func NewCheckCommand[B firecore.Block](chain *firecore.Chain[B], rootLog *zap.Lo
 		"s3://<project>/<bucket>/<path>" -f
 		"az://<project>/<bucket>/<path>" -r ":1_000_000"
 		"az://<project>/<bucket>/<path>" -r "100_000:1_000_000"
+		"./sf-data/storage/merged-blocks" --sidecar-store-url "./sf-data/storage/merged-blocks-sidecars"
 	`)
 
 	toolsCheckForksCmd.RunE = toolsCheckForksE
@@ -81,7 +94,10 @@ func NewCheckCommand[B firecore.Block](chain *firecore.Chain[B], rootLog *zap.Lo
 func createToolsCheckMergedBlocksE[B firecore.Block](chain *firecore.Chain[B], rootLog *zap.Logger) firecore.CommandExecutor {
 	return func(cmd *cobra.Command, args []string) error {
 		storeURL := args[0]
-		fileBlockSize := uint64(100)
+		fileBlockSize, err := mergedBlocksBundleSize(cmd.Context(), storeURL)
+		if err != nil {
+			return err
+		}
 
 		blockRange, err := types.GetBlockRangeFromFlagDefault(cmd, "range", types.NewOpenRange(0))
 		if err != nil {
@@ -97,27 +113,75 @@ func createToolsCheckMergedBlocksE[B firecore.Block](chain *firecore.Chain[B], r
 			printDetails = PrintFull
 		}
 
-		return CheckMergedBlocks(cmd.Context(), chain, rootLog, storeURL, fileBlockSize, blockRange, printDetails)
+		var sidecarStore dstore.Store
+		if sidecarStoreURL := sflags.MustGetString(cmd, "sidecar-store-url"); sidecarStoreURL != "" {
+			sidecarStore, err = sidecar.NewStore(sidecarStoreURL)
+			if err != nil {
+				return err
+			}
+		}
+
+		return CheckMergedBlocks(cmd.Context(), chain, rootLog, storeURL, sidecarStore, fileBlockSize, blockRange, printDetails)
 	}
 }
 
-func toolsCheckForksE(cmd *cobra.Command, args []string) error {
-	storeURL := args[0]
+func mergedBlocksBundleSize(ctx context.Context, storeURL string) (uint64, error) {
+	store, err := encryptedstore.NewDBinStore(storeURL)
+	if err != nil {
+		return 0, fmt.Errorf("unable to create store at path %q: %w", storeURL, err)
+	}
 
-	blocksStore, err := dstore.NewDBinStore(storeURL)
-	cli.NoError(err, "unable to create blocks store")
+	return types.ResolveBundleSize(ctx, store, 0)
+}
+
+func toolsCheckForksE(cmd *cobra.Command, args []string) error {
+	archiveStoreURL := sflags.MustGetString(cmd, "archive-store-url")
+	if len(args) == 0 && archiveStoreURL == "" {
+		return fmt.Errorf("a forked blocks store URL argument and/or the --archive-store-url flag is required")
+	}
 
 	oneBlockFiles := []*bstream.OneBlockFile{}
 	oneBlockFilesByID := map[string]*bstream.OneBlockFile{}
-	err = blocksStore.Walk(cmd.Context(), "", func(filename string) error {
-		file, err := bstream.NewOneBlockFile(filename)
-		cli.NoError(err, "unable to parse block filename %q", filename)
+	addFile := func(file *bstream.OneBlockFile) {
+		if _, found := oneBlockFilesByID[file.ID]; found {
+			return
+		}
 
 		oneBlockFiles = append(oneBlockFiles, file)
 		oneBlockFilesByID[file.ID] = file
-		return nil
-	})
-	cli.NoError(err, "unable to walk blocks store")
+	}
+
+	if len(args) == 1 {
+		blocksStore, err := encryptedstore.NewDBinStore(args[0])
+		cli.NoError(err, "unable to create blocks store")
+
+		err = blocksStore.Walk(cmd.Context(), "", func(filename string) error {
+			file, err := bstream.NewOneBlockFile(filename)
+			cli.NoError(err, "unable to parse block filename %q", filename)
+
+			addFile(file)
+			return nil
+		})
+		cli.NoError(err, "unable to walk blocks store")
+	}
+
+	if archiveStoreURL != "" {
+		archiveStore, err := forkarchive.NewStore(archiveStoreURL)
+		cli.NoError(err, "unable to create forked blocks archive store")
+
+		err = forkarchive.Walk(cmd.Context(), archiveStore, "", func(block *pbbstream.Block) error {
+			// archived blocks are keyed like one-block files, by their truncated IDs
+			addFile(&bstream.OneBlockFile{
+				CanonicalName: bstream.BlockFileName(block),
+				ID:            bstream.TruncateBlockID(block.Id),
+				Num:           block.Number,
+				PreviousID:    bstream.TruncateBlockID(block.ParentId),
+				LibNum:        block.LibNum,
+			})
+			return nil
+		})
+		cli.NoError(err, "unable to walk forked blocks archive store")
+	}
 
 	if len(oneBlockFiles) == 0 {
 		fmt.Println("No forked blocks found")
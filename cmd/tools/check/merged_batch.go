@@ -10,6 +10,7 @@ import (
 	"github.com/streamingfast/bstream"
 	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
 	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/encryptedstore"
 	"github.com/streamingfast/firehose-core/types"
 )
 
@@ -50,7 +51,7 @@ func CheckMergedBlocksBatch(
 	expected := types.RoundToBundleStartBlock(uint64(blockRange.Start), fileBlockSize)
 	fileBlockSize64 := uint64(fileBlockSize)
 
-	blocksStore, err := dstore.NewDBinStore(sourceStoreURL)
+	blocksStore, err := encryptedstore.NewDBinStore(sourceStoreURL)
 	if err != nil {
 		return err
 	}
@@ -103,7 +104,7 @@ func CheckMergedBlocksBatch(
 					destStore.WriteObject(ctx, outputFile, strings.NewReader(""))
 				}
 			} else {
-				brokenSince := types.RoundToBundleStartBlock(uint64(lastSeenBlock.num+1), 100)
+				brokenSince := types.RoundToBundleStartBlock(uint64(lastSeenBlock.num+1), fileBlockSize)
 				for i := brokenSince; i <= baseNum; i += fileBlockSize64 {
 					fmt.Printf("found broken file %q, %s\n", filename, details)
 					if destStore != nil {
@@ -44,7 +44,10 @@ func checkMergedBlocksBatchRunE(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	fileBlockSize := uint64(100)
+	fileBlockSize, err := mergedBlocksBundleSize(cmd.Context(), storeURL)
+	if err != nil {
+		return err
+	}
 
 	blockRange := types.BlockRange{
 		Start: int64(start),
@@ -30,6 +30,7 @@ import (
 	"github.com/streamingfast/dstore"
 	firecore "github.com/streamingfast/firehose-core"
 	"github.com/streamingfast/firehose-core/cmd/tools/check"
+	"github.com/streamingfast/firehose-core/encryptedstore"
 	"github.com/streamingfast/firehose-core/json"
 	fcproto "github.com/streamingfast/firehose-core/proto"
 	"github.com/streamingfast/firehose-core/types"
@@ -102,11 +103,11 @@ func runCompareBlocksE[B firecore.Block](chain *firecore.Chain[B]) firecore.Comm
 		stopBlock := blockRange.GetStopBlockOr(firecore.MaxUint64)
 
 		// Create stores
-		storeReference, err := dstore.NewDBinStore(args[0])
+		storeReference, err := encryptedstore.NewDBinStore(args[0])
 		if err != nil {
 			return fmt.Errorf("unable to create store at path %q: %w", args[0], err)
 		}
-		storeCurrent, err := dstore.NewDBinStore(args[1])
+		storeCurrent, err := encryptedstore.NewDBinStore(args[1])
 		if err != nil {
 			return fmt.Errorf("unable to create store at path %q: %w", args[1], err)
 		}
@@ -126,7 +127,16 @@ func runCompareBlocksE[B firecore.Block](chain *firecore.Chain[B]) firecore.Comm
 
 		sanitizer := chain.Tools.GetSanitizeBlockForCompare()
 
-		err = storeReference.Walk(ctx, check.WalkBlockPrefix(blockRange, 100), func(filename string) (err error) {
+		bundleSize, err := types.ResolveBundleSize(ctx, storeReference, 0)
+		if err != nil {
+			return fmt.Errorf("resolving reference store bundle size: %w", err)
+		}
+
+		err = storeReference.Walk(ctx, check.WalkBlockPrefix(blockRange, bundleSize), func(filename string) (err error) {
+			if types.IsMergedBlocksStoreMetadataObject(filename) {
+				return nil
+			}
+
 			fileStartBlock, err := strconv.Atoi(filename)
 			if err != nil {
 				return fmt.Errorf("parsing filename: %w", err)
@@ -10,8 +10,11 @@ import (
 	"github.com/streamingfast/bstream"
 	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
 	"github.com/streamingfast/cli"
+	"github.com/streamingfast/cli/sflags"
 	"github.com/streamingfast/dstore"
 	firecore "github.com/streamingfast/firehose-core"
+	"github.com/streamingfast/firehose-core/encryptedstore"
+	"github.com/streamingfast/firehose-core/sidecar"
 	"github.com/streamingfast/firehose-core/types"
 	pbfirehose "github.com/streamingfast/pbgo/sf/firehose/v2"
 	"go.uber.org/zap"
@@ -32,6 +35,7 @@ func NewToolsDownloadFromFirehoseCmd[B firecore.Block](chain *firecore.Chain[B],
 	}
 
 	addFirehoseStreamClientFlagsToSet(cmd.Flags(), chain)
+	cmd.Flags().String("sidecar-store", "", "Store URL receiving the sidecar (block index and checksums) of each written bundle, none written if empty")
 
 	return cmd
 }
@@ -55,15 +59,30 @@ func createToolsDownloadFromFirehoseE[B firecore.Block](chain *firecore.Chain[B]
 
 		var retryDelay = time.Second * 4
 
-		store, err := dstore.NewDBinStore(destFolder)
+		store, err := encryptedstore.NewDBinStore(destFolder)
 		if err != nil {
 			return err
 		}
 
+		bundleSize, err := types.ResolveBundleSize(ctx, store, 0)
+		if err != nil {
+			return fmt.Errorf("resolving destination store bundle size: %w", err)
+		}
+
+		var sidecarStore dstore.Store
+		if sidecarStoreURL := sflags.MustGetString(cmd, "sidecar-store"); sidecarStoreURL != "" {
+			sidecarStore, err = sidecar.NewStore(sidecarStoreURL)
+			if err != nil {
+				return err
+			}
+		}
+
 		mergeWriter := &firecore.MergedBlocksWriter{
-			Store:      store,
-			TweakBlock: func(b *pbbstream.Block) (*pbbstream.Block, error) { return b, nil },
-			Logger:     zlog,
+			Store:        store,
+			SidecarStore: sidecarStore,
+			BundleSize:   bundleSize,
+			TweakBlock:   func(b *pbbstream.Block) (*pbbstream.Block, error) { return b, nil },
+			Logger:       zlog,
 		}
 
 		approximateLIBWarningIssued := false
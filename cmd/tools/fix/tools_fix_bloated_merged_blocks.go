@@ -10,6 +10,7 @@ import (
 	"github.com/streamingfast/dstore"
 	firecore "github.com/streamingfast/firehose-core"
 	"github.com/streamingfast/firehose-core/cmd/tools/check"
+	"github.com/streamingfast/firehose-core/encryptedstore"
 	"github.com/streamingfast/firehose-core/types"
 	"go.uber.org/zap"
 )
@@ -27,12 +28,12 @@ func runFixBloatedMergedBlocksE(zlog *zap.Logger) firecore.CommandExecutor {
 	return func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
 
-		srcStore, err := dstore.NewDBinStore(args[0])
+		srcStore, err := encryptedstore.NewDBinStore(args[0])
 		if err != nil {
 			return fmt.Errorf("unable to create source store: %w", err)
 		}
 
-		destStore, err := dstore.NewDBinStore(args[1])
+		destStore, err := encryptedstore.NewDBinStore(args[1])
 		if err != nil {
 			return fmt.Errorf("unable to create destination store: %w", err)
 		}
@@ -42,7 +43,20 @@ func runFixBloatedMergedBlocksE(zlog *zap.Logger) firecore.CommandExecutor {
 			return fmt.Errorf("parsing block range: %w", err)
 		}
 
-		err = srcStore.Walk(ctx, check.WalkBlockPrefix(blockRange, 100), func(filename string) error {
+		bundleSize, err := types.ResolveBundleSize(ctx, srcStore, 0)
+		if err != nil {
+			return fmt.Errorf("resolving source store bundle size: %w", err)
+		}
+
+		if _, err := types.EnsureBundleSize(ctx, destStore, bundleSize); err != nil {
+			return fmt.Errorf("destination store bundle size: %w", err)
+		}
+
+		err = srcStore.Walk(ctx, check.WalkBlockPrefix(blockRange, bundleSize), func(filename string) error {
+			if types.IsMergedBlocksStoreMetadataObject(filename) {
+				return nil
+			}
+
 			zlog.Debug("checking merged block file", zap.String("filename", filename))
 
 			startBlock := firecore.MustParseUint64(filename)
@@ -52,7 +66,7 @@ func runFixBloatedMergedBlocksE(zlog *zap.Logger) firecore.CommandExecutor {
 				return dstore.StopIteration
 			}
 
-			if startBlock+100 < uint64(blockRange.Start) {
+			if startBlock+bundleSize < uint64(blockRange.Start) {
 				zlog.Debug("skipping merged block file", zap.String("reason", "before start block"), zap.String("filename", filename))
 				return nil
 			}
@@ -70,6 +84,7 @@ func runFixBloatedMergedBlocksE(zlog *zap.Logger) firecore.CommandExecutor {
 
 			mergeWriter := &firecore.MergedBlocksWriter{
 				Store:      destStore,
+				BundleSize: bundleSize,
 				TweakBlock: func(b *pbbstream.Block) (*pbbstream.Block, error) { return b, nil },
 				Logger:     zlog,
 			}
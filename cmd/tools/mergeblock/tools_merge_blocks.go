@@ -11,8 +11,12 @@ import (
 	"github.com/streamingfast/bstream"
 
 	"github.com/spf13/cobra"
+	"github.com/streamingfast/cli/sflags"
 	"github.com/streamingfast/dstore"
 	firecore "github.com/streamingfast/firehose-core"
+	"github.com/streamingfast/firehose-core/encryptedstore"
+	"github.com/streamingfast/firehose-core/sidecar"
+	"github.com/streamingfast/firehose-core/types"
 	"go.uber.org/zap"
 )
 
@@ -23,6 +27,7 @@ func NewToolsMergeBlocksCmd[B firecore.Block](chain *firecore.Chain[B], zlog *za
 		Args:  cobra.ExactArgs(3),
 		RunE:  runMergeBlocksE(zlog),
 	}
+	cmd.Flags().String("sidecar-store", "", "Store URL receiving the sidecar (block index and checksums) of each written bundle, none written if empty")
 
 	return cmd
 }
@@ -31,12 +36,12 @@ func runMergeBlocksE(zlog *zap.Logger) firecore.CommandExecutor {
 	return func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
 
-		srcStore, err := dstore.NewDBinStore(args[0])
+		srcStore, err := encryptedstore.NewDBinStore(args[0])
 		if err != nil {
 			return fmt.Errorf("unable to create source store: %w", err)
 		}
 
-		destStore, err := dstore.NewDBinStore(args[1])
+		destStore, err := encryptedstore.NewDBinStore(args[1])
 		if err != nil {
 			return fmt.Errorf("unable to create destination store: %w", err)
 		}
@@ -46,8 +51,23 @@ func runMergeBlocksE(zlog *zap.Logger) firecore.CommandExecutor {
 			return fmt.Errorf("converting low bundary string to uint64: %w", err)
 		}
 
+		bundleSize, err := types.ResolveBundleSize(ctx, destStore, 0)
+		if err != nil {
+			return fmt.Errorf("resolving destination store bundle size: %w", err)
+		}
+
+		var sidecarStore dstore.Store
+		if sidecarStoreURL := sflags.MustGetString(cmd, "sidecar-store"); sidecarStoreURL != "" {
+			sidecarStore, err = sidecar.NewStore(sidecarStoreURL)
+			if err != nil {
+				return err
+			}
+		}
+
 		mergeWriter := &firecore.MergedBlocksWriter{
 			Store:        destStore,
+			SidecarStore: sidecarStore,
+			BundleSize:   bundleSize,
 			LowBlockNum:  lowBundary,
 			StopBlockNum: 0,
 			Logger:       zlog,
@@ -71,7 +91,7 @@ func runMergeBlocksE(zlog *zap.Logger) firecore.CommandExecutor {
 				return nil
 			}
 
-			if currentBlockNumber > lowBundary+100 {
+			if currentBlockNumber > lowBundary+bundleSize {
 				return dstore.StopIteration
 			}
 
@@ -10,8 +10,10 @@ import (
 	"github.com/spf13/cobra"
 	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
 	"github.com/streamingfast/bstream/stream"
-	"github.com/streamingfast/dstore"
 	firecore "github.com/streamingfast/firehose-core"
+	"github.com/streamingfast/firehose-core/encryptedstore"
+	"github.com/streamingfast/firehose-core/streamablestore"
+	"github.com/streamingfast/firehose-core/types"
 	"go.uber.org/zap"
 )
 
@@ -27,13 +29,13 @@ func NewToolsUpgradeMergedBlocksCmd[B firecore.Block](chain *firecore.Chain[B],
 func getMergedBlockUpgrader(tweakFunc func(block *pbbstream.Block) (*pbbstream.Block, error), rootLog *zap.Logger) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
 		source := args[0]
-		sourceStore, err := dstore.NewDBinStore(source)
+		sourceStore, err := encryptedstore.NewDBinStore(source)
 		if err != nil {
 			return fmt.Errorf("reading source store: %w", err)
 		}
 
 		dest := args[1]
-		destStore, err := dstore.NewStore(dest, "dbin.zst", "zstd", true)
+		destStore, err := encryptedstore.NewStore(dest, "dbin.zst", "zstd", true)
 		if err != nil {
 			return fmt.Errorf("reading destination store: %w", err)
 		}
@@ -47,16 +49,26 @@ func getMergedBlockUpgrader(tweakFunc func(block *pbbstream.Block) (*pbbstream.B
 			return fmt.Errorf("parsing stop block num: %w", err)
 		}
 
+		bundleSize, err := types.ResolveBundleSize(cmd.Context(), sourceStore, 0)
+		if err != nil {
+			return fmt.Errorf("source store bundle size: %w", err)
+		}
+
+		if _, err := types.EnsureBundleSize(cmd.Context(), destStore, bundleSize); err != nil {
+			return fmt.Errorf("destination store bundle size: %w", err)
+		}
+
 		rootLog.Info("starting block upgrader process", zap.Uint64("start", start), zap.Uint64("stop", stop), zap.String("source", source), zap.String("dest", dest))
 		writer := &firecore.MergedBlocksWriter{
 			Cmd:          cmd,
 			Store:        destStore,
-			LowBlockNum:  firecore.LowBoundary(start),
+			LowBlockNum:  firecore.LowBoundaryForBundleSize(start, bundleSize),
+			BundleSize:   bundleSize,
 			StopBlockNum: stop,
 			TweakBlock:   tweakFunc,
 			Logger:       rootLog,
 		}
-		stream := stream.New(nil, sourceStore, nil, int64(start), writer, stream.WithFinalBlocksOnly())
+		stream := stream.New(nil, streamablestore.NewStore(sourceStore, bundleSize), nil, int64(start), writer, stream.WithFinalBlocksOnly())
 
 		err = stream.Run(context.Background())
 		if errors.Is(err, io.EOF) {
@@ -24,8 +24,8 @@ import (
 	"github.com/streamingfast/bstream"
 	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
 	"github.com/streamingfast/cli/sflags"
-	"github.com/streamingfast/dstore"
 	firecore "github.com/streamingfast/firehose-core"
+	"github.com/streamingfast/firehose-core/encryptedstore"
 	fcjson "github.com/streamingfast/firehose-core/json"
 	"github.com/streamingfast/firehose-core/proto"
 	"github.com/streamingfast/firehose-core/types"
@@ -76,7 +76,7 @@ func createToolsPrintMergedBlocksE[B firecore.Block](chain *firecore.Chain[B]) f
 		printTransactions := sflags.MustGetBool(cmd, "transactions")
 
 		storeURL := args[0]
-		store, err := dstore.NewDBinStore(storeURL)
+		store, err := encryptedstore.NewDBinStore(storeURL)
 		if err != nil {
 			return fmt.Errorf("unable to create store at path %q: %w", store, err)
 		}
@@ -85,7 +85,12 @@ func createToolsPrintMergedBlocksE[B firecore.Block](chain *firecore.Chain[B]) f
 		if err != nil {
 			return fmt.Errorf("invalid base block %q: %w", args[1], err)
 		}
-		blockBoundary := types.RoundToBundleStartBlock(startBlock, 100)
+		bundleSize, err := types.ResolveBundleSize(ctx, store, 0)
+		if err != nil {
+			return fmt.Errorf("resolving store bundle size: %w", err)
+		}
+
+		blockBoundary := types.RoundToBundleStartBlock(startBlock, bundleSize)
 
 		filename := fmt.Sprintf("%010d", blockBoundary)
 		reader, err := store.OpenObject(ctx, filename)
@@ -144,7 +149,7 @@ func createToolsPrintOneBlockE[B firecore.Block](chain *firecore.Chain[B]) firec
 		}
 
 		storeURL := args[0]
-		store, err := dstore.NewDBinStore(storeURL)
+		store, err := encryptedstore.NewDBinStore(storeURL)
 		if err != nil {
 			return fmt.Errorf("unable to create store at path %q: %w", store, err)
 		}
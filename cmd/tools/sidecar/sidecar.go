@@ -0,0 +1,185 @@
+package sidecar
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/streamingfast/cli/sflags"
+	"github.com/streamingfast/dstore"
+	firecore "github.com/streamingfast/firehose-core"
+	"github.com/streamingfast/firehose-core/cmd/tools/check"
+	"github.com/streamingfast/firehose-core/encryptedstore"
+	"github.com/streamingfast/firehose-core/sidecar"
+	"github.com/streamingfast/firehose-core/types"
+	"go.uber.org/zap"
+)
+
+func NewToolsSidecarCmd(logger *zap.Logger) *cobra.Command {
+	toolsSidecarCmd := &cobra.Command{
+		Use:   "sidecar",
+		Short: "Tools to manage the block index sidecars of merged blocks bundles",
+	}
+
+	backfillCmd := &cobra.Command{
+		Use:   "backfill <merged_blocks_store> <sidecar_store> [<block_range>]",
+		Short: "Writes the sidecar of the merged blocks bundles having none, the whole store when no block range is given",
+		Args:  cobra.RangeArgs(2, 3),
+		RunE:  runBackfillE(logger),
+	}
+	backfillCmd.Flags().Bool("overwrite", false, "Rewrite the sidecar of bundles already having one")
+
+	printCmd := &cobra.Command{
+		Use:   "print <sidecar_store> <base_block>",
+		Short: "Prints the sidecar of the merged blocks bundle starting at <base_block>",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runPrintE,
+	}
+
+	lastBlockCmd := &cobra.Command{
+		Use:   "last-block <merged_blocks_store> <sidecar_store>",
+		Short: "Prints the last merged block using the merged blocks store head pointer, or the sidecar of the last bundle when it is stale",
+		Args:  cobra.ExactArgs(2),
+		RunE:  runLastBlockE(logger),
+	}
+
+	toolsSidecarCmd.AddCommand(backfillCmd)
+	toolsSidecarCmd.AddCommand(printCmd)
+	toolsSidecarCmd.AddCommand(lastBlockCmd)
+
+	return toolsSidecarCmd
+}
+
+func runBackfillE(logger *zap.Logger) firecore.CommandExecutor {
+	return func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		mergedBlocksStore, err := encryptedstore.NewDBinStore(args[0])
+		if err != nil {
+			return fmt.Errorf("unable to create merged blocks store: %w", err)
+		}
+
+		sidecarStore, err := sidecar.NewStore(args[1])
+		if err != nil {
+			return err
+		}
+
+		blockRange := types.NewOpenRange(0)
+		if len(args) == 3 {
+			blockRange, err = types.GetBlockRangeFromArg(args[2])
+			if err != nil {
+				return fmt.Errorf("parsing block range: %w", err)
+			}
+		}
+
+		bundleSize, err := types.ResolveBundleSize(ctx, mergedBlocksStore, 0)
+		if err != nil {
+			return fmt.Errorf("resolving merged blocks store bundle size: %w", err)
+		}
+
+		overwrite := sflags.MustGetBool(cmd, "overwrite")
+
+		written, skipped := 0, 0
+		err = mergedBlocksStore.Walk(ctx, check.WalkBlockPrefix(blockRange, bundleSize), func(filename string) error {
+			if types.IsMergedBlocksStoreMetadataObject(filename) {
+				return nil
+			}
+
+			baseBlockNum := firecore.MustParseUint64(filename)
+			if baseBlockNum > blockRange.GetStopBlockOr(firecore.MaxUint64) {
+				return dstore.StopIteration
+			}
+
+			if baseBlockNum+bundleSize <= uint64(blockRange.Start) {
+				return nil
+			}
+
+			if !overwrite {
+				exists, err := sidecarStore.FileExists(ctx, sidecar.Filename(baseBlockNum))
+				if err != nil {
+					return fmt.Errorf("checking sidecar #%d: %w", baseBlockNum, err)
+				}
+
+				if exists {
+					skipped++
+					return nil
+				}
+			}
+
+			bundleSidecar, err := sidecar.BuildFromBundle(ctx, mergedBlocksStore, baseBlockNum)
+			if err != nil {
+				return err
+			}
+
+			if err := sidecar.Write(ctx, sidecarStore, bundleSidecar); err != nil {
+				return err
+			}
+
+			written++
+			logger.Debug("wrote sidecar", zap.Uint64("base_block_num", baseBlockNum), zap.Int("block_count", len(bundleSidecar.Blocks)))
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("walking merged blocks store: %w", err)
+		}
+
+		fmt.Printf("Wrote %d sidecars, skipped %d bundles already having one\n", written, skipped)
+		return nil
+	}
+}
+
+func runPrintE(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	sidecarStore, err := sidecar.NewStore(args[0])
+	if err != nil {
+		return err
+	}
+
+	baseBlockNum, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid base block %q: %w", args[1], err)
+	}
+
+	bundleSidecar, err := sidecar.Read(ctx, sidecarStore, baseBlockNum)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Sidecar #%d (%d blocks, header of %d bytes, checksum %q)\n", bundleSidecar.BaseBlockNum, len(bundleSidecar.Blocks), bundleSidecar.HeaderLength, bundleSidecar.Checksum)
+	for _, entry := range bundleSidecar.Blocks {
+		fmt.Printf("#%d (%s) parent #%d (%s) lib #%d at %s, offset %d, %d bytes, checksum %q\n", entry.Num, entry.ID, entry.ParentNum, entry.ParentID, entry.LibNum, entry.Timestamp.Format(time.RFC3339), entry.Offset, entry.Length, entry.Checksum)
+	}
+
+	return nil
+}
+
+func runLastBlockE(logger *zap.Logger) firecore.CommandExecutor {
+	return func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+
+		mergedBlocksStore, err := encryptedstore.NewDBinStore(args[0])
+		if err != nil {
+			return fmt.Errorf("unable to create merged blocks store: %w", err)
+		}
+
+		sidecarStore, err := sidecar.NewStore(args[1])
+		if err != nil {
+			return err
+		}
+
+		bundleSize, err := types.ResolveBundleSize(ctx, mergedBlocksStore, 0)
+		if err != nil {
+			return fmt.Errorf("resolving merged blocks store bundle size: %w", err)
+		}
+
+		ref, err := firecore.LastMergedBlockRef(ctx, 0, bundleSize, mergedBlocksStore, sidecarStore, logger)
+		if err != nil {
+			return fmt.Errorf("last merged block: %w", err)
+		}
+
+		fmt.Printf("Last merged block: %s\n", ref)
+		return nil
+	}
+}
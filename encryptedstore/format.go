@@ -0,0 +1,201 @@
+package encryptedstore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// An encrypted object is a header followed by the encrypted chunks of the object:
+//
+//	magic | key ID length (1 byte) | key ID | nonce prefix (8 bytes) | chunk... | final chunk
+//
+// Each chunk holds chunkSize bytes of the object, the final one less (possibly none) so that a truncated object is
+// detected. The nonce of a chunk is the nonce prefix followed by the big endian index of the chunk (4 bytes), its
+// additional data the header followed by 1 for the final chunk and 0 otherwise, so chunks cannot be reordered, moved
+// to another object or dropped.
+var magic = []byte("FCENC\x01")
+
+const (
+	chunkSize       = 64 * 1024
+	noncePrefixSize = 8
+)
+
+var ErrNotEncrypted = errors.New("object is not encrypted")
+
+// encrypter reads the encrypted object of the plain object read from `source`
+type encrypter struct {
+	source io.Reader
+	key    *key
+	header []byte
+	nonce  []byte
+
+	chunk   uint32
+	plain   []byte
+	sealed  []byte
+	pending []byte
+	done    bool
+}
+
+func newEncrypter(source io.Reader, key *key) (*encrypter, error) {
+	noncePrefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(noncePrefix); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+
+	header := make([]byte, 0, len(magic)+1+len(key.id)+noncePrefixSize)
+	header = append(header, magic...)
+	header = append(header, byte(len(key.id)))
+	header = append(header, key.id...)
+	header = append(header, noncePrefix...)
+
+	return &encrypter{
+		source:  source,
+		key:     key,
+		header:  header,
+		nonce:   append(noncePrefix, 0, 0, 0, 0),
+		plain:   make([]byte, chunkSize),
+		sealed:  make([]byte, 0, chunkSize+key.aead.Overhead()),
+		pending: header,
+	}, nil
+}
+
+func (e *encrypter) Read(p []byte) (int, error) {
+	for len(e.pending) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+
+		if err := e.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, e.pending)
+	e.pending = e.pending[n:]
+	return n, nil
+}
+
+func (e *encrypter) next() error {
+	n, err := io.ReadFull(e.source, e.plain)
+	final := false
+	switch {
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		final = true
+	case err != nil:
+		return err
+	}
+
+	if e.chunk == math.MaxUint32 {
+		return fmt.Errorf("object is too big to be encrypted")
+	}
+
+	binary.BigEndian.PutUint32(e.nonce[noncePrefixSize:], e.chunk)
+	e.pending = e.key.aead.Seal(e.sealed[:0], e.nonce, e.plain[:n], additionalData(e.header, final))
+	e.chunk++
+	e.done = final
+	return nil
+}
+
+// decrypter reads the plain object of the encrypted object read from `source`
+type decrypter struct {
+	source io.Reader
+	key    *key
+	header []byte
+	nonce  []byte
+
+	chunk   uint32
+	sealed  []byte
+	plain   []byte
+	pending []byte
+	done    bool
+}
+
+// newDecrypter reads the header of the encrypted object, returning an error if it's not encrypted or its key is not
+// in `keys`
+func newDecrypter(source io.Reader, keys *Keyring) (*decrypter, error) {
+	header := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(source, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrNotEncrypted
+		}
+		return nil, err
+	}
+
+	if !bytes.Equal(header[:len(magic)], magic) {
+		return nil, ErrNotEncrypted
+	}
+
+	header = append(header, make([]byte, int(header[len(magic)])+noncePrefixSize)...)
+	if _, err := io.ReadFull(source, header[len(magic)+1:]); err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	keyID := string(header[len(magic)+1 : len(header)-noncePrefixSize])
+	key, err := keys.get(keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decrypter{
+		source: source,
+		key:    key,
+		header: header,
+		nonce:  append(bytes.Clone(header[len(header)-noncePrefixSize:]), 0, 0, 0, 0),
+		sealed: make([]byte, chunkSize+key.aead.Overhead()),
+		plain:  make([]byte, 0, chunkSize),
+	}, nil
+}
+
+func (d *decrypter) Read(p []byte) (int, error) {
+	for len(d.pending) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+
+		if err := d.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+func (d *decrypter) next() error {
+	n, err := io.ReadFull(d.source, d.sealed)
+	final := false
+	switch {
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		final = true
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("object is truncated after chunk %d", d.chunk)
+	case err != nil:
+		return err
+	}
+
+	binary.BigEndian.PutUint32(d.nonce[noncePrefixSize:], d.chunk)
+	plain, err := d.key.aead.Open(d.plain[:0], d.nonce, d.sealed[:n], additionalData(d.header, final))
+	if err != nil {
+		return fmt.Errorf("decrypt chunk %d: %w", d.chunk, err)
+	}
+
+	d.pending = plain
+	d.chunk++
+	d.done = final
+	return nil
+}
+
+func additionalData(header []byte, final bool) []byte {
+	data := make([]byte, len(header), len(header)+1)
+	copy(data, header)
+	if final {
+		return append(data, 1)
+	}
+	return append(data, 0)
+}
@@ -0,0 +1,118 @@
+package encryptedstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeySize is the size in bytes of the AES-256 keys encrypting the objects
+const KeySize = 32
+
+// Keyring holds the keys of an encrypted store, the first one encrypting the objects written and all of them
+// decrypting the objects read, so that keys can be rotated without re-encrypting the existing objects
+type Keyring struct {
+	keys []*key
+	byID map[string]*key
+}
+
+type key struct {
+	// id identifies the key in the header of the objects it encrypts, it's the start of the SHA-256 of the key so it
+	// does not leak the key itself
+	id   string
+	aead cipher.AEAD
+}
+
+// ParseKeyring parses hex encoded 32 bytes keys separated by commas or whitespaces, the first one encrypting the
+// objects written
+func ParseKeyring(in string) (*Keyring, error) {
+	fields := strings.FieldsFunc(in, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r' })
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no encryption key defined")
+	}
+
+	keyring := &Keyring{byID: map[string]*key{}}
+	for i, field := range fields {
+		secret, err := hex.DecodeString(field)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key #%d is not hex encoded: %w", i+1, err)
+		}
+
+		k, err := newKey(secret)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key #%d: %w", i+1, err)
+		}
+
+		if _, found := keyring.byID[k.id]; found {
+			return nil, fmt.Errorf("encryption key #%d (%s) is defined twice", i+1, k.id)
+		}
+
+		keyring.keys = append(keyring.keys, k)
+		keyring.byID[k.id] = k
+	}
+
+	return keyring, nil
+}
+
+// LoadKeyring reads the keys from the file `keyFile` if set, otherwise from the environment variable `keyEnv`, see
+// [ParseKeyring]
+func LoadKeyring(keyFile, keyEnv string) (*Keyring, error) {
+	if keyFile != "" {
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read encryption key file: %w", err)
+		}
+
+		keyring, err := ParseKeyring(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("encryption key file %q: %w", keyFile, err)
+		}
+		return keyring, nil
+	}
+
+	keyring, err := ParseKeyring(os.Getenv(keyEnv))
+	if err != nil {
+		return nil, fmt.Errorf("encryption key environment variable %q: %w", keyEnv, err)
+	}
+	return keyring, nil
+}
+
+// WriteKeyID returns the ID of the key encrypting the objects written
+func (k *Keyring) WriteKeyID() string {
+	return k.keys[0].id
+}
+
+func (k *Keyring) writeKey() *key {
+	return k.keys[0]
+}
+
+func (k *Keyring) get(id string) (*key, error) {
+	found, ok := k.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("encryption key %s is not in the keyring", id)
+	}
+	return found, nil
+}
+
+func newKey(secret []byte) (*key, error) {
+	if len(secret) != KeySize {
+		return nil, fmt.Errorf("key must be %d bytes long, got %d", KeySize, len(secret))
+	}
+
+	block, err := aes.NewCipher(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(secret)
+	return &key{id: hex.EncodeToString(hash[:8]), aead: aead}, nil
+}
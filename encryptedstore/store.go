@@ -0,0 +1,263 @@
+// Package encryptedstore encrypts the objects of block stores at rest with AES-256-GCM. Encryption is enabled by
+// adding the keys to the store URL, like 'gs://bucket/merged-blocks?encryption-key-file=/etc/firehose/blocks.key', so
+// the apps and tools reading and writing block stores through [NewDBinStore] handle encrypted stores transparently.
+package encryptedstore
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/streamingfast/dstore"
+)
+
+const (
+	// KeyFileParam is the store URL query parameter giving the path of the file holding the encryption keys, see
+	// [ParseKeyring] for the format
+	KeyFileParam = "encryption-key-file"
+	// KeyEnvParam is the store URL query parameter giving the name of the environment variable holding the encryption
+	// keys, see [ParseKeyring] for the format
+	KeyEnvParam = "encryption-key-env"
+)
+
+// Store encrypts the objects written to the wrapped store and decrypts the objects read from it. Objects are compressed
+// before being encrypted, the wrapped store storing the encrypted objects as is.
+type Store struct {
+	dstore.Store
+
+	keys            *Keyring
+	compressionType string
+}
+
+// NewDBinStore is [dstore.NewDBinStore] returning an encrypted store if the keys are given in `baseURL`
+func NewDBinStore(baseURL string, opts ...dstore.Option) (dstore.Store, error) {
+	return NewStore(baseURL, "dbin.zst", "zstd", false, opts...)
+}
+
+// NewStore is [dstore.NewStore] returning an encrypted store if the keys are given in `baseURL`, through the
+// KeyFileParam or KeyEnvParam query parameter
+func NewStore(baseURL, extension, compressionType string, overwrite bool, opts ...dstore.Option) (dstore.Store, error) {
+	storeURL, keys, err := parseURL(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if keys == nil {
+		return dstore.NewStore(baseURL, extension, compressionType, overwrite, opts...)
+	}
+
+	// encrypted objects do not compress, they are compressed before being encrypted
+	store, err := dstore.NewStore(storeURL, extension, "", overwrite, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return Wrap(store, compressionType, keys), nil
+}
+
+// Wrap returns `store` encrypting its objects with `keys`, compressed with `compressionType` ("zstd", "gzip" or ""
+// for none) before being encrypted. The wrapped store must not compress the objects itself.
+func Wrap(store dstore.Store, compressionType string, keys *Keyring) *Store {
+	return &Store{
+		Store:           store,
+		keys:            keys,
+		compressionType: compressionType,
+	}
+}
+
+// IsEncrypted returns true if the store URL enables encryption
+func IsEncrypted(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return false
+	}
+
+	query := u.Query()
+	return query.Get(KeyFileParam) != "" || query.Get(KeyEnvParam) != ""
+}
+
+// KeyringOf returns the keys of the encrypted store found unwrapping `store`, nil if `store` is not encrypted
+func KeyringOf(store dstore.Store) *Keyring {
+	for store != nil {
+		if encrypted, ok := store.(*Store); ok {
+			return encrypted.keys
+		}
+
+		unwrapper, ok := store.(interface{ Unwrap() dstore.Store })
+		if !ok {
+			return nil
+		}
+		store = unwrapper.Unwrap()
+	}
+	return nil
+}
+
+// parseURL returns the store URL without the encryption parameters and the keys it defines, nil if it does not enable
+// encryption
+func parseURL(baseURL string) (string, *Keyring, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := u.Query()
+	keyFile, keyEnv := query.Get(KeyFileParam), query.Get(KeyEnvParam)
+	if keyFile == "" && keyEnv == "" {
+		return baseURL, nil, nil
+	}
+
+	if keyFile != "" && keyEnv != "" {
+		return "", nil, fmt.Errorf("store URL %q: only one of %q or %q can be set", baseURL, KeyFileParam, KeyEnvParam)
+	}
+
+	keys, err := LoadKeyring(keyFile, keyEnv)
+	if err != nil {
+		return "", nil, fmt.Errorf("store URL %q: %w", baseURL, err)
+	}
+
+	query.Del(KeyFileParam)
+	query.Del(KeyEnvParam)
+	u.RawQuery = query.Encode()
+
+	return u.String(), keys, nil
+}
+
+func (s *Store) OpenObject(ctx context.Context, name string) (io.ReadCloser, error) {
+	reader, err := s.Store.OpenObject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted, err := newDecrypter(reader, s.keys)
+	if err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("decrypt object %q: %w", name, err)
+	}
+
+	var out io.Reader = decrypted
+	var closeDecompressor func()
+	switch s.compressionType {
+	case "zstd":
+		decoder, err := zstd.NewReader(decrypted)
+		if err != nil {
+			reader.Close()
+			return nil, fmt.Errorf("object %q: unable to create zstd reader: %w", name, err)
+		}
+		out, closeDecompressor = decoder, decoder.Close
+	case "gzip":
+		decoder, err := gzip.NewReader(decrypted)
+		if err != nil {
+			reader.Close()
+			return nil, fmt.Errorf("object %q: unable to create gzip reader: %w", name, err)
+		}
+		out, closeDecompressor = decoder, func() { decoder.Close() }
+	}
+
+	return &readCloser{Reader: out, source: reader, closeDecompressor: closeDecompressor}, nil
+}
+
+// Unwrap returns the wrapped store, holding the encrypted objects
+func (s *Store) Unwrap() dstore.Store {
+	return s.Store
+}
+
+func (s *Store) WriteObject(ctx context.Context, base string, f io.Reader) error {
+	source := f
+	if s.compressionType != "" {
+		reader, writer := io.Pipe()
+		defer reader.Close()
+
+		go func() {
+			writer.CloseWithError(s.compress(writer, f))
+		}()
+		source = reader
+	}
+
+	encrypted, err := newEncrypter(source, s.keys.writeKey())
+	if err != nil {
+		return err
+	}
+
+	return s.Store.WriteObject(ctx, base, encrypted)
+}
+
+func (s *Store) compress(destination io.Writer, source io.Reader) error {
+	switch s.compressionType {
+	case "zstd":
+		encoder, err := zstd.NewWriter(destination)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(encoder, source); err != nil {
+			encoder.Close()
+			return err
+		}
+		return encoder.Close()
+	case "gzip":
+		encoder := gzip.NewWriter(destination)
+		if _, err := io.Copy(encoder, source); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+
+	return fmt.Errorf("unsupported compression type %q", s.compressionType)
+}
+
+// PushLocalFile writes the local file as an encrypted object then deletes it, the wrapped store would push it as is
+func (s *Store) PushLocalFile(ctx context.Context, localFile, toBaseName string) error {
+	f, err := os.Open(localFile)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+
+	err = s.WriteObject(ctx, toBaseName, f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("writing %q to storage %q: %w", localFile, s.ObjectPath(toBaseName), err)
+	}
+
+	return os.Remove(localFile)
+}
+
+func (s *Store) SubStore(subFolder string) (dstore.Store, error) {
+	store, err := s.Store.SubStore(subFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	return Wrap(store, s.compressionType, s.keys), nil
+}
+
+// Clone clones the wrapped store if it's clonable, the clone encrypting its objects with the same keys
+func (s *Store) Clone(ctx context.Context, opts ...dstore.Option) (dstore.Store, error) {
+	clonable, ok := s.Store.(dstore.Clonable)
+	if !ok {
+		return s, nil
+	}
+
+	store, err := clonable.Clone(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return Wrap(store, s.compressionType, s.keys), nil
+}
+
+type readCloser struct {
+	io.Reader
+
+	source            io.ReadCloser
+	closeDecompressor func()
+}
+
+func (r *readCloser) Close() error {
+	if r.closeDecompressor != nil {
+		r.closeDecompressor()
+	}
+	return r.source.Close()
+}
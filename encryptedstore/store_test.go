@@ -0,0 +1,207 @@
+package encryptedstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newHexKey(t *testing.T) string {
+	t.Helper()
+
+	secret := make([]byte, KeySize)
+	_, err := rand.Read(secret)
+	require.NoError(t, err)
+	return hex.EncodeToString(secret)
+}
+
+func newContent(t *testing.T, size int) []byte {
+	t.Helper()
+
+	content := make([]byte, size)
+	_, err := rand.Read(content)
+	require.NoError(t, err)
+	return content
+}
+
+func readAll(t *testing.T, store dstore.Store, name string) ([]byte, error) {
+	t.Helper()
+
+	reader, err := store.OpenObject(context.Background(), name)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+func TestStore_EncryptsObjects(t *testing.T) {
+	t.Setenv("TEST_BLOCKS_KEY", newHexKey(t))
+
+	for _, compressionType := range []string{"zstd", "gzip", ""} {
+		t.Run("compression "+compressionType, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := NewStore(dir+"?"+KeyEnvParam+"=TEST_BLOCKS_KEY", "dbin.zst", compressionType, false)
+			require.NoError(t, err)
+			require.IsType(t, &Store{}, store)
+
+			plainStore, err := dstore.NewStore(dir, "dbin.zst", "", false)
+			require.NoError(t, err)
+
+			for _, size := range []int{0, 1, chunkSize - 1, chunkSize, 2*chunkSize + 5} {
+				name := fmt.Sprintf("%010d", size)
+				content := newContent(t, size)
+				require.NoError(t, store.WriteObject(context.Background(), name, bytes.NewReader(content)))
+
+				read, err := readAll(t, store, name)
+				require.NoError(t, err, "size %d", size)
+				assert.Equal(t, content, read, "size %d", size)
+
+				stored, err := readAll(t, plainStore, name)
+				require.NoError(t, err)
+				assert.True(t, bytes.HasPrefix(stored, magic))
+				if size >= 16 {
+					assert.NotContains(t, string(stored), string(content))
+				}
+			}
+		})
+	}
+}
+
+func TestStore_RotatesKeys(t *testing.T) {
+	oldKey, newKey := newHexKey(t), newHexKey(t)
+	dir := t.TempDir()
+
+	keyFile := filepath.Join(t.TempDir(), "blocks.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte(oldKey+"\n"), 0600))
+
+	oldStore, err := NewDBinStore(dir + "?" + KeyFileParam + "=" + keyFile)
+	require.NoError(t, err)
+	require.NoError(t, oldStore.WriteObject(context.Background(), "0000000100", bytes.NewReader([]byte("old bundle"))))
+
+	require.NoError(t, os.WriteFile(keyFile, []byte(newKey+"\n"+oldKey+"\n"), 0600))
+	rotatedStore, err := NewDBinStore(dir + "?" + KeyFileParam + "=" + keyFile)
+	require.NoError(t, err)
+	require.NoError(t, rotatedStore.WriteObject(context.Background(), "0000000200", bytes.NewReader([]byte("new bundle"))))
+
+	read, err := readAll(t, rotatedStore, "0000000100")
+	require.NoError(t, err)
+	assert.Equal(t, "old bundle", string(read))
+
+	read, err = readAll(t, rotatedStore, "0000000200")
+	require.NoError(t, err)
+	assert.Equal(t, "new bundle", string(read))
+
+	_, err = readAll(t, oldStore, "0000000200")
+	assert.ErrorContains(t, err, "is not in the keyring")
+}
+
+func TestStore_RejectsTamperedObjects(t *testing.T) {
+	t.Setenv("TEST_BLOCKS_KEY", newHexKey(t))
+	dir := t.TempDir()
+
+	store, err := NewDBinStore(dir + "?" + KeyEnvParam + "=TEST_BLOCKS_KEY")
+	require.NoError(t, err)
+
+	content := newContent(t, 3*chunkSize)
+	require.NoError(t, store.WriteObject(context.Background(), "0000000100", bytes.NewReader(content)))
+
+	path := filepath.Join(dir, "0000000100.dbin.zst")
+	stored, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	tampered := bytes.Clone(stored)
+	tampered[len(tampered)/2] ^= 0xff
+	require.NoError(t, os.WriteFile(path, tampered, 0644))
+	_, err = readAll(t, store, "0000000100")
+	assert.ErrorContains(t, err, "message authentication failed")
+
+	// truncated on a chunk boundary, the final chunk missing
+	header := len(magic) + 1 + 16 + noncePrefixSize
+	require.NoError(t, os.WriteFile(path, stored[:header+chunkSize+16], 0644))
+	_, err = readAll(t, store, "0000000100")
+	assert.ErrorContains(t, err, "object is truncated after chunk 1")
+
+	plainStore, err := dstore.NewDBinStore(dir)
+	require.NoError(t, err)
+	require.NoError(t, plainStore.WriteObject(context.Background(), "0000000200", bytes.NewReader([]byte("plain bundle"))))
+	_, err = store.OpenObject(context.Background(), "0000000200")
+	assert.ErrorIs(t, err, ErrNotEncrypted)
+}
+
+func TestStore_PushLocalFile(t *testing.T) {
+	t.Setenv("TEST_BLOCKS_KEY", newHexKey(t))
+	dir := t.TempDir()
+
+	store, err := NewDBinStore(dir + "?" + KeyEnvParam + "=TEST_BLOCKS_KEY")
+	require.NoError(t, err)
+
+	localFile := filepath.Join(t.TempDir(), "0000000100-block.dbin")
+	require.NoError(t, os.WriteFile(localFile, []byte("one block"), 0644))
+	require.NoError(t, store.PushLocalFile(context.Background(), localFile, "0000000100-block"))
+
+	assert.NoFileExists(t, localFile)
+
+	read, err := readAll(t, store, "0000000100-block")
+	require.NoError(t, err)
+	assert.Equal(t, "one block", string(read))
+
+	var meteredBytes int
+	clone, err := store.(dstore.Clonable).Clone(context.Background(), dstore.WithCompressedReadCallback(func(_ context.Context, n int) { meteredBytes += n }))
+	require.NoError(t, err)
+
+	read, err = readAll(t, clone, "0000000100-block")
+	require.NoError(t, err)
+	assert.Equal(t, "one block", string(read))
+	assert.NotZero(t, meteredBytes)
+}
+
+func TestNewStore_URL(t *testing.T) {
+	t.Setenv("TEST_BLOCKS_KEY", newHexKey(t))
+
+	assert.True(t, IsEncrypted("gs://bucket/merged-blocks?encryption-key-env=TEST_BLOCKS_KEY"))
+	assert.False(t, IsEncrypted("gs://bucket/merged-blocks"))
+
+	store, err := NewDBinStore("file:///data/merged-blocks?encryption-key-env=TEST_BLOCKS_KEY")
+	require.NoError(t, err)
+	assert.Equal(t, "file:///data/merged-blocks", store.BaseURL().String())
+
+	store, err = NewDBinStore("/data/merged-blocks")
+	require.NoError(t, err)
+	assert.IsType(t, &dstore.LocalStore{}, store)
+
+	_, err = NewDBinStore("/data/merged-blocks?encryption-key-env=TEST_BLOCKS_KEY&encryption-key-file=/data/blocks.key")
+	assert.ErrorContains(t, err, "only one of")
+
+	_, err = NewDBinStore("/data/merged-blocks?encryption-key-env=TEST_MISSING_BLOCKS_KEY")
+	assert.ErrorContains(t, err, "no encryption key defined")
+}
+
+func TestParseKeyring(t *testing.T) {
+	first, second := newHexKey(t), newHexKey(t)
+
+	keyring, err := ParseKeyring(first + ", " + second + "\n")
+	require.NoError(t, err)
+	assert.Len(t, keyring.keys, 2)
+	assert.Len(t, keyring.WriteKeyID(), 16)
+
+	_, err = ParseKeyring(first + "," + first)
+	assert.ErrorContains(t, err, "is defined twice")
+
+	_, err = ParseKeyring("deadbeef")
+	assert.ErrorContains(t, err, "key must be 32 bytes long, got 4")
+
+	_, err = ParseKeyring("not-hex")
+	assert.ErrorContains(t, err, "is not hex encoded")
+}
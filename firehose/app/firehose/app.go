@@ -30,25 +30,57 @@ import (
 	"github.com/streamingfast/dmetrics"
 	"github.com/streamingfast/dstore"
 	firecore "github.com/streamingfast/firehose-core"
+	"github.com/streamingfast/firehose-core/blockscache"
+	"github.com/streamingfast/firehose-core/encryptedstore"
 	"github.com/streamingfast/firehose-core/firehose"
 	"github.com/streamingfast/firehose-core/firehose/info"
 	"github.com/streamingfast/firehose-core/firehose/metrics"
 	"github.com/streamingfast/firehose-core/firehose/server"
+	"github.com/streamingfast/firehose-core/forkarchive"
+	"github.com/streamingfast/firehose-core/sidecar"
+	"github.com/streamingfast/firehose-core/streamablestore"
+	"github.com/streamingfast/firehose-core/tieredstore"
+	"github.com/streamingfast/firehose-core/types"
 	"github.com/streamingfast/logging"
 	"github.com/streamingfast/shutter"
 	"go.uber.org/atomic"
 	"go.uber.org/zap"
 )
 
+// PrimaryMergedBlocksTier is the name of the tier of MergedBlocksStoreURL when there are merged blocks store tiers
+const PrimaryMergedBlocksTier = "primary"
+
+// mergedBlocksHeadRefreshInterval is how often the last merged block is resolved when there is no live source
+const mergedBlocksHeadRefreshInterval = 30 * time.Second
+
 type Config struct {
-	MergedBlocksStoreURL    string
-	OneBlocksStoreURL       string
-	ForkedBlocksStoreURL    string
-	BlockStreamAddr         string        // gRPC endpoint to get real-time blocks, can be "" in which live streams is disabled
-	GRPCListenAddr          string        // gRPC address where this app will listen to
-	GRPCShutdownGracePeriod time.Duration // The duration we allow for gRPC connections to terminate gracefully prior forcing shutdown
-	ServiceDiscoveryURL     *url.URL
-	ServerOptions           []server.Option `json:"-"`
+	MergedBlocksStoreURL string
+	// MergedBlocksBundleSize is the bundle size of the merged blocks stores when they do not define it in their
+	// metadata, [types.DefaultBundleSize] if 0 (see [types.ResolveBundleSize])
+	MergedBlocksBundleSize uint64
+	OneBlocksStoreURL      string
+	ForkedBlocksStoreURL   string
+	// ForkedBlocksArchiveStoreURL is the forked blocks archive written by the merger used by single block fetches, if set
+	ForkedBlocksArchiveStoreURL string
+	// MergedBlocksSidecarStoreURL is the store of merged blocks bundle sidecars used by single block fetches, if set
+	MergedBlocksSidecarStoreURL string
+	// MergedBlocksChecksumVerification is how bundles not matching the checksum of their sidecar are handled (see
+	// [sidecar.VerifyMode]), bundles are only verified when MergedBlocksSidecarStoreURL is set
+	MergedBlocksChecksumVerification string
+	// BlocksCacheDir is the local directory caching the merged blocks bundles read by streams and single block
+	// fetches, the cache is disabled if empty. Bundles are cached encrypted when the merged blocks store (or one of its
+	// tiers) is encrypted.
+	BlocksCacheDir                 string
+	BlocksCacheMaxRecentEntryBytes int64
+	BlocksCacheMaxEntryByAgeBytes  int64
+	// MergedBlocksStoreTierURLs are the merged blocks stores read after MergedBlocksStoreURL by streams and single
+	// block fetches, see [tieredstore.ParseTierURL]
+	MergedBlocksStoreTierURLs []string
+	BlockStreamAddr           string        // gRPC endpoint to get real-time blocks, can be "" in which live streams is disabled
+	GRPCListenAddr            string        // gRPC address where this app will listen to
+	GRPCShutdownGracePeriod   time.Duration // The duration we allow for gRPC connections to terminate gracefully prior forcing shutdown
+	ServiceDiscoveryURL       *url.URL
+	ServerOptions             []server.Option `json:"-"`
 }
 
 type RegisterServiceExtensionFunc func(server dgrpcserver.Server,
@@ -91,18 +123,21 @@ func New(logger *zap.Logger, tracer logging.Tracer, config *Config, modules *Mod
 
 func (a *App) Run() error {
 	dmetrics.Register(metrics.Metricset)
+	if a.config.BlocksCacheDir != "" {
+		dmetrics.Register(blockscache.MetricSet)
+	}
 
 	a.logger.Info("running firehose", zap.Reflect("config", a.config))
 	if err := a.config.Validate(); err != nil {
 		return fmt.Errorf("invalid app config: %w", err)
 	}
 
-	mergedBlocksStore, err := dstore.NewDBinStore(a.config.MergedBlocksStoreURL)
+	mergedBlocksStore, err := encryptedstore.NewDBinStore(a.config.MergedBlocksStoreURL)
 	if err != nil {
 		return fmt.Errorf("failed setting up block store from url %q: %w", a.config.MergedBlocksStoreURL, err)
 	}
 
-	oneBlocksStore, err := dstore.NewDBinStore(a.config.OneBlocksStoreURL)
+	oneBlocksStore, err := encryptedstore.NewDBinStore(a.config.OneBlocksStoreURL)
 	if err != nil {
 		return fmt.Errorf("failed setting up block store from url %q: %w", a.config.OneBlocksStoreURL, err)
 	}
@@ -110,12 +145,40 @@ func (a *App) Run() error {
 	// set to empty store interface if URL is ""
 	var forkedBlocksStore dstore.Store
 	if a.config.ForkedBlocksStoreURL != "" {
-		forkedBlocksStore, err = dstore.NewDBinStore(a.config.ForkedBlocksStoreURL)
+		forkedBlocksStore, err = encryptedstore.NewDBinStore(a.config.ForkedBlocksStoreURL)
 		if err != nil {
 			return fmt.Errorf("failed setting up block store from url %q: %w", a.config.ForkedBlocksStoreURL, err)
 		}
 	}
 
+	var forkedBlocksArchiveStore dstore.Store
+	if a.config.ForkedBlocksArchiveStoreURL != "" {
+		forkedBlocksArchiveStore, err = forkarchive.NewStore(a.config.ForkedBlocksArchiveStoreURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	bundleSize, err := types.ResolveBundleSize(context.Background(), mergedBlocksStore, a.config.MergedBlocksBundleSize)
+	if err != nil {
+		return fmt.Errorf("merged blocks bundle size: %w", err)
+	}
+
+	var sidecarStore dstore.Store
+	if a.config.MergedBlocksSidecarStoreURL != "" {
+		sidecarStore, err = sidecar.NewStore(a.config.MergedBlocksSidecarStoreURL)
+		if err != nil {
+			return err
+		}
+	}
+
+	// streams and single block fetches read bundles from the tiers, through the blocks cache, verified against their
+	// sidecar checksum
+	readMergedBlocksStore, err := a.readMergedBlocksStore(mergedBlocksStore, bundleSize, sidecarStore)
+	if err != nil {
+		return err
+	}
+
 	withLive := a.config.BlockStreamAddr != ""
 
 	var forkableHub *hub.ForkableHub
@@ -140,16 +203,19 @@ func (a *App) Run() error {
 		forkableHub.OnTerminated(a.Shutdown)
 
 		go forkableHub.Run()
+	} else {
+		go a.trackMergedBlocksHead(mergedBlocksStore, bundleSize, sidecarStore)
 	}
 
 	streamFactory := firecore.NewStreamFactory(
-		mergedBlocksStore,
+		readMergedBlocksStore,
+		bundleSize,
 		forkedBlocksStore,
 		forkableHub,
 		a.modules.TransformRegistry,
 	)
 
-	blockGetter := firehose.NewBlockGetter(mergedBlocksStore, forkedBlocksStore, forkableHub)
+	blockGetter := firehose.NewBlockGetter(readMergedBlocksStore, bundleSize, forkedBlocksStore, forkedBlocksArchiveStore, sidecarStore, forkableHub)
 
 	firehoseServer := server.New(
 		a.modules.TransformRegistry,
@@ -169,10 +235,12 @@ func (a *App) Run() error {
 	})
 	firehoseServer.OnTerminated(a.Shutdown)
 
+	// extensions and the info server read blocks with bstream, which reads 100 blocks bundles
+	streamableMergedBlocksStore := streamablestore.NewStore(mergedBlocksStore, bundleSize)
 	if a.modules.RegisterServiceExtension != nil {
 		a.modules.RegisterServiceExtension(
 			firehoseServer.Server,
-			mergedBlocksStore,
+			streamableMergedBlocksStore,
 			forkedBlocksStore,
 			forkableHub,
 			a.logger)
@@ -191,7 +259,7 @@ func (a *App) Run() error {
 
 		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
 		defer cancel()
-		if err := a.modules.InfoServer.Init(ctx, forkableHub, mergedBlocksStore, oneBlocksStore, a.logger); err != nil {
+		if err := a.modules.InfoServer.Init(ctx, forkableHub, streamableMergedBlocksStore, oneBlocksStore, a.logger); err != nil {
 			a.Shutdown(fmt.Errorf("cannot initialize info server: %w", err))
 		}
 
@@ -203,6 +271,92 @@ func (a *App) Run() error {
 	return nil
 }
 
+// readMergedBlocksStore returns the merged blocks store read by streams and single block fetches
+func (a *App) readMergedBlocksStore(mergedBlocksStore dstore.Store, bundleSize uint64, sidecarStore dstore.Store) (store dstore.Store, err error) {
+	store = mergedBlocksStore
+	// the blocks cache is encrypted when the merged blocks store or one of its tiers is
+	cacheKeys := encryptedstore.KeyringOf(mergedBlocksStore)
+	if len(a.config.MergedBlocksStoreTierURLs) != 0 {
+		tiers := []tieredstore.Tier{{Name: PrimaryMergedBlocksTier, Store: mergedBlocksStore, Range: types.NewOpenRange(0)}}
+		for i, tierURL := range a.config.MergedBlocksStoreTierURLs {
+			storeURL, name, blockRange, err := tieredstore.ParseTierURL(tierURL, fmt.Sprintf("tier-%d", i+1))
+			if err != nil {
+				return nil, err
+			}
+
+			tierStore, err := encryptedstore.NewDBinStore(storeURL)
+			if err != nil {
+				return nil, fmt.Errorf("failed setting up merged blocks store tier %q from url %q: %w", name, storeURL, err)
+			}
+
+			tierBundleSize, err := types.ResolveBundleSize(context.Background(), tierStore, a.config.MergedBlocksBundleSize)
+			if err != nil {
+				return nil, fmt.Errorf("merged blocks store tier %q bundle size: %w", name, err)
+			}
+			if tierBundleSize != bundleSize {
+				return nil, fmt.Errorf("merged blocks store tier %q has a bundle size of %d, all tiers must have the bundle size of the merged blocks store (%d)", name, tierBundleSize, bundleSize)
+			}
+
+			if cacheKeys == nil {
+				cacheKeys = encryptedstore.KeyringOf(tierStore)
+			}
+
+			tiers = append(tiers, tieredstore.Tier{Name: name, Store: tierStore, Range: blockRange})
+		}
+
+		store, err = tieredstore.NewStore(bundleSize, tiers...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if a.config.BlocksCacheDir != "" {
+		store, err = blockscache.NewStore(context.Background(), store, a.config.BlocksCacheDir, cacheKeys, a.config.BlocksCacheMaxRecentEntryBytes, a.config.BlocksCacheMaxEntryByAgeBytes, a.logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if sidecarStore != nil {
+		mode := sidecar.VerifyModeFail
+		if a.config.MergedBlocksChecksumVerification != "" {
+			mode, err = sidecar.ParseVerifyMode(a.config.MergedBlocksChecksumVerification)
+			if err != nil {
+				return nil, err
+			}
+		}
+		// bundles read from the blocks cache are verified too, a cached bundle not matching its checksum being evicted
+		// so that it's downloaded again by the refetch or the next read
+		store = sidecar.NewVerifyingStore(store, sidecarStore, mode, a.logger)
+	}
+
+	return store, nil
+}
+
+// trackMergedBlocksHead reports the last merged block as the head block when there is no live source, discovered
+// through the head pointer of the merged blocks store maintained by the merger
+func (a *App) trackMergedBlocksHead(mergedBlocksStore dstore.Store, bundleSize uint64, sidecarStore dstore.Store) {
+	ticker := time.NewTicker(mergedBlocksHeadRefreshInterval)
+	defer ticker.Stop()
+
+	startBlockNum := uint64(0)
+	for {
+		ref, err := firecore.LastMergedBlockRef(context.Background(), startBlockNum, bundleSize, mergedBlocksStore, sidecarStore, a.logger)
+		if err != nil {
+			a.logger.Debug("unable to resolve last merged block", zap.Error(err))
+		} else {
+			a.modules.HeadBlockNumberMetric.SetUint64(ref.Num())
+			startBlockNum = ref.Num()
+		}
+
+		select {
+		case <-a.Terminating():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // IsReady return `true` if the apps is ready to accept requests, `false` is returned
 // otherwise.
 func (a *App) IsReady(ctx context.Context) bool {
@@ -11,7 +11,10 @@ import (
 	"github.com/streamingfast/derr"
 	"github.com/streamingfast/dmetering"
 	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/forkarchive"
 	"github.com/streamingfast/firehose-core/metering"
+	"github.com/streamingfast/firehose-core/sidecar"
+	"github.com/streamingfast/firehose-core/streamablestore"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -19,19 +22,30 @@ import (
 
 type BlockGetter struct {
 	mergedBlocksStore dstore.Store
+	bundleSize        uint64
 	forkedBlocksStore dstore.Store
-	hub               *hub.ForkableHub
+	// forkedBlocksArchiveStore holds the forked blocks pruned by the merger, nil if they are not archived
+	forkedBlocksArchiveStore dstore.Store
+	// sidecarStore holds the block index sidecars of the merged blocks bundles, nil if sidecars are disabled
+	sidecarStore dstore.Store
+	hub          *hub.ForkableHub
 }
 
 func NewBlockGetter(
 	mergedBlocksStore dstore.Store,
+	bundleSize uint64,
 	forkedBlocksStore dstore.Store,
+	forkedBlocksArchiveStore dstore.Store,
+	sidecarStore dstore.Store,
 	hub *hub.ForkableHub,
 ) *BlockGetter {
 	return &BlockGetter{
-		mergedBlocksStore: mergedBlocksStore,
-		forkedBlocksStore: forkedBlocksStore,
-		hub:               hub,
+		mergedBlocksStore:        mergedBlocksStore,
+		bundleSize:               bundleSize,
+		forkedBlocksStore:        forkedBlocksStore,
+		forkedBlocksArchiveStore: forkedBlocksArchiveStore,
+		sidecarStore:             sidecarStore,
+		hub:                      hub,
 	}
 }
 
@@ -69,11 +83,26 @@ func (g *BlockGetter) Get(
 		mergedBlocksStore.SetMeter(dmetering.GetBytesMeter(ctx))
 	}
 
-	// check for block in mergedBlocksStore
+	// check for block in mergedBlocksStore, decoding only the requested block when its bundle has a sidecar (the bundle is still read up to it)
+	if g.sidecarStore != nil {
+		blk, err := sidecar.FetchBlockFromMergedBlocksStore(ctx, num, id, g.bundleSize, mergedBlocksStore, g.sidecarStore)
+		if err == nil {
+			reqLogger.Info("single block request", zap.String("source", "merged_blocks_sidecar"), zap.Bool("found", true))
+			return blk, nil
+		}
+		if errors.Is(err, sidecar.ErrChecksumMismatch) {
+			reqLogger.Warn("block does not match its sidecar checksum, reading full bundle", zap.Error(err))
+		} else {
+			reqLogger.Debug("unable to fetch block using sidecar, reading full bundle", zap.Error(err))
+		}
+	}
+
+	streamableMergedBlocksStore := streamablestore.NewStore(mergedBlocksStore, g.bundleSize)
 	err = derr.RetryContext(ctx, 3, func(ctx context.Context) error {
-		blk, err := bstream.FetchBlockFromMergedBlocksStore(ctx, num, mergedBlocksStore)
+		blk, err := bstream.FetchBlockFromMergedBlocksStore(ctx, num, streamableMergedBlocksStore)
 		if err != nil {
-			if errors.Is(err, dstore.ErrNotFound) {
+			// bundles not matching their checksum are already read again according to the verification mode
+			if errors.Is(err, dstore.ErrNotFound) || errors.Is(err, sidecar.ErrChecksumMismatch) {
 				return derr.NewFatalError(err)
 			}
 			return err
@@ -109,6 +138,28 @@ func (g *BlockGetter) Get(
 		}
 	}
 
+	// check for block in forkedBlocksArchiveStore, forked blocks pruned by the merger
+	if g.forkedBlocksArchiveStore != nil && id != "" {
+		forkedBlocksArchiveStore := g.forkedBlocksArchiveStore
+		if clonable, ok := forkedBlocksArchiveStore.(dstore.Clonable); ok {
+			var err error
+			forkedBlocksArchiveStore, err = clonable.Clone(ctx, metering.WithForkedBlockBytesReadMeteringOptions(dmetering.GetBytesMeter(ctx), logger)...)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		blk, err := forkarchive.FetchBlock(ctx, forkedBlocksArchiveStore, num, id)
+		if err == nil {
+			reqLogger.Info("single block request", zap.String("source", "forked_blocks_archive"), zap.Bool("found", true))
+			return blk, nil
+		}
+		if !errors.Is(err, dstore.ErrNotFound) {
+			reqLogger.Warn("unable to fetch block from forked blocks archive", zap.Error(err))
+			return nil, status.Error(codes.Unavailable, "unable to read forked blocks archive")
+		}
+	}
+
 	reqLogger.Info("single block request", zap.Bool("found", false), zap.Error(err))
 	return nil, status.Error(codes.NotFound, "block not found in files")
 }
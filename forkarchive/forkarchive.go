@@ -0,0 +1,208 @@
+// Package forkarchive implements the forked blocks archive, forked blocks packed into compressed bundles by the
+// merger before they are pruned from the forked blocks store, keeping historical reorg data around.
+package forkarchive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/streamingfast/bstream"
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/encryptedstore"
+)
+
+// BundleSize is the block span of a forked bundle, forked blocks being rare a forked bundle spans more blocks
+// than a merged blocks bundle
+const BundleSize uint64 = 1000
+
+func NewStore(storeURL string) (dstore.Store, error) {
+	store, err := encryptedstore.NewDBinStore(storeURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create forked blocks archive store at %q: %w", storeURL, err)
+	}
+	return store, nil
+}
+
+// Filename returns the name of the forked bundle holding blocks of the span starting at `baseBlockNum` up to
+// `highestBlockNum`. A span has a single forked bundle, replaced each time blocks of the span are archived (a span
+// can briefly have two of them when the previous one could not be deleted yet).
+func Filename(baseBlockNum, highestBlockNum uint64) string {
+	return fmt.Sprintf("%010d-%010d", baseBlockNum, highestBlockNum)
+}
+
+func baseBlockNum(num uint64) uint64 {
+	return num - num%BundleSize
+}
+
+// Write archives `blocks`, one forked bundle per span of BundleSize blocks. The blocks are added to the blocks
+// already archived for their span, a new forked bundle replacing the previous ones of the span. Blocks already
+// archived are skipped, making it safe to archive the same blocks again.
+func Write(ctx context.Context, store dstore.Store, blocks []*pbbstream.Block) error {
+	sorted := append([]*pbbstream.Block(nil), blocks...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Number < sorted[j].Number })
+
+	for len(sorted) != 0 {
+		base := baseBlockNum(sorted[0].Number)
+		end := sort.Search(len(sorted), func(i int) bool { return sorted[i].Number >= base+BundleSize })
+
+		if err := writeBundle(ctx, store, base, sorted[:end]); err != nil {
+			return err
+		}
+		sorted = sorted[end:]
+	}
+
+	return nil
+}
+
+func writeBundle(ctx context.Context, store dstore.Store, base uint64, blocks []*pbbstream.Block) error {
+	var previous []string
+	err := store.Walk(ctx, fmt.Sprintf("%010d-", base), func(filename string) error {
+		previous = append(previous, filename)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("walk forked bundles of span #%d: %w", base, err)
+	}
+
+	seen := map[string]bool{}
+	var archived []*pbbstream.Block
+	for _, filename := range previous {
+		err := readBundle(ctx, store, filename, func(block *pbbstream.Block) error {
+			if !seen[block.Id] {
+				seen[block.Id] = true
+				archived = append(archived, block)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	added := false
+	for _, block := range blocks {
+		if !seen[block.Id] {
+			seen[block.Id] = true
+			archived = append(archived, block)
+			added = true
+		}
+	}
+
+	if !added {
+		return nil
+	}
+
+	sort.SliceStable(archived, func(i, j int) bool { return archived[i].Number < archived[j].Number })
+	filename := Filename(base, archived[len(archived)-1].Number)
+
+	buffer := new(bytes.Buffer)
+	writer, err := bstream.NewDBinBlockWriter(buffer)
+	if err != nil {
+		return fmt.Errorf("new block writer: %w", err)
+	}
+
+	for _, block := range archived {
+		if err := writer.Write(block); err != nil {
+			return fmt.Errorf("write block #%d: %w", block.Number, err)
+		}
+	}
+
+	if err := store.WriteObject(ctx, filename, buffer); err != nil {
+		return fmt.Errorf("write forked bundle %q: %w", filename, err)
+	}
+
+	// the new forked bundle holds all their blocks, a bundle left behind is merged again by the next write
+	for _, previousFilename := range previous {
+		if previousFilename == filename {
+			continue
+		}
+
+		if err := store.DeleteObject(ctx, previousFilename); err != nil {
+			return fmt.Errorf("delete replaced forked bundle %q: %w", previousFilename, err)
+		}
+	}
+
+	return nil
+}
+
+// Walk calls `f` with each archived block of the forked bundles whose span starts at `prefix` (all of them if
+// empty), a block archived more than once being reported once
+func Walk(ctx context.Context, store dstore.Store, prefix string, f func(block *pbbstream.Block) error) error {
+	var filenames []string
+	err := store.Walk(ctx, prefix, func(filename string) error {
+		filenames = append(filenames, filename)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("walk forked bundles: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, filename := range filenames {
+		err := readBundle(ctx, store, filename, func(block *pbbstream.Block) error {
+			if seen[block.Id] {
+				return nil
+			}
+			seen[block.Id] = true
+
+			return f(block)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func readBundle(ctx context.Context, store dstore.Store, filename string, f func(block *pbbstream.Block) error) error {
+	reader, err := store.OpenObject(ctx, filename)
+	if err != nil {
+		return fmt.Errorf("open forked bundle %q: %w", filename, err)
+	}
+	defer reader.Close()
+
+	blockReader, err := bstream.NewDBinBlockReader(reader)
+	if err != nil {
+		return fmt.Errorf("new block reader for forked bundle %q: %w", filename, err)
+	}
+
+	for {
+		block, err := blockReader.Read()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("read forked bundle %q: %w", filename, err)
+		}
+
+		if err := f(block); err != nil {
+			return err
+		}
+	}
+}
+
+// FetchBlock returns the archived forked block `num` with `id` (the first archived block `num` if `id` is
+// empty), dstore.ErrNotFound if it's not archived
+func FetchBlock(ctx context.Context, store dstore.Store, num uint64, id string) (out *pbbstream.Block, err error) {
+	err = Walk(ctx, store, fmt.Sprintf("%010d-", baseBlockNum(num)), func(block *pbbstream.Block) error {
+		if block.Number == num && (id == "" || block.Id == id) {
+			out = block
+			return dstore.StopIteration
+		}
+		return nil
+	})
+	if err != nil && err != dstore.StopIteration {
+		return nil, err
+	}
+
+	if out == nil {
+		return nil, fmt.Errorf("forked block #%d (%s): %w", num, id, dstore.ErrNotFound)
+	}
+
+	return out, nil
+}
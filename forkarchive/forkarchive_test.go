@@ -0,0 +1,89 @@
+package forkarchive
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+func TestWrite(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	require.NoError(t, Write(ctx, store, []*pbbstream.Block{
+		testBlock(1205, "b"),
+		testBlock(998, "b"),
+		testBlock(1001, "b"),
+	}))
+
+	var filenames []string
+	require.NoError(t, store.Walk(ctx, "", func(filename string) error {
+		filenames = append(filenames, filename)
+		return nil
+	}))
+	assert.Equal(t, []string{"0000000000-0000000998", "0000001000-0000001205"}, filenames)
+
+	// archiving blocks again, as a pruning run interrupted before deleting the forked blocks would
+	require.NoError(t, Write(ctx, store, []*pbbstream.Block{testBlock(1001, "b"), testBlock(1001, "c"), testBlock(1300, "b")}))
+
+	filenames = nil
+	require.NoError(t, store.Walk(ctx, "", func(filename string) error {
+		filenames = append(filenames, filename)
+		return nil
+	}))
+	assert.Equal(t, []string{"0000000000-0000000998", "0000001000-0000001300"}, filenames, "one forked bundle per span")
+
+	var ids []string
+	require.NoError(t, Walk(ctx, store, "", func(block *pbbstream.Block) error {
+		ids = append(ids, block.Id)
+		return nil
+	}))
+	assert.ElementsMatch(t, []string{"998b", "1001b", "1001c", "1205b", "1300b"}, ids)
+}
+
+func TestFetchBlock(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore(t)
+
+	require.NoError(t, Write(ctx, store, []*pbbstream.Block{testBlock(1001, "b"), testBlock(1001, "c")}))
+
+	block, err := FetchBlock(ctx, store, 1001, "1001c")
+	require.NoError(t, err)
+	assert.Equal(t, "1001c", block.Id)
+	assert.Equal(t, "1000a", block.ParentId)
+
+	block, err = FetchBlock(ctx, store, 1001, "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1001), block.Number)
+
+	_, err = FetchBlock(ctx, store, 1001, "1001d")
+	assert.ErrorIs(t, err, dstore.ErrNotFound)
+
+	_, err = FetchBlock(ctx, store, 2001, "2001b")
+	assert.ErrorIs(t, err, dstore.ErrNotFound)
+}
+
+func newTestStore(t *testing.T) dstore.Store {
+	t.Helper()
+
+	store, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+	return store
+}
+
+func testBlock(num uint64, fork string) *pbbstream.Block {
+	return &pbbstream.Block{
+		Number:    num,
+		Id:        fmt.Sprintf("%d%s", num, fork),
+		ParentNum: num - 1,
+		ParentId:  fmt.Sprintf("%da", num-1),
+		LibNum:    num - 10,
+		Payload:   &anypb.Any{TypeUrl: "type.googleapis.com/sf.test.Block"},
+	}
+}
@@ -7,9 +7,10 @@ import (
 	"github.com/streamingfast/bstream"
 	"github.com/streamingfast/dgrpc"
 	"github.com/streamingfast/dmetrics"
-	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/encryptedstore"
 	index_builder "github.com/streamingfast/firehose-core/index-builder"
 	"github.com/streamingfast/firehose-core/index-builder/metrics"
+	"github.com/streamingfast/firehose-core/types"
 	"github.com/streamingfast/shutter"
 	"go.uber.org/zap"
 	pbhealth "google.golang.org/grpc/health/grpc_health_v1"
@@ -20,8 +21,11 @@ type Config struct {
 	StartBlockResolver   func(ctx context.Context) (uint64, error)
 	EndBlock             uint64
 	MergedBlocksStoreURL string
-	ForkedBlocksStoreURL string
-	GRPCListenAddr       string
+	// MergedBlocksBundleSize is the bundle size of the merged blocks store when it does not define it in its metadata,
+	// [types.DefaultBundleSize] if 0 (see [types.ResolveBundleSize])
+	MergedBlocksBundleSize uint64
+	ForkedBlocksStoreURL   string
+	GRPCListenAddr         string
 }
 
 type App struct {
@@ -38,7 +42,7 @@ func New(config *Config) *App {
 }
 
 func (a *App) Run() error {
-	blockStore, err := dstore.NewDBinStore(a.config.MergedBlocksStoreURL)
+	blockStore, err := encryptedstore.NewDBinStore(a.config.MergedBlocksStoreURL)
 	if err != nil {
 		return err
 	}
@@ -48,6 +52,11 @@ func (a *App) Run() error {
 		cancel()
 	})
 
+	bundleSize, err := types.ResolveBundleSize(ctx, blockStore, a.config.MergedBlocksBundleSize)
+	if err != nil {
+		return fmt.Errorf("merged blocks bundle size: %w", err)
+	}
+
 	startBlock, err := a.config.StartBlockResolver(ctx)
 	if err != nil {
 		return fmt.Errorf("resolve start block: %w", err)
@@ -59,6 +68,7 @@ func (a *App) Run() error {
 		startBlock,
 		a.config.EndBlock,
 		blockStore,
+		bundleSize,
 	)
 
 	gs, err := dgrpc.NewInternalClient(a.config.GRPCListenAddr)
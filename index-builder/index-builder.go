@@ -27,15 +27,17 @@ type IndexBuilder struct {
 	handler bstream.Handler
 
 	blocksStore dstore.Store
+	bundleSize  uint64
 }
 
-func NewIndexBuilder(logger *zap.Logger, handler bstream.Handler, startBlockNum, stopBlockNum uint64, blockStore dstore.Store) *IndexBuilder {
+func NewIndexBuilder(logger *zap.Logger, handler bstream.Handler, startBlockNum, stopBlockNum uint64, blockStore dstore.Store, bundleSize uint64) *IndexBuilder {
 	return &IndexBuilder{
 		Shutter:       shutter.New(),
 		startBlockNum: startBlockNum,
 		stopBlockNum:  stopBlockNum,
 		handler:       handler,
 		blocksStore:   blockStore,
+		bundleSize:    bundleSize,
 
 		logger: logger,
 	}
@@ -57,6 +59,7 @@ func (app *IndexBuilder) launch() error {
 
 	streamFactory := firecore.NewStreamFactory(
 		app.blocksStore,
+		app.bundleSize,
 		nil,
 		nil,
 		nil,
@@ -0,0 +1,99 @@
+package lease
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Elector keeps trying to acquire the lock, renewing it while it's held. A follower takes over at most
+// `ttl + ttl/3` after the leader stopped renewing the lease.
+type Elector struct {
+	lock     *Lock
+	onChange func(leader bool)
+	logger   *zap.Logger
+
+	leader atomic.Bool
+}
+
+// NewElector returns an elector on `lock`, `onChange` (optional) is called each time leadership is gained or lost
+func NewElector(lock *Lock, onChange func(leader bool), logger *zap.Logger) *Elector {
+	return &Elector{
+		lock:     lock,
+		onChange: onChange,
+		logger:   logger.With(zap.String("holder", lock.Holder())),
+	}
+}
+
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run campaigns until `ctx` is done, at which point the lease is released if it's held
+func (e *Elector) Run(ctx context.Context) {
+	interval := e.lock.TTL() / 3
+	e.logger.Info("starting leader election", zap.Duration("ttl", e.lock.TTL()), zap.Duration("interval", interval))
+
+	var lastRenewal time.Time
+	for {
+		acquired, err := e.lock.TryAcquire(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			e.logger.Warn("unable to acquire or renew lease", zap.Bool("leader", e.IsLeader()), zap.Error(err))
+
+			// Step down before the lease expires as others are allowed to take over once it does
+			if e.IsLeader() && time.Since(lastRenewal) > e.lock.TTL()-interval {
+				e.setLeader(false)
+			}
+
+		case err == nil:
+			if acquired {
+				lastRenewal = time.Now()
+			}
+			e.setLeader(acquired)
+		}
+
+		select {
+		case <-ctx.Done():
+			e.release()
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (e *Elector) release() {
+	if !e.IsLeader() {
+		return
+	}
+
+	e.setLeader(false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := e.lock.Release(ctx); err != nil {
+		e.logger.Warn("unable to release lease, others will take over once it expires", zap.Error(err))
+		return
+	}
+
+	e.logger.Info("released lease")
+}
+
+func (e *Elector) setLeader(leader bool) {
+	if e.leader.Swap(leader) == leader {
+		return
+	}
+
+	if leader {
+		e.logger.Info("acquired lease, now leader")
+	} else {
+		e.logger.Info("lost lease, now follower")
+	}
+
+	if e.onChange != nil {
+		e.onChange(leader)
+	}
+}
@@ -0,0 +1,180 @@
+// Package lease implements best-effort lease based locking on top of a [dstore.Store], used to elect a single
+// active writer among redundant processes sharing the same storage.
+//
+// Object stores do not offer compare-and-swap through [dstore.Store], so a lease is acquired by writing the
+// lease object then reading it back after a settle delay, the last writer winning. Lease expiration is based
+// on the holder's wall clock, hosts sharing a lease are expected to have reasonably synchronized clocks.
+package lease
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/streamingfast/dstore"
+)
+
+// Record is the content of the lease object
+type Record struct {
+	Holder     string    `json:"holder"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	RenewedAt  time.Time `json:"renewed_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (r *Record) expired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
+
+type Lock struct {
+	store  dstore.Store
+	name   string
+	holder string
+	ttl    time.Duration
+
+	// settleDelay is waited between writing the lease object and reading it back when taking over the lease,
+	// giving a concurrent acquisition the time to land so that a single holder wins
+	settleDelay time.Duration
+	now         func() time.Time
+
+	acquiredAt time.Time
+}
+
+// NewLock returns a lock on the object `name` of `store`, held for `ttl` by `holder` once acquired unless renewed
+func NewLock(store dstore.Store, name string, holder string, ttl time.Duration) *Lock {
+	return &Lock{
+		store:       store,
+		name:        name,
+		holder:      holder,
+		ttl:         ttl,
+		settleDelay: min(2*time.Second, ttl/10),
+		now:         time.Now,
+	}
+}
+
+// NewStore returns the store holding lease objects for `storeURL`, a sibling of it (`<path>-<suffix>`) so that
+// the lease objects are not listed among the store's own files
+func NewStore(storeURL string, suffix string) (dstore.Store, error) {
+	u, err := url.Parse(storeURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse store URL %q: %w", storeURL, err)
+	}
+
+	u.Path += "-" + suffix
+	return dstore.NewSimpleStore(u.String())
+}
+
+func (l *Lock) Holder() string {
+	return l.holder
+}
+
+func (l *Lock) TTL() time.Duration {
+	return l.ttl
+}
+
+// TryAcquire acquires the lease if it's free, expired or already held by us (renewing it), returns whether
+// we hold the lease.
+func (l *Lock) TryAcquire(ctx context.Context) (bool, error) {
+	current, err := l.Current(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	now := l.now()
+	if current != nil && current.Holder != l.holder && !current.expired(now) {
+		return false, nil
+	}
+
+	renewing := current != nil && current.Holder == l.holder && !l.acquiredAt.IsZero()
+	if !renewing {
+		l.acquiredAt = now
+	}
+
+	if err := l.write(ctx, &Record{Holder: l.holder, AcquiredAt: l.acquiredAt, RenewedAt: now, ExpiresAt: now.Add(l.ttl)}); err != nil {
+		return false, err
+	}
+
+	if renewing {
+		return true, nil
+	}
+
+	select {
+	case <-time.After(l.settleDelay):
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+
+	current, err = l.Current(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if current == nil || current.Holder != l.holder {
+		l.acquiredAt = time.Time{}
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// Release deletes the lease object if we hold it, letting another process acquire it right away
+func (l *Lock) Release(ctx context.Context) error {
+	current, err := l.Current(ctx)
+	if err != nil {
+		return err
+	}
+
+	l.acquiredAt = time.Time{}
+	if current == nil || current.Holder != l.holder {
+		return nil
+	}
+
+	if err := l.store.DeleteObject(ctx, l.name); err != nil && !errors.Is(err, dstore.ErrNotFound) {
+		return fmt.Errorf("delete lease %q: %w", l.name, err)
+	}
+
+	return nil
+}
+
+// Current returns the lease currently in the store, nil if there is none
+func (l *Lock) Current(ctx context.Context) (*Record, error) {
+	reader, err := l.store.OpenObject(ctx, l.name)
+	if err != nil {
+		if errors.Is(err, dstore.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("open lease %q: %w", l.name, err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read lease %q: %w", l.name, err)
+	}
+
+	record := &Record{}
+	if err := json.Unmarshal(content, record); err != nil {
+		return nil, fmt.Errorf("unmarshal lease %q: %w", l.name, err)
+	}
+
+	return record, nil
+}
+
+func (l *Lock) write(ctx context.Context, record *Record) error {
+	content, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("marshal lease: %w", err)
+	}
+
+	if err := l.store.WriteObject(ctx, l.name, bytes.NewReader(content)); err != nil {
+		return fmt.Errorf("write lease %q: %w", l.name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package lease
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+	store, err := dstore.NewSimpleStore("file://" + t.TempDir())
+	require.NoError(t, err)
+
+	first := NewLock(store, "leader.json", "first", time.Second)
+	second := NewLock(store, "leader.json", "second", time.Second)
+
+	assertAcquire(t, first, true)
+	assertAcquire(t, second, false)
+	assertAcquire(t, first, true)
+
+	// Lease expired from the second's point of view
+	second.now = func() time.Time { return time.Now().Add(2 * time.Second) }
+	assertAcquire(t, second, true)
+	assertAcquire(t, first, false)
+
+	current, err := first.Current(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "second", current.Holder)
+
+	require.NoError(t, first.Release(ctx))
+	current, err = first.Current(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "second", current.Holder, "release by a non-holder must not delete the lease")
+
+	require.NoError(t, second.Release(ctx))
+	current, err = first.Current(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, current)
+
+	assertAcquire(t, first, true)
+}
+
+func TestElector(t *testing.T) {
+	store, err := dstore.NewSimpleStore("file://" + t.TempDir())
+	require.NoError(t, err)
+
+	changes := make(chan bool, 10)
+	elector := NewElector(NewLock(store, "leader.json", "first", 300*time.Millisecond), func(leader bool) { changes <- leader }, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		elector.Run(ctx)
+		close(done)
+	}()
+
+	assert.True(t, <-changes)
+	assert.True(t, elector.IsLeader())
+
+	cancel()
+	<-done
+	assert.False(t, <-changes)
+
+	current, err := NewLock(store, "leader.json", "other", time.Second).Current(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, current)
+}
+
+func TestNewStore(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewStore("file://"+dir+"/one-blocks", "leases")
+	require.NoError(t, err)
+	assert.Equal(t, dir+"/one-blocks-leases", store.BaseURL().Path)
+}
+
+func assertAcquire(t *testing.T, lock *Lock, expected bool) {
+	t.Helper()
+
+	acquired, err := lock.TryAcquire(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, expected, acquired)
+}
@@ -2,6 +2,7 @@ package firecore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 
@@ -9,6 +10,8 @@ import (
 	"github.com/streamingfast/bstream"
 	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
 	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/sidecar"
+	"github.com/streamingfast/firehose-core/types"
 	"go.uber.org/zap"
 )
 
@@ -16,6 +19,10 @@ type MergedBlocksWriter struct {
 	Store        dstore.Store
 	LowBlockNum  uint64
 	StopBlockNum uint64
+	// BundleSize is the number of blocks per bundle, [types.DefaultBundleSize] if 0
+	BundleSize uint64
+	// SidecarStore receives the sidecar (block index and checksums) of each written bundle, if set
+	SidecarStore dstore.Store
 
 	blocks []*pbbstream.Block
 	Logger *zap.Logger
@@ -33,16 +40,20 @@ func (w *MergedBlocksWriter) ProcessBlock(blk *pbbstream.Block, obj interface{})
 		blk = b
 	}
 
-	if w.LowBlockNum == 0 && blk.Number > 99 { // initial block
-		if blk.Number%100 != 0 && blk.Number != bstream.GetProtocolFirstStreamableBlock {
+	bundleSize := w.bundleSize()
+	lastBundleBlock := w.LowBlockNum + bundleSize - 1
+
+	if w.LowBlockNum == 0 && blk.Number >= bundleSize { // initial block
+		if blk.Number%bundleSize != 0 && blk.Number != bstream.GetProtocolFirstStreamableBlock {
 			return fmt.Errorf("received unexpected block %s (not a boundary, not the first streamable block %d)", blk, bstream.GetProtocolFirstStreamableBlock)
 		}
-		w.LowBlockNum = LowBoundary(blk.Number)
+		w.LowBlockNum = LowBoundaryForBundleSize(blk.Number, bundleSize)
+		lastBundleBlock = w.LowBlockNum + bundleSize - 1
 		w.Logger.Debug("setting initial boundary to %d upon seeing block %s", zap.Uint64("low_boundary", w.LowBlockNum), zap.Uint64("blk_num", blk.Number))
 	}
 
-	if blk.Number > w.LowBlockNum+99 {
-		w.Logger.Debug("bundling because we saw block %s from next bundle (%d was not seen, it must not exist on this chain)", zap.Uint64("blk_num", blk.Number), zap.Uint64("last_bundle_block", w.LowBlockNum+99))
+	if blk.Number > lastBundleBlock {
+		w.Logger.Debug("bundling because we saw block %s from next bundle (%d was not seen, it must not exist on this chain)", zap.Uint64("blk_num", blk.Number), zap.Uint64("last_bundle_block", lastBundleBlock))
 		if err := w.WriteBundle(); err != nil {
 			return err
 		}
@@ -54,8 +65,8 @@ func (w *MergedBlocksWriter) ProcessBlock(blk *pbbstream.Block, obj interface{})
 
 	w.blocks = append(w.blocks, blk)
 
-	if blk.Number == w.LowBlockNum+99 {
-		w.Logger.Debug("bundling on last bundle block", zap.Uint64("last_bundle_block", w.LowBlockNum+99))
+	if blk.Number == lastBundleBlock {
+		w.Logger.Debug("bundling on last bundle block", zap.Uint64("last_bundle_block", lastBundleBlock))
 		if err := w.WriteBundle(); err != nil {
 			return err
 		}
@@ -73,41 +84,36 @@ func (w *MergedBlocksWriter) WriteBundle() error {
 		return fmt.Errorf("no blocks to write to bundle")
 	}
 
-	pr, pw := io.Pipe()
-
-	go func() {
-		var err error
-		defer func() {
-			pw.CloseWithError(err)
-		}()
-
-		blockWriter, err := bstream.NewDBinBlockWriter(pw)
-		if err != nil {
-			return
-		}
-
-		for _, blk := range w.blocks {
-			err = blockWriter.Write(blk)
-			if err != nil {
-				return
-			}
-		}
-	}()
-
-	err := w.Store.WriteObject(context.Background(), file, pr)
+	err := sidecar.WriteBlocks(context.Background(), w.Store, w.SidecarStore, w.LowBlockNum, w.blocks)
+	if errors.Is(err, sidecar.ErrBuildFailed) {
+		w.Logger.Warn("unable to build sidecar of merged bundle, previous one deleted", zap.String("filename", file), zap.Error(err))
+		err = nil
+	}
 	if err != nil {
 		w.Logger.Error("writing to store", zap.Error(err))
 	}
 
-	w.LowBlockNum += 100
+	w.LowBlockNum += w.bundleSize()
 	w.blocks = nil
 
 	return err
 }
+
+func (w *MergedBlocksWriter) bundleSize() uint64 {
+	if w.BundleSize == 0 {
+		return types.DefaultBundleSize
+	}
+	return w.BundleSize
+}
+
 func filename(num uint64) string {
 	return fmt.Sprintf("%010d", num)
 }
 
 func LowBoundary(i uint64) uint64 {
-	return i - (i % 100)
+	return LowBoundaryForBundleSize(i, types.DefaultBundleSize)
+}
+
+func LowBoundaryForBundleSize(i uint64, bundleSize uint64) uint64 {
+	return i - (i % bundleSize)
 }
@@ -17,14 +17,21 @@ package merger
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/streamingfast/bstream"
 	"github.com/streamingfast/dgrpc"
 	"github.com/streamingfast/dmetrics"
 	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/encryptedstore"
+	"github.com/streamingfast/firehose-core/firehose/client"
+	"github.com/streamingfast/firehose-core/forkarchive"
+	"github.com/streamingfast/firehose-core/internal/lease"
 	"github.com/streamingfast/firehose-core/merger"
 	"github.com/streamingfast/firehose-core/merger/metrics"
+	"github.com/streamingfast/firehose-core/sidecar"
+	"github.com/streamingfast/firehose-core/types"
 	"github.com/streamingfast/shutter"
 	"go.uber.org/zap"
 	pbhealth "google.golang.org/grpc/health/grpc_health_v1"
@@ -34,16 +41,63 @@ type Config struct {
 	StorageOneBlockFilesPath     string
 	StorageMergedBlocksFilesPath string
 	StorageForkedBlocksFilesPath string
+	// StorageForkedBlocksArchivePath is the store forked blocks are archived to before being pruned, forked blocks are
+	// deleted without being archived if empty
+	StorageForkedBlocksArchivePath string
+	// StorageMergedBlocksSidecarsPath is the store receiving the block index sidecar of each merged bundle, disabled
+	// if empty
+	StorageMergedBlocksSidecarsPath string
 
 	FilesDeleteThreads int
 
 	GRPCListenAddr string
+	// HTTPListenAddr is the address the status HTTP API listens on, disabled if empty
+	HTTPListenAddr string
 
 	PruneForkedBlocksAfter uint64
 
 	TimeBetweenPruning time.Duration
 	TimeBetweenPolling time.Duration
 	StopBlock          uint64
+
+	// BundleSize is the number of blocks per merged bundle, 0 for the merged blocks store's own bundle size
+	// (see [types.EnsureBundleSize])
+	BundleSize uint64
+
+	// CatchUpParallelism is the number of bundles merged concurrently while the merger is far behind head, 1 or less
+	// disables catch-up merging
+	CatchUpParallelism int
+	// CatchUpLiveThreshold is the block age under which the merger considers it is close to head and merges bundles
+	// one at a time
+	CatchUpLiveThreshold time.Duration
+
+	// MirrorMergedBlocksStoreURLs are stores receiving a copy of each merged bundle, each one with its own retry queue
+	MirrorMergedBlocksStoreURLs []string
+	// MirrorDeletionPolicy decides if one-block files are deleted once their bundle is in the primary merged blocks
+	// store ("primary", the default) or in all the mirror stores too ("all")
+	MirrorDeletionPolicy string
+	// MirrorReconcileWindow is the number of blocks before the first bundle to merge checked at startup for bundles
+	// missing from the mirror stores, 0 disables reconciliation
+	MirrorReconcileWindow uint64
+
+	// WriterLockTTL is the TTL of the lease the merger must hold to write to its stores, 0 disables the writer lock
+	WriterLockTTL time.Duration
+	// WriterLockStoreURL is the store holding the writer lock lease object, '<merged blocks store>-leases' if empty
+	WriterLockStoreURL string
+	// WriterLockHolder identifies this merger in the writer lock, '<hostname>-<pid>' if empty
+	WriterLockHolder string
+
+	// HoleDetectionTimeout is the time without progress on the current bundle after which the merger looks for
+	// missing one-block files, 0 disables hole detection
+	HoleDetectionTimeout time.Duration
+	// HoleFillFirehoseEndpoint is a peer Firehose endpoint missing blocks are fetched from, if set
+	HoleFillFirehoseEndpoint  string
+	HoleFillFirehoseAPIKey    string
+	HoleFillFirehoseAPIToken  string
+	HoleFillFirehosePlaintext bool
+	HoleFillFirehoseInsecure  bool
+	// HoleFillOneBlocksStoreURL is a secondary one-block files store missing blocks are fetched from, if set
+	HoleFillOneBlocksStoreURL string
 }
 
 type App struct {
@@ -64,25 +118,48 @@ func (a *App) Run() error {
 
 	dmetrics.Register(metrics.MetricSet)
 
-	oneBlockStoreStore, err := dstore.NewDBinStore(a.config.StorageOneBlockFilesPath)
+	oneBlockStoreStore, err := encryptedstore.NewDBinStore(a.config.StorageOneBlockFilesPath)
 	if err != nil {
 		return fmt.Errorf("failed to init source archive store: %w", err)
 	}
 
-	mergedBlocksStore, err := dstore.NewDBinStore(a.config.StorageMergedBlocksFilesPath)
+	mergedBlocksStore, err := encryptedstore.NewDBinStore(a.config.StorageMergedBlocksFilesPath)
 	if err != nil {
 		return fmt.Errorf("failed to init destination archive store: %w", err)
 	}
 
 	var forkedBlocksStore dstore.Store
 	if a.config.StorageForkedBlocksFilesPath != "" {
-		forkedBlocksStore, err = dstore.NewDBinStore(a.config.StorageForkedBlocksFilesPath)
+		forkedBlocksStore, err = encryptedstore.NewDBinStore(a.config.StorageForkedBlocksFilesPath)
 		if err != nil {
 			return fmt.Errorf("failed to init destination archive store: %w", err)
 		}
 	}
 
-	bundleSize := uint64(100)
+	var forkedBlocksArchiveStore dstore.Store
+	if a.config.StorageForkedBlocksArchivePath != "" {
+		if forkedBlocksStore == nil {
+			return fmt.Errorf("archiving forked blocks requires a forked blocks store")
+		}
+
+		forkedBlocksArchiveStore, err = forkarchive.NewStore(a.config.StorageForkedBlocksArchivePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	var sidecarStore dstore.Store
+	if a.config.StorageMergedBlocksSidecarsPath != "" {
+		sidecarStore, err = sidecar.NewStore(a.config.StorageMergedBlocksSidecarsPath)
+		if err != nil {
+			return fmt.Errorf("failed to init sidecar store: %w", err)
+		}
+	}
+
+	bundleSize, err := types.EnsureBundleSize(context.Background(), mergedBlocksStore, a.config.BundleSize)
+	if err != nil {
+		return fmt.Errorf("merged blocks bundle size: %w", err)
+	}
 
 	// we are setting the backoff here for dstoreIO
 	io := merger.NewDStoreIO(
@@ -91,11 +168,21 @@ func (a *App) Run() error {
 		oneBlockStoreStore,
 		mergedBlocksStore,
 		forkedBlocksStore,
+		forkedBlocksArchiveStore,
+		sidecarStore,
 		5,
 		500*time.Millisecond,
 		bundleSize,
 		a.config.FilesDeleteThreads)
 
+	if len(a.config.MirrorMergedBlocksStoreURLs) != 0 {
+		mirrors, policy, err := a.mirrors(bundleSize)
+		if err != nil {
+			return err
+		}
+		io.(merger.MirroringIOInterface).EnableMirroring(mirrors, policy, a.config.MirrorReconcileWindow)
+	}
+
 	m := merger.NewMerger(
 		zlog,
 		a.config.GRPCListenAddr,
@@ -107,6 +194,30 @@ func (a *App) Run() error {
 		a.config.TimeBetweenPolling,
 		a.config.StopBlock,
 	)
+	if a.config.HTTPListenAddr != "" {
+		m.EnableHTTPServer(a.config.HTTPListenAddr)
+	}
+
+	if a.config.CatchUpParallelism > 1 {
+		m.EnableCatchUp(a.config.CatchUpParallelism, a.config.CatchUpLiveThreshold)
+	}
+
+	if a.config.WriterLockTTL > 0 {
+		lock, err := a.writerLock()
+		if err != nil {
+			return err
+		}
+		m.EnableWriterLock(lock)
+	}
+
+	if a.config.HoleDetectionTimeout > 0 {
+		fillers, err := a.holeFillers()
+		if err != nil {
+			return err
+		}
+		m.EnableHoleDetection(a.config.HoleDetectionTimeout, fillers...)
+	}
+
 	zlog.Info("merger initiated")
 
 	gs, err := dgrpc.NewInternalClient(a.config.GRPCListenAddr)
@@ -124,6 +235,76 @@ func (a *App) Run() error {
 	return nil
 }
 
+func (a *App) mirrors(bundleSize uint64) (out []dstore.Store, policy merger.MirrorDeletionPolicy, err error) {
+	policy = merger.MirrorDeletionPolicyPrimary
+	if a.config.MirrorDeletionPolicy != "" {
+		policy, err = merger.ParseMirrorDeletionPolicy(a.config.MirrorDeletionPolicy)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	for _, storeURL := range a.config.MirrorMergedBlocksStoreURLs {
+		store, err := encryptedstore.NewDBinStore(storeURL)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to init mirror merged blocks store %q: %w", storeURL, err)
+		}
+
+		if _, err := types.EnsureBundleSize(context.Background(), store, bundleSize); err != nil {
+			return nil, "", fmt.Errorf("mirror merged blocks store %q bundle size: %w", storeURL, err)
+		}
+
+		out = append(out, store)
+	}
+
+	return out, policy, nil
+}
+
+func (a *App) writerLock() (*lease.Lock, error) {
+	var store dstore.Store
+	var err error
+	if a.config.WriterLockStoreURL == "" {
+		store, err = lease.NewStore(a.config.StorageMergedBlocksFilesPath, "leases")
+	} else {
+		store, err = dstore.NewSimpleStore(a.config.WriterLockStoreURL)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to init writer lock store: %w", err)
+	}
+
+	holder := a.config.WriterLockHolder
+	if holder == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("unable to get hostname for writer lock holder, set it explicitly: %w", err)
+		}
+		holder = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	return lease.NewLock(store, merger.WriterLockObjectName, holder, a.config.WriterLockTTL), nil
+}
+
+func (a *App) holeFillers() (out []merger.HoleFiller, err error) {
+	if a.config.HoleFillOneBlocksStoreURL != "" {
+		store, err := encryptedstore.NewDBinStore(a.config.HoleFillOneBlocksStoreURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init hole fill one-block store: %w", err)
+		}
+		out = append(out, merger.NewStoreHoleFiller(store))
+	}
+
+	if endpoint := a.config.HoleFillFirehoseEndpoint; endpoint != "" {
+		fetchClient, closeFunc, callOpts, err := client.NewFirehoseFetchClient(endpoint, a.config.HoleFillFirehoseAPIToken, a.config.HoleFillFirehoseAPIKey, a.config.HoleFillFirehoseInsecure, a.config.HoleFillFirehosePlaintext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to init hole fill Firehose client: %w", err)
+		}
+		a.OnTerminated(func(_ error) { closeFunc() })
+		out = append(out, merger.NewFirehoseHoleFiller(endpoint, fetchClient, callOpts...))
+	}
+
+	return out, nil
+}
+
 func (a *App) IsReady() bool {
 	if a.readinessProbe == nil {
 		return false
@@ -54,6 +54,15 @@ type Bundler struct {
 	forkable           *forkable.Forkable
 
 	logger *zap.Logger
+
+	// catchUp merges bundles concurrently while far from head, nil if catch-up merging is not enabled
+	catchUp *catchUpMerger
+
+	// holdsWriterLock is nil unless the merger writer lock is enabled, see checkWriterLock
+	holdsWriterLock func() bool
+
+	lastMergedLock sync.Mutex
+	lastMerged     *MergedBundleStatus
 }
 
 var logger, _ = logging.PackageLogger("merger", "github.com/streamingfast/firehose-core/merger/bundler")
@@ -77,7 +86,48 @@ func (b *Bundler) BaseBlockNum() uint64 {
 	b.inProcess.Lock()
 	defer b.inProcess.Unlock()
 	// while inProcess is locked, all blocks below b.baseBlockNum are actually merged
-	return b.baseBlockNum
+	baseBlockNum := b.baseBlockNum
+	if b.catchUp != nil {
+		// bundles merged concurrently are only merged once committed
+		if lowest, found := b.catchUp.lowestUncommitted(); found && lowest < baseBlockNum {
+			return lowest
+		}
+	}
+	return baseBlockNum
+}
+
+// waitForMerges waits for the bundles being merged asynchronously
+func (b *Bundler) waitForMerges() {
+	if b.catchUp != nil {
+		b.catchUp.wait()
+	}
+	b.inProcess.Lock()
+	b.inProcess.Unlock()
+}
+
+// resumeAfterLastMerged waits for the bundles being merged and resets the bundler on the bundle following the last
+// one merged, the bundles that could not be merged being processed again
+func (b *Bundler) resumeAfterLastMerged() {
+	b.drainBundleError() // unblocks a bundle failing while merging asynchronously
+	b.waitForMerges()
+	b.drainBundleError()
+	if b.catchUp != nil {
+		b.catchUp.reset()
+	}
+
+	last := b.lastMergedBundle()
+	if last == nil {
+		b.Reset(toBaseNum(b.firstStreamableBlock, b.bundleSize), nil)
+		return
+	}
+	b.Reset(last.BaseBlockNum+b.bundleSize, bstream.NewBlockRef(last.LIBID, last.LIBNum))
+}
+
+func (b *Bundler) drainBundleError() {
+	select {
+	case <-b.bundleError:
+	default:
+	}
 }
 
 func (b *Bundler) HandleBlockFile(obf *bstream.OneBlockFile) error {
@@ -193,29 +243,65 @@ func (b *Bundler) ProcessBlock(_ *pbbstream.Block, obj interface{}) error {
 	forkedBlocks := b.forkedBlocksInCurrentBundle()
 	blocksToBundle := b.irreversibleBlocks
 	baseBlockNum := b.baseBlockNum
+	if b.catchUp != nil {
+		if b.catchUp.isCatchingUp(blocksToBundle[len(blocksToBundle)-1]) {
+			b.catchUp.merge(baseBlockNum, blocksToBundle, forkedBlocks)
+			return b.nextBundle(obf)
+		}
+
+		// back to merging one bundle at a time, the bundles being merged concurrently are merged first
+		b.catchUp.wait()
+		select {
+		case err := <-b.bundleError:
+			return err
+		default:
+		}
+	}
+
 	b.inProcess.Lock()
 	go func() {
 		defer b.inProcess.Unlock()
+		if err := b.checkWriterLock(); err != nil {
+			b.bundleError <- err
+			return
+		}
 		if err := b.io.MergeAndStore(context.Background(), baseBlockNum, blocksToBundle); err != nil {
 			b.bundleError <- err
 			return
 		}
+		lastBundleBlock := blocksToBundle[len(blocksToBundle)-1]
+		b.recordMergedBundle(baseBlockNum, lastBundleBlock.Num, lastBundleBlock.ID, time.Now())
 		if forkableIO, ok := b.io.(ForkAwareIOInterface); ok {
 			forkableIO.MoveForkedBlocks(context.Background(), forkedBlocks)
 		}
 		// we do not delete bundled blocks here, they get pruned later. keeping the blocks from the last bundle is useful for bootstrapping
 	}()
 
+	return b.nextBundle(obf)
+}
+
+// nextBundle moves to the bundle of `obf`, the first block above the bundle being merged
+func (b *Bundler) nextBundle(obf *bstream.OneBlockFile) error {
 	b.Lock()
 	// we keep the last block of the bundle, only deleting it on next merge, to facilitate joining to one-block-filled hub
 	lastBlock := b.irreversibleBlocks[len(b.irreversibleBlocks)-1]
 	b.irreversibleBlocks = []*bstream.OneBlockFile{lastBlock, obf}
 	b.baseBlockNum += b.bundleSize
 	for obf.Num > b.baseBlockNum+b.bundleSize { // skip more merged-block-files
+		if b.catchUp != nil {
+			b.catchUp.wait() // empty bundles are merged in order with the ones merged concurrently
+		}
 		b.inProcess.Lock()
-		if err := b.io.MergeAndStore(context.Background(), b.baseBlockNum, []*bstream.OneBlockFile{lastBlock}); err != nil { // lastBlock will be excluded from bundle but is useful to bundler
+		err := b.checkWriterLock()
+		if err == nil {
+			err = b.io.MergeAndStore(context.Background(), b.baseBlockNum, []*bstream.OneBlockFile{lastBlock}) // lastBlock will be excluded from bundle but is useful to bundler
+		}
+		if err != nil {
+			b.inProcess.Unlock()
+			b.Unlock()
 			return err
 		}
+		b.recordMergedBundle(b.baseBlockNum, lastBlock.Num, lastBlock.ID, time.Now())
 		b.inProcess.Unlock()
 		b.baseBlockNum += b.bundleSize
 	}
@@ -228,6 +314,50 @@ func (b *Bundler) ProcessBlock(_ *pbbstream.Block, obj interface{}) error {
 	return nil
 }
 
+// recordMergedBundle can be called from a different thread, `mergedAt` is zero when the bundle was merged before
+// the merger started. Bundles merged by the merger also move the head pointer of the merged blocks store, callers
+// recording them in block order.
+func (b *Bundler) recordMergedBundle(baseBlockNum uint64, libNum uint64, libID string, mergedAt time.Time) {
+	merged := &MergedBundleStatus{
+		BaseBlockNum: baseBlockNum,
+		LIBNum:       libNum,
+		LIBID:        libID,
+		MergedAt:     mergedAt,
+	}
+
+	b.lastMergedLock.Lock()
+	b.lastMerged = merged
+	b.lastMergedLock.Unlock()
+
+	if !mergedAt.IsZero() {
+		b.writeHead(merged)
+	}
+}
+
+// lastMergedBundle can be called from a different thread
+func (b *Bundler) lastMergedBundle() *MergedBundleStatus {
+	b.lastMergedLock.Lock()
+	defer b.lastMergedLock.Unlock()
+
+	if b.lastMerged == nil {
+		return nil
+	}
+
+	out := *b.lastMerged
+	return &out
+}
+
+// lastIrreversibleBlock returns the highest irreversible block seen by the bundler, nil if there is none
+func (b *Bundler) lastIrreversibleBlock() *bstream.OneBlockFile {
+	b.Lock()
+	defer b.Unlock()
+
+	if len(b.irreversibleBlocks) == 0 {
+		return nil
+	}
+	return b.irreversibleBlocks[len(b.irreversibleBlocks)-1]
+}
+
 // String can be called from a different thread
 func (b *Bundler) String() string {
 	b.Lock()
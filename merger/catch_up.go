@@ -0,0 +1,154 @@
+package merger
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/streamingfast/bstream"
+	"github.com/streamingfast/firehose-core/merger/metrics"
+	"go.uber.org/zap"
+)
+
+// EnableCatchUp makes the merger merge up to `parallelism` bundles concurrently while their last block is older
+// than `liveThreshold`, switching back to merging one bundle at a time near head. Must be called before the merger
+// is run.
+func (m *Merger) EnableCatchUp(parallelism int, liveThreshold time.Duration) {
+	m.bundler.catchUp = newCatchUpMerger(parallelism, liveThreshold, m.bundler)
+}
+
+// catchUpMerger merges bundles concurrently, fork resolution still being done block by block by the bundler.
+// Merged bundles are committed in order: the bundler base block (and so the pruning target), the last merged
+// bundle and forked blocks moves only progress once all the bundles below are merged.
+type catchUpMerger struct {
+	bundler       *Bundler
+	liveThreshold time.Duration
+	slots         chan struct{}
+
+	lock sync.Mutex
+	// inFlight holds the bundles not yet committed, ordered by base block
+	inFlight   []*catchUpBundle
+	catchingUp bool
+	done       sync.WaitGroup
+}
+
+type catchUpBundle struct {
+	baseBlockNum uint64
+	blocks       []*bstream.OneBlockFile
+	forkedBlocks []*bstream.OneBlockFile
+	merged       bool
+	mergedAt     time.Time
+}
+
+func newCatchUpMerger(parallelism int, liveThreshold time.Duration, bundler *Bundler) *catchUpMerger {
+	return &catchUpMerger{
+		bundler:       bundler,
+		liveThreshold: liveThreshold,
+		slots:         make(chan struct{}, parallelism),
+	}
+}
+
+// isCatchingUp tells if the bundle ending with `lastBlock` is far enough from head to be merged concurrently,
+// switching to live mode (returning false) when the block time cannot be read
+func (c *catchUpMerger) isCatchingUp(lastBlock *bstream.OneBlockFile) bool {
+	catchingUp := false
+	if data, err := lastBlock.Data(context.Background(), c.bundler.io.DownloadOneBlockFile); err == nil {
+		if blockTime, err := readBlockTime(data); err == nil {
+			catchingUp = time.Since(blockTime) > c.liveThreshold
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if catchingUp != c.catchingUp {
+		c.catchingUp = catchingUp
+		if catchingUp {
+			c.bundler.logger.Info("merger is far behind head, merging bundles concurrently", zap.Uint64("block_num", lastBlock.Num), zap.Int("parallelism", cap(c.slots)))
+		} else {
+			c.bundler.logger.Info("merger is close to head, merging bundles one at a time", zap.Uint64("block_num", lastBlock.Num))
+		}
+	}
+
+	return catchingUp
+}
+
+// merge starts merging the bundle, blocking while `parallelism` bundles are already being merged
+func (c *catchUpMerger) merge(baseBlockNum uint64, blocks, forkedBlocks []*bstream.OneBlockFile) {
+	c.slots <- struct{}{}
+
+	bundle := &catchUpBundle{baseBlockNum: baseBlockNum, blocks: blocks, forkedBlocks: forkedBlocks}
+	c.lock.Lock()
+	c.inFlight = append(c.inFlight, bundle)
+	metrics.CatchUpMergesInFlight.SetUint64(uint64(len(c.inFlight)))
+	c.lock.Unlock()
+
+	c.done.Add(1)
+	go func() {
+		defer c.done.Done()
+
+		err := c.bundler.checkWriterLock()
+		if err == nil {
+			err = c.bundler.io.MergeAndStore(context.Background(), baseBlockNum, blocks)
+		}
+		<-c.slots
+
+		if err != nil {
+			// bundles above are left uncommitted, the merger restarting from the first bundle not merged
+			select {
+			case c.bundler.bundleError <- err:
+			default:
+			}
+			return
+		}
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		bundle.merged = true
+		bundle.mergedAt = time.Now()
+		c.commitMerged()
+	}()
+}
+
+// commitMerged commits the merged bundles having no unmerged bundle below them, must be called with the lock held
+func (c *catchUpMerger) commitMerged() {
+	for len(c.inFlight) != 0 && c.inFlight[0].merged {
+		c.commit(c.inFlight[0])
+		c.inFlight = c.inFlight[1:]
+	}
+	metrics.CatchUpMergesInFlight.SetUint64(uint64(len(c.inFlight)))
+}
+
+func (c *catchUpMerger) commit(bundle *catchUpBundle) {
+	lastBundleBlock := bundle.blocks[len(bundle.blocks)-1]
+	c.bundler.recordMergedBundle(bundle.baseBlockNum, lastBundleBlock.Num, lastBundleBlock.ID, bundle.mergedAt)
+	if forkableIO, ok := c.bundler.io.(ForkAwareIOInterface); ok {
+		forkableIO.MoveForkedBlocks(context.Background(), bundle.forkedBlocks)
+	}
+}
+
+// lowestUncommitted returns the base block of the lowest bundle not committed yet, false if there is none
+func (c *catchUpMerger) lowestUncommitted() (uint64, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.inFlight) == 0 {
+		return 0, false
+	}
+	return c.inFlight[0].baseBlockNum, true
+}
+
+// reset drops the bundles not committed, must be called once they are not being merged anymore
+func (c *catchUpMerger) reset() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.inFlight = nil
+	metrics.CatchUpMergesInFlight.SetUint64(0)
+}
+
+// wait waits for the bundles being merged
+func (c *catchUpMerger) wait() {
+	c.done.Wait()
+}
@@ -0,0 +1,79 @@
+package merger
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/bstream"
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func blockDataAt(t *testing.T, blockTime time.Time) func(context.Context, *bstream.OneBlockFile) ([]byte, error) {
+	return func(_ context.Context, obf *bstream.OneBlockFile) ([]byte, error) {
+		out := new(bytes.Buffer)
+		writer, err := bstream.NewDBinBlockWriter(out)
+		require.NoError(t, err)
+		require.NoError(t, writer.Write(&pbbstream.Block{Number: obf.Num, Id: obf.ID, Timestamp: timestamppb.New(blockTime), Payload: &anypb.Any{TypeUrl: "type.googleapis.com/sf.test.Block"}}))
+		return out.Bytes(), nil
+	}
+}
+
+func TestBundler_CatchUpMergesConcurrentlyAndCommitsInOrder(t *testing.T) {
+	releaseFirstBundle := make(chan struct{})
+
+	var lock sync.Mutex
+	var merged []uint64
+	b := NewBundler(100, 0, 2, 2, &TestMergerIO{
+		DownloadOneBlockFileFunc: blockDataAt(t, time.Now().Add(-time.Hour)),
+		MergeAndStoreFunc: func(_ context.Context, inclusiveLowerBlock uint64, _ []*bstream.OneBlockFile) error {
+			if inclusiveLowerBlock == 100 {
+				<-releaseFirstBundle
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			merged = append(merged, inclusiveLowerBlock)
+			return nil
+		},
+	})
+	b.catchUp = newCatchUpMerger(2, 15*time.Minute, b)
+
+	for _, blk := range []*bstream.OneBlockFile{block100(), block101(), block102Final100(), block103Final101(), block104Final102(), block105Final103(), block106Final104()} {
+		require.NoError(t, b.HandleBlockFile(blk))
+	}
+
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(merged) == 1
+	}, time.Second, 10*time.Millisecond, "second bundle merged while the first one is still merging")
+	assert.Equal(t, []uint64{102}, merged)
+
+	assert.EqualValues(t, 100, b.BaseBlockNum(), "second bundle not committed before the first one")
+	assert.Nil(t, b.lastMergedBundle())
+
+	close(releaseFirstBundle)
+	b.waitForMerges()
+
+	assert.Equal(t, []uint64{102, 100}, merged)
+	assert.EqualValues(t, 104, b.BaseBlockNum())
+	require.NotNil(t, b.lastMergedBundle())
+	assert.EqualValues(t, 102, b.lastMergedBundle().BaseBlockNum)
+}
+
+func TestCatchUpMerger_IsCatchingUp(t *testing.T) {
+	newCatchUp := func(blockTime time.Time) *catchUpMerger {
+		b := NewBundler(100, 0, 2, 2, &TestMergerIO{DownloadOneBlockFileFunc: blockDataAt(t, blockTime)})
+		return newCatchUpMerger(2, 15*time.Minute, b)
+	}
+
+	assert.True(t, newCatchUp(time.Now().Add(-time.Hour)).isCatchingUp(block100()))
+	assert.False(t, newCatchUp(time.Now()).isCatchingUp(block100()))
+}
@@ -22,3 +22,6 @@ var GetObjectTimeout = 5 * time.Minute
 var DeleteObjectTimeout = 5 * time.Minute
 
 const ParallelOneBlockDownload = 2
+
+// filledOneBlockFileSuffix is the suffix of the one-block files written by the merger when filling holes
+const filledOneBlockFileSuffix = "merger-filled"
@@ -0,0 +1,47 @@
+package merger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/streamingfast/firehose-core/types"
+	"go.uber.org/zap"
+)
+
+func (s *DStoreIO) WriteHead(ctx context.Context, head *types.MergedBlocksStoreHead) error {
+	s.headLock.Lock()
+	defer s.headLock.Unlock()
+
+	if s.headStore == nil {
+		store, err := types.NewMergedBlocksStoreHeadStore(ctx, s.mergedBlocksStore)
+		if err != nil {
+			return fmt.Errorf("merged blocks store head: %w", err)
+		}
+		s.headStore = store
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, WriteObjectTimeout)
+	defer cancel()
+
+	return types.WriteMergedBlocksStoreHead(ctx, s.headStore, head)
+}
+
+// writeHead points the head of the merged blocks store to the merged bundle, readers falling back to probing the
+// store when it cannot be written
+func (b *Bundler) writeHead(merged *MergedBundleStatus) {
+	headIO, ok := b.io.(HeadIOInterface)
+	if !ok {
+		return
+	}
+
+	head := &types.MergedBlocksStoreHead{
+		LastBundleBaseBlockNum: merged.BaseBlockNum,
+		LIBNum:                 merged.LIBNum,
+		LIBID:                  merged.LIBID,
+		UpdatedAt:              merged.MergedAt,
+	}
+
+	if err := headIO.WriteHead(context.Background(), head); err != nil {
+		b.logger.Warn("unable to write merged blocks store head", zap.Uint64("base_block_num", merged.BaseBlockNum), zap.Error(err))
+	}
+}
@@ -0,0 +1,40 @@
+package merger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundler_WritesMergedBlocksStoreHead(t *testing.T) {
+	mergedBlocksStore, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	mio := NewDStoreIO(testLogger, testTracer, dstore.NewMockStore(nil), mergedBlocksStore, nil, nil, nil, 0, 0, 100, 0)
+	b := NewBundler(100, 0, 0, 100, mio)
+
+	// bundles merged before the merger started do not move the head
+	b.recordMergedBundle(0, 99, "99a", time.Time{})
+	head, err := types.ReadMergedBlocksStoreHead(context.Background(), mergedBlocksStore)
+	require.NoError(t, err)
+	assert.Nil(t, head)
+
+	mergedAt := time.Now().UTC().Truncate(time.Second)
+	b.recordMergedBundle(100, 199, "199a", mergedAt)
+	b.recordMergedBundle(200, 299, "299a", mergedAt.Add(time.Second))
+
+	head, err = types.ReadMergedBlocksStoreHead(context.Background(), mergedBlocksStore)
+	require.NoError(t, err)
+	require.NotNil(t, head)
+	assert.Equal(t, &types.MergedBlocksStoreHead{
+		LastBundleBaseBlockNum: 200,
+		LIBNum:                 299,
+		LIBID:                  "299a",
+		UpdatedAt:              mergedAt.Add(time.Second),
+	}, head)
+}
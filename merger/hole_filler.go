@@ -0,0 +1,140 @@
+package merger
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/streamingfast/bstream"
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/streamingfast/dstore"
+	pbfirehose "github.com/streamingfast/pbgo/sf/firehose/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HoleFiller fetches blocks missing from the one-block files store
+type HoleFiller interface {
+	// FetchBlocks returns the blocks known to the source at `num`, none if it does not have any
+	FetchBlocks(ctx context.Context, num uint64) ([]*pbbstream.Block, error)
+
+	String() string
+}
+
+// FirehoseHoleFiller fetches missing blocks from a peer Firehose endpoint, which must return the block metadata
+type FirehoseHoleFiller struct {
+	endpoint string
+	client   pbfirehose.FetchClient
+	callOpts []grpc.CallOption
+}
+
+func NewFirehoseHoleFiller(endpoint string, client pbfirehose.FetchClient, callOpts ...grpc.CallOption) *FirehoseHoleFiller {
+	return &FirehoseHoleFiller{
+		endpoint: endpoint,
+		client:   client,
+		callOpts: callOpts,
+	}
+}
+
+func (f *FirehoseHoleFiller) FetchBlocks(ctx context.Context, num uint64) ([]*pbbstream.Block, error) {
+	resp, err := f.client.Block(ctx, &pbfirehose.SingleBlockRequest{
+		Reference: &pbfirehose.SingleBlockRequest_BlockNumber_{
+			BlockNumber: &pbfirehose.SingleBlockRequest_BlockNumber{Num: num},
+		},
+	}, f.callOpts...)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("fetch block #%d: %w", num, err)
+	}
+
+	metadata := resp.Metadata
+	if metadata == nil {
+		return nil, fmt.Errorf("block #%d response has no metadata, the endpoint must run a more recent Firehose version", num)
+	}
+
+	if resp.Block == nil {
+		return nil, fmt.Errorf("block #%d response has no block", num)
+	}
+
+	return []*pbbstream.Block{{
+		Id:        metadata.Id,
+		Number:    metadata.Num,
+		ParentId:  metadata.ParentId,
+		ParentNum: metadata.ParentNum,
+		LibNum:    metadata.LibNum,
+		Timestamp: metadata.Time,
+		Payload:   resp.Block,
+	}}, nil
+}
+
+func (f *FirehoseHoleFiller) String() string {
+	return "firehose " + f.endpoint
+}
+
+// StoreHoleFiller fetches missing blocks from a secondary one-block files store
+type StoreHoleFiller struct {
+	store dstore.Store
+}
+
+func NewStoreHoleFiller(store dstore.Store) *StoreHoleFiller {
+	return &StoreHoleFiller{store: store}
+}
+
+func (f *StoreHoleFiller) FetchBlocks(ctx context.Context, num uint64) (out []*pbbstream.Block, err error) {
+	var filenames []string
+	err = f.store.Walk(ctx, fileNameForBlocksBundle(num)+"-", func(filename string) error {
+		filenames = append(filenames, filename)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list one-block files #%d: %w", num, err)
+	}
+
+	for _, filename := range filenames {
+		block, err := f.readBlock(ctx, filename)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, block)
+	}
+
+	return out, nil
+}
+
+// readBlock reads the one-block file, checking its content matches the block number and ID of its name
+func (f *StoreHoleFiller) readBlock(ctx context.Context, filename string) (*pbbstream.Block, error) {
+	num, id, _, _, _, err := bstream.ParseFilename(filename)
+	if err != nil {
+		return nil, fmt.Errorf("parse one-block filename %q: %w", filename, err)
+	}
+
+	reader, err := f.store.OpenObject(ctx, filename)
+	if err != nil {
+		return nil, fmt.Errorf("open one-block file %q: %w", filename, err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read one-block file %q: %w", filename, err)
+	}
+
+	block, err := bstream.DecodeOneblockfileData(content)
+	if err != nil {
+		return nil, fmt.Errorf("decode one-block file %q: %w", filename, err)
+	}
+
+	if block.Number != num || bstream.TruncateBlockID(block.Id) != bstream.TruncateBlockID(id) {
+		return nil, fmt.Errorf("one-block file %q contains block %s", filename, blockRef(block))
+	}
+
+	return block, nil
+}
+
+func (f *StoreHoleFiller) String() string {
+	return "store " + f.store.BaseURL().Redacted()
+}
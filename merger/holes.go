@@ -0,0 +1,301 @@
+package merger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/streamingfast/bstream"
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/streamingfast/firehose-core/merger/metrics"
+	"go.uber.org/zap"
+)
+
+// holeDetectionWindowBundles is the number of bundles, starting at the stalled one, inspected when looking for holes
+const holeDetectionWindowBundles = 10
+
+// maxFilledBlocksPerCheck bounds the blocks fetched from hole fillers on each check
+const maxFilledBlocksPerCheck = 1000
+
+// HoleReport describes the one-block files preventing the current bundle from being completed
+type HoleReport struct {
+	BaseBlockNum uint64
+	// LowestBlockNum is the first block number expected in the one-block files store, the block following the last
+	// irreversible block when it's known
+	LowestBlockNum  uint64
+	HighestBlockNum uint64
+
+	// MissingBlockNums are the block numbers between LowestBlockNum and HighestBlockNum without any one-block file.
+	// On chains skipping block numbers, skipped numbers are reported too.
+	MissingBlockNums []uint64
+	// UnlinkedBlocks are the blocks whose parent has no one-block file
+	UnlinkedBlocks []*bstream.OneBlockFile
+}
+
+func (r *HoleReport) Empty() bool {
+	return len(r.MissingBlockNums) == 0 && len(r.UnlinkedBlocks) == 0
+}
+
+func (r *HoleReport) unlinkedBlockRefs() (out []string) {
+	for _, obf := range r.UnlinkedBlocks {
+		out = append(out, fmt.Sprintf("#%d (%s, parent %s)", obf.Num, obf.ID, obf.PreviousID))
+	}
+	return
+}
+
+// detectHoles inspects the one-block files following `lastIrreversible` (nil if unknown, then starting at
+// `baseBlockNum`) and reports the missing block numbers and the blocks that cannot be linked to their parent
+func detectHoles(baseBlockNum uint64, lastIrreversible *bstream.OneBlockFile, files []*bstream.OneBlockFile) *HoleReport {
+	report := &HoleReport{BaseBlockNum: baseBlockNum, LowestBlockNum: baseBlockNum}
+
+	known := map[string]bool{}
+	if lastIrreversible != nil {
+		report.LowestBlockNum = lastIrreversible.Num + 1
+		known[bstream.TruncateBlockID(lastIrreversible.ID)] = true
+	}
+
+	var candidates []*bstream.OneBlockFile
+	seenNums := map[uint64]bool{}
+	for _, obf := range files {
+		if obf.Num < report.LowestBlockNum {
+			continue
+		}
+
+		candidates = append(candidates, obf)
+		seenNums[obf.Num] = true
+		known[bstream.TruncateBlockID(obf.ID)] = true
+		if obf.Num > report.HighestBlockNum {
+			report.HighestBlockNum = obf.Num
+		}
+	}
+
+	if len(candidates) == 0 {
+		return report
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Num < candidates[j].Num })
+	lowestSeenNum := candidates[0].Num
+
+	for num := report.LowestBlockNum; num < report.HighestBlockNum; num++ {
+		if !seenNums[num] {
+			report.MissingBlockNums = append(report.MissingBlockNums, num)
+		}
+	}
+
+	for _, obf := range candidates {
+		// without a known last irreversible block, the parent of the lowest blocks is expected to be missing
+		if lastIrreversible == nil && obf.Num == lowestSeenNum {
+			continue
+		}
+
+		if !known[bstream.TruncateBlockID(obf.PreviousID)] {
+			report.UnlinkedBlocks = append(report.UnlinkedBlocks, obf)
+		}
+	}
+
+	return report
+}
+
+// checkStalledBundle looks for holes in the one-block files once the bundler did not progress for the hole
+// detection timeout, filling them from the hole fillers if any, and then at most once per timeout
+func (m *Merger) checkStalledBundle(ctx context.Context) {
+	if m.holeDetectionTimeout == 0 {
+		return
+	}
+
+	base := m.bundler.BaseBlockNum()
+	if base != m.stalledBaseBlockNum || m.stalledSince.IsZero() {
+		m.stalledBaseBlockNum = base
+		m.stalledSince = m.now()
+		m.lastHoleCheck = m.stalledSince
+		metrics.MissingBlocks.SetUint64(0)
+		return
+	}
+
+	if m.now().Sub(m.lastHoleCheck) < m.holeDetectionTimeout {
+		return
+	}
+	m.lastHoleCheck = m.now()
+
+	lastIrreversible := m.bundler.lastIrreversibleBlock()
+	var files []*bstream.OneBlockFile
+	windowEnd := base + holeDetectionWindowBundles*m.bundler.bundleSize
+	err := m.io.WalkOneBlockFiles(ctx, base, func(obf *bstream.OneBlockFile) error {
+		if obf.Num >= windowEnd {
+			return ErrStopBlockReached
+		}
+		files = append(files, obf)
+		return nil
+	})
+	if err != nil && !errors.Is(err, ErrStopBlockReached) {
+		m.logger.Warn("unable to walk one-block files looking for holes", zap.Error(err))
+		return
+	}
+
+	// the first bundle starts at the first streamable block
+	report := detectHoles(max(base, m.firstStreamableBlock), lastIrreversible, files)
+	metrics.MissingBlocks.SetUint64(uint64(len(report.MissingBlockNums) + len(report.UnlinkedBlocks)))
+
+	if report.Empty() {
+		m.logger.Info("bundle not completed yet but no hole found in one-block files, waiting for more blocks",
+			zap.Uint64("base_block_num", base),
+			zap.Duration("stalled_for", m.now().Sub(m.stalledSince)),
+			zap.Uint64("highest_block_num", report.HighestBlockNum),
+		)
+		return
+	}
+
+	m.logger.Warn("bundle is stalled by missing one-block files",
+		zap.Uint64("base_block_num", base),
+		zap.Duration("stalled_for", m.now().Sub(m.stalledSince)),
+		zap.Uint64("lowest_block_num", report.LowestBlockNum),
+		zap.Uint64("highest_block_num", report.HighestBlockNum),
+		zap.Int("missing_block_count", len(report.MissingBlockNums)),
+		zap.Uint64s("missing_block_nums", truncateUint64s(report.MissingBlockNums, 100)),
+		zap.Strings("unlinked_blocks", report.unlinkedBlockRefs()),
+	)
+
+	if len(m.holeFillers) != 0 {
+		m.fillHoles(ctx, report, lastIrreversible, files)
+	}
+}
+
+// fillHoles fetches the blocks reported missing from the hole fillers, writing them as one-block files once
+// validated: a filled block must be the expected parent of an unlinked block (same ID) and/or follow a known
+// block (its parent is known), and must be the parent of the known blocks following it
+func (m *Merger) fillHoles(ctx context.Context, report *HoleReport, lastIrreversible *bstream.OneBlockFile, files []*bstream.OneBlockFile) {
+	writer, ok := m.io.(OneBlockWriterIOInterface)
+	if !ok {
+		m.logger.Warn("merger IO cannot write one-block files, not filling holes")
+		return
+	}
+
+	known := map[string]bool{}
+	childrenByNum := map[uint64][]*bstream.OneBlockFile{}
+	if lastIrreversible != nil {
+		known[bstream.TruncateBlockID(lastIrreversible.ID)] = true
+	}
+	for _, obf := range files {
+		known[bstream.TruncateBlockID(obf.ID)] = true
+		childrenByNum[obf.Num] = append(childrenByNum[obf.Num], obf)
+	}
+
+	expectedIDs := map[uint64]string{}
+	nums := append([]uint64(nil), report.MissingBlockNums...)
+	for _, obf := range report.UnlinkedBlocks {
+		if obf.Num == 0 {
+			continue
+		}
+
+		if _, found := expectedIDs[obf.Num-1]; !found {
+			nums = append(nums, obf.Num-1)
+		}
+		expectedIDs[obf.Num-1] = bstream.TruncateBlockID(obf.PreviousID)
+	}
+	nums = dedupeSortedUint64s(nums)
+	if len(nums) > maxFilledBlocksPerCheck {
+		nums = nums[:maxFilledBlocksPerCheck]
+	}
+
+	filled := 0
+	for _, num := range nums {
+		if ctx.Err() != nil {
+			return
+		}
+
+		block, source, err := m.fetchHoleBlock(ctx, num, func(candidate *pbbstream.Block) error {
+			return validateHoleBlock(candidate, num, expectedIDs[num], known, childrenByNum[num+1])
+		})
+		if err != nil {
+			m.logger.Warn("unable to fill hole", zap.Uint64("block_num", num), zap.Error(err))
+			continue
+		}
+
+		if err := writer.WriteOneBlockFile(ctx, block); err != nil {
+			m.logger.Warn("unable to write filled one-block file", zap.Uint64("block_num", num), zap.Error(err))
+			continue
+		}
+
+		known[bstream.TruncateBlockID(block.Id)] = true
+		filled++
+		metrics.FilledBlocks.Inc()
+		m.logger.Info("filled hole in one-block files", zap.String("block", blockRef(block)), zap.String("source", source))
+	}
+
+	m.logger.Info("hole filling completed", zap.Int("filled_block_count", filled), zap.Int("attempted_block_count", len(nums)))
+}
+
+// fetchHoleBlock returns the first block at `num` from the hole fillers passing `validate`
+func (m *Merger) fetchHoleBlock(ctx context.Context, num uint64, validate func(*pbbstream.Block) error) (*pbbstream.Block, string, error) {
+	var errs []error
+	for _, filler := range m.holeFillers {
+		candidates, err := filler.FetchBlocks(ctx, num)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filler, err))
+			continue
+		}
+
+		for _, candidate := range candidates {
+			if err := validate(candidate); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", filler, err))
+				continue
+			}
+
+			return candidate, filler.String(), nil
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil, "", fmt.Errorf("block not found in any hole filler")
+	}
+
+	return nil, "", errors.Join(errs...)
+}
+
+func validateHoleBlock(block *pbbstream.Block, num uint64, expectedID string, known map[string]bool, children []*bstream.OneBlockFile) error {
+	if block.Number != num {
+		return fmt.Errorf("received block %s, expected block number %d", blockRef(block), num)
+	}
+
+	id := bstream.TruncateBlockID(block.Id)
+	if expectedID != "" && id != expectedID {
+		return fmt.Errorf("received block %s, expected block ID %s", blockRef(block), expectedID)
+	}
+
+	if expectedID == "" && !known[bstream.TruncateBlockID(block.ParentId)] {
+		return fmt.Errorf("parent %s of received block %s has no one-block file", block.ParentId, blockRef(block))
+	}
+
+	if len(children) != 0 {
+		for _, child := range children {
+			if bstream.TruncateBlockID(child.PreviousID) == id {
+				return nil
+			}
+		}
+		return fmt.Errorf("received block %s is not the parent of any block #%d", blockRef(block), num+1)
+	}
+
+	return nil
+}
+
+func blockRef(block *pbbstream.Block) string {
+	return fmt.Sprintf("#%d (%s)", block.Number, block.Id)
+}
+
+func dedupeSortedUint64s(in []uint64) (out []uint64) {
+	sort.Slice(in, func(i, j int) bool { return in[i] < in[j] })
+	for i, v := range in {
+		if i == 0 || v != in[i-1] {
+			out = append(out, v)
+		}
+	}
+	return
+}
+
+func truncateUint64s(in []uint64, max int) []uint64 {
+	if len(in) > max {
+		return in[:max]
+	}
+	return in
+}
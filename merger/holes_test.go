@@ -0,0 +1,101 @@
+package merger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/bstream"
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectHoles(t *testing.T) {
+	files := []*bstream.OneBlockFile{
+		block100(),
+		block101(),
+		block103Final101(),
+		block104Final102(),
+		bstream.MustNewOneBlockFile("0000000105-0000000000000105b-0000000000000104b-103-suffix"),
+	}
+
+	report := detectHoles(100, block99(), files)
+	assert.Equal(t, uint64(100), report.LowestBlockNum)
+	assert.Equal(t, uint64(105), report.HighestBlockNum)
+	assert.Equal(t, []uint64{102}, report.MissingBlockNums)
+	require.Len(t, report.UnlinkedBlocks, 2)
+	assert.Equal(t, uint64(103), report.UnlinkedBlocks[0].Num)
+	assert.Equal(t, uint64(105), report.UnlinkedBlocks[1].Num)
+
+	report = detectHoles(100, nil, []*bstream.OneBlockFile{block100(), block101()})
+	assert.True(t, report.Empty())
+
+	report = detectHoles(100, block101(), []*bstream.OneBlockFile{block100(), block101(), block102Final100()})
+	assert.True(t, report.Empty())
+}
+
+func TestMerger_FillsHoles(t *testing.T) {
+	files := []*bstream.OneBlockFile{block100(), block101(), block103Final101(), block104Final102()}
+
+	var written []*pbbstream.Block
+	io := &testHoleFillingIO{
+		TestMergerIO: &TestMergerIO{
+			WalkOneBlockFilesFunc: func(ctx context.Context, inclusiveLowerBlock uint64, callback func(*bstream.OneBlockFile) error) error {
+				for _, obf := range files {
+					if err := callback(obf); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+		writeFunc: func(block *pbbstream.Block) error {
+			written = append(written, block)
+			return nil
+		},
+	}
+
+	filler := &testHoleFiller{blocks: map[uint64][]*pbbstream.Block{
+		102: {
+			{Id: "0000000000000102b", Number: 102, ParentId: "0000000000000101a", LibNum: 100},
+			{Id: "0000000000000102a", Number: 102, ParentId: "0000000000000101a", LibNum: 100},
+		},
+	}}
+
+	now := time.Now()
+	m := NewMerger(testLogger, "", io, 1, 100, 100, time.Second, time.Second, 0)
+	m.now = func() time.Time { return now }
+	m.EnableHoleDetection(time.Minute, filler)
+	m.bundler.Reset(100, nil)
+
+	m.checkStalledBundle(context.Background())
+	assert.Empty(t, written, "bundle just started stalling")
+
+	now = now.Add(2 * time.Minute)
+	m.checkStalledBundle(context.Background())
+
+	require.Len(t, written, 1)
+	assert.Equal(t, "0000000000000102a", written[0].Id)
+}
+
+type testHoleFillingIO struct {
+	*TestMergerIO
+	writeFunc func(block *pbbstream.Block) error
+}
+
+func (io *testHoleFillingIO) WriteOneBlockFile(ctx context.Context, block *pbbstream.Block) error {
+	return io.writeFunc(block)
+}
+
+type testHoleFiller struct {
+	blocks map[uint64][]*pbbstream.Block
+}
+
+func (f *testHoleFiller) FetchBlocks(ctx context.Context, num uint64) ([]*pbbstream.Block, error) {
+	return f.blocks[num], nil
+}
+
+func (f *testHoleFiller) String() string {
+	return "test"
+}
@@ -0,0 +1,55 @@
+package merger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// EnableHTTPServer makes the merger serve its status API over HTTP on `listenAddr`, must be called before the
+// merger is run
+func (m *Merger) EnableHTTPServer(listenAddr string) {
+	m.httpListenAddr = listenAddr
+}
+
+func (m *Merger) startHTTPServer() {
+	if m.httpListenAddr == "" {
+		return
+	}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/v1/status", m.statusHandler).Methods("GET")
+	r.HandleFunc("/v1/prune", m.pruneHandler).Methods("POST")
+
+	srv := &http.Server{Addr: m.httpListenAddr, Handler: r}
+	m.OnTerminated(func(_ error) {
+		srv.Close()
+	})
+
+	m.logger.Info("starting http server", zap.String("http_addr", m.httpListenAddr))
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			m.logger.Error("http server failed", zap.Error(err))
+			m.Shutdown(err)
+		}
+	}()
+}
+
+func (m *Merger) statusHandler(w http.ResponseWriter, r *http.Request) {
+	m.writeJSON(w, m.Status(r.Context()))
+}
+
+func (m *Merger) pruneHandler(w http.ResponseWriter, r *http.Request) {
+	m.ForcePrune()
+	m.writeJSON(w, map[string]string{"result": "pruning scheduled"})
+}
+
+func (m *Merger) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		m.logger.Warn("unable to write http response", zap.Error(err))
+	}
+}
@@ -17,9 +17,11 @@ package merger
 import (
 	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/streamingfast/bstream"
+	"github.com/streamingfast/firehose-core/internal/lease"
 	"github.com/streamingfast/shutter"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -28,6 +30,7 @@ import (
 type Merger struct {
 	*shutter.Shutter
 	grpcListenAddr string
+	httpListenAddr string
 
 	io                   IOInterface
 	firstStreamableBlock uint64
@@ -39,6 +42,30 @@ type Merger struct {
 	pruningDistanceToLIB uint64
 
 	bundler *Bundler
+
+	holeDetectionTimeout time.Duration
+	holeFillers          []HoleFiller
+	stalledBaseBlockNum  uint64
+	stalledSince         time.Time
+	lastHoleCheck        time.Time
+	now                  func() time.Time
+
+	forcePruneOneBlocks    chan struct{}
+	forcePruneForkedBlocks chan struct{}
+
+	writerLock        *lease.Lock
+	writerLockElector *lease.Elector
+	lastWriterLockLog time.Time
+
+	statusLock               sync.Mutex
+	pendingOneBlockFiles     int
+	lastOneBlockFilesPruning *PruningStatus
+	lastForkedBlocksPruning  *PruningStatus
+
+	// forkedBlocksCountLock is held while counting forked blocks so concurrent status requests walk the store once
+	forkedBlocksCountLock   sync.Mutex
+	lastForkedBlocksCount   *int
+	lastForkedBlocksCountAt time.Time
 }
 
 func NewMerger(
@@ -63,16 +90,29 @@ func NewMerger(
 		timeBetweenPolling:   timeBetweenPolling,
 		timeBetweenPruning:   timeBetweenPruning,
 		logger:               logger,
+		now:                  time.Now,
+
+		forcePruneOneBlocks:    make(chan struct{}, 1),
+		forcePruneForkedBlocks: make(chan struct{}, 1),
 	}
-	m.OnTerminating(func(_ error) { m.bundler.inProcess.Lock(); m.bundler.inProcess.Unlock() }) // finish bundle that may be merging async
+	m.OnTerminating(func(_ error) { m.bundler.waitForMerges() }) // finish bundles that may be merging async
 
 	return m
 }
 
+// EnableHoleDetection makes the merger look for missing one-block files once the current bundle did not progress
+// for `timeout`, reporting them and fetching them from `fillers` (if any). Must be called before the merger is run.
+func (m *Merger) EnableHoleDetection(timeout time.Duration, fillers ...HoleFiller) {
+	m.holeDetectionTimeout = timeout
+	m.holeFillers = fillers
+}
+
 func (m *Merger) Run() {
 	m.logger.Info("starting merger")
 
 	m.startGRPCServer()
+	m.startHTTPServer()
+	m.startWriterLock()
 
 	m.startOldFilesPruner()
 	m.startForkedBlocksPruner()
@@ -97,11 +137,16 @@ func (m *Merger) startForkedBlocksPruner() {
 	go func() {
 		delay := m.timeBetweenPruning // do not start pruning immediately
 		for {
-			time.Sleep(delay)
+			m.waitForPruning(delay, m.forcePruneForkedBlocks)
+			if !m.holdsWriterLock() {
+				m.logger.Debug("skipping forked blocks pruning, not holding the merger writer lock")
+				continue
+			}
 			now := time.Now()
 
 			pruningTarget := m.pruningTarget(m.pruningDistanceToLIB)
 			forkableIO.DeleteForkedBlocksAsync(bstream.GetProtocolFirstStreamableBlock, pruningTarget)
+			m.recordPruning(&m.lastForkedBlocksPruning, pruningTarget, -1)
 
 			if spentTime := time.Since(now); spentTime < m.timeBetweenPruning {
 				delay = m.timeBetweenPruning - spentTime
@@ -126,7 +171,11 @@ func (m *Merger) startOldFilesPruner() {
 
 		ctx := context.Background()
 		for {
-			time.Sleep(delay)
+			m.waitForPruning(delay, m.forcePruneOneBlocks)
+			if !m.holdsWriterLock() {
+				m.logger.Debug("skipping one-block files pruning, not holding the merger writer lock")
+				continue
+			}
 
 			var toDelete []*bstream.OneBlockFile
 
@@ -152,10 +201,31 @@ func (m *Merger) startOldFilesPruner() {
 			}
 
 			m.io.DeleteAsync(toDelete)
+			m.recordPruning(&m.lastOneBlockFilesPruning, pruningTarget, len(toDelete))
 		}
 	}()
 }
 
+// waitForPruning waits for `delay` unless a prune is forced through `force`
+func (m *Merger) waitForPruning(delay time.Duration, force <-chan struct{}) {
+	select {
+	case <-time.After(delay):
+	case <-force:
+		m.logger.Info("forced pruning")
+	}
+}
+
+// ForcePrune makes the one-block files and forked blocks pruners run right away instead of waiting for
+// their next run
+func (m *Merger) ForcePrune() {
+	for _, force := range []chan struct{}{m.forcePruneOneBlocks, m.forcePruneForkedBlocks} {
+		select {
+		case force <- struct{}{}:
+		default: // already forced
+		}
+	}
+}
+
 func (m *Merger) pruningTarget(distance uint64) uint64 {
 	bundlerBase := m.bundler.BaseBlockNum()
 	if distance > bundlerBase {
@@ -168,13 +238,19 @@ func (m *Merger) pruningTarget(distance uint64) uint64 {
 func (m *Merger) run() error {
 	ctx := context.Background()
 
-	var holeFoundLogged bool
+	var holeFoundLogged, mirrorsReconciled bool
 	for {
 		now := time.Now()
 		if m.IsTerminating() {
 			return nil
 		}
 
+		if !m.holdsWriterLock() {
+			m.logWriterLockHolder(ctx)
+			time.Sleep(m.timeBetweenPolling)
+			continue
+		}
+
 		base, lib, err := m.io.NextBundle(ctx, m.bundler.baseBlockNum)
 		if err != nil {
 			if errors.Is(err, ErrHoleFound) {
@@ -189,6 +265,11 @@ func (m *Merger) run() error {
 			}
 		}
 
+		if !mirrorsReconciled {
+			mirrorsReconciled = true
+			m.reconcileMirrorsAsync(ctx, base)
+		}
+
 		if m.bundler.stopBlock != 0 && base > m.bundler.stopBlock {
 			if err == ErrStopBlockReached {
 				m.logger.Info("stop block reached")
@@ -206,13 +287,19 @@ func (m *Merger) run() error {
 			}
 			m.logger.Info("resetting bundler base block num", logFields...)
 			m.bundler.Reset(base, lib)
+			if lib != nil {
+				m.bundler.recordMergedBundle(base-m.bundler.bundleSize, lib.Num(), lib.ID(), time.Time{})
+			}
 		}
 
 		var walkErr error
 		retryErr := Retry(m.logger, 12, 5*time.Second, func() error {
+			walked := 0
 			err = m.io.WalkOneBlockFiles(ctx, m.bundler.baseBlockNum, func(obf *bstream.OneBlockFile) error {
+				walked++
 				return m.bundler.HandleBlockFile(obf)
 			})
+			m.setPendingOneBlockFiles(walked)
 
 			if err == ErrFirstBlockAfterInitialStreamableBlock {
 				m.bundler.Reset(base, lib)
@@ -230,6 +317,11 @@ func (m *Merger) run() error {
 		}
 
 		if walkErr != nil {
+			if errors.Is(walkErr, ErrWriterLockLost) {
+				m.logger.Warn("lost the merger writer lock while merging, resuming after the last merged bundle once it's acquired again", zap.Error(walkErr))
+				m.bundler.resumeAfterLastMerged()
+				continue
+			}
 			if walkErr == ErrStopBlockReached {
 				m.logger.Info("stop block reached")
 				return nil
@@ -237,6 +329,8 @@ func (m *Merger) run() error {
 			return walkErr
 		}
 
+		m.checkStalledBundle(ctx)
+
 		if spentTime := time.Since(now); spentTime < m.timeBetweenPolling {
 			time.Sleep(m.timeBetweenPolling - spentTime)
 		}
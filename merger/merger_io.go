@@ -16,7 +16,10 @@ import (
 
 	"github.com/streamingfast/bstream"
 	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/forkarchive"
 	"github.com/streamingfast/firehose-core/merger/metrics"
+	"github.com/streamingfast/firehose-core/sidecar"
+	"github.com/streamingfast/firehose-core/types"
 	"github.com/streamingfast/logging"
 	"go.uber.org/zap"
 )
@@ -49,17 +52,43 @@ type ForkAwareIOInterface interface {
 
 	// MoveForkedBlocks will copy an array of oneBlockFiles to the forkedBlocksStore, then delete them (dstore does not have MOVE primitive)
 	MoveForkedBlocks(ctx context.Context, oneBlockFiles []*bstream.OneBlockFile)
+
+	// CountForkedBlocks returns the number of forked blocks in the forkedBlocksStore, all of them awaiting pruning
+	CountForkedBlocks(ctx context.Context) (int, error)
+}
+
+type OneBlockWriterIOInterface interface {
+	// WriteOneBlockFile writes the block as a one-block file, used to fill holes in the one-block files
+	WriteOneBlockFile(ctx context.Context, block *pbbstream.Block) error
+}
+
+type MirroringIOInterface interface {
+	// EnableMirroring makes every merged bundle be copied to the `mirrors` stores too
+	EnableMirroring(mirrors []dstore.Store, policy MirrorDeletionPolicy, reconcileWindow uint64)
+
+	// ReconcileMirrors queues the bundles of the primary merged blocks store missing from the mirror stores, looking
+	// at the bundles of the reconciliation window preceding `baseBlockNum`
+	ReconcileMirrors(ctx context.Context, baseBlockNum uint64) error
+}
+
+type HeadIOInterface interface {
+	// WriteHead replaces the head pointer of the merged blocks store, called after each merged bundle
+	WriteHead(ctx context.Context, head *types.MergedBlocksStoreHead) error
 }
 
 type ForkAwareDStoreIO struct {
 	*DStoreIO
 	forkedBlocksStore dstore.Store
-	forkOd            *oneBlockFilesDeleter
+	// forkedBlocksArchiveStore receives the forked blocks before they are pruned, nil if they are not archived
+	forkedBlocksArchiveStore dstore.Store
+	forkOd                   *oneBlockFilesDeleter
 }
 
 type DStoreIO struct {
 	oneBlocksStore    dstore.Store
 	mergedBlocksStore dstore.Store
+	// sidecarStore receives the block index sidecar of each merged bundle, nil if sidecars are disabled
+	sidecarStore dstore.Store
 
 	retryAttempts int
 	retryCooldown time.Duration
@@ -70,6 +99,15 @@ type DStoreIO struct {
 	tracer logging.Tracer
 	od     *oneBlockFilesDeleter
 	forkOd *oneBlockFilesDeleter
+
+	// mirrors receive a copy of each merged bundle, see EnableMirroring
+	mirrors               []*mirrorDestination
+	mirrorDeletionPolicy  MirrorDeletionPolicy
+	mirrorReconcileWindow uint64
+
+	headLock sync.Mutex
+	// headStore is the merged blocks store allowing overwrites of its head pointer, created on first write
+	headStore dstore.Store
 }
 
 func NewDStoreIO(
@@ -78,6 +116,8 @@ func NewDStoreIO(
 	oneBlocksStore dstore.Store,
 	mergedBlocksStore dstore.Store,
 	forkedBlocksStore dstore.Store,
+	forkedBlocksArchiveStore dstore.Store,
+	sidecarStore dstore.Store,
 	retryAttempts int,
 	retryCooldown time.Duration,
 	bundleSize uint64,
@@ -89,6 +129,7 @@ func NewDStoreIO(
 	dstoreIO := &DStoreIO{
 		oneBlocksStore:    oneBlocksStore,
 		mergedBlocksStore: mergedBlocksStore,
+		sidecarStore:      sidecarStore,
 		retryAttempts:     retryAttempts,
 		retryCooldown:     retryCooldown,
 		bundleSize:        bundleSize,
@@ -106,9 +147,10 @@ func NewDStoreIO(
 	forkOd.Start(numDeleteThreads, DefaultFilesDeleteBatchSize*2)
 
 	return &ForkAwareDStoreIO{
-		DStoreIO:          dstoreIO,
-		forkedBlocksStore: forkedBlocksStore,
-		forkOd:            forkOd,
+		DStoreIO:                 dstoreIO,
+		forkedBlocksStore:        forkedBlocksStore,
+		forkedBlocksArchiveStore: forkedBlocksArchiveStore,
+		forkOd:                   forkOd,
 	}
 }
 
@@ -141,6 +183,8 @@ func (s *DStoreIO) MergeAndStore(ctx context.Context, inclusiveLowerBlock uint64
 
 	s.logger.Info("about to write merged blocks to storage location", zapFields...)
 
+	var bundleSidecar *sidecar.Sidecar
+
 	err = Retry(s.logger, s.retryAttempts, s.retryCooldown, func() error {
 		inCtx, cancel := context.WithTimeout(ctx, WriteObjectTimeout)
 		defer cancel()
@@ -148,17 +192,83 @@ func (s *DStoreIO) MergeAndStore(ctx context.Context, inclusiveLowerBlock uint64
 		if err != nil {
 			return err
 		}
-		return s.mergedBlocksStore.WriteObject(inCtx, bundleFilename, bundleReader)
+		if s.sidecarStore == nil {
+			return s.mergedBlocksStore.WriteObject(inCtx, bundleFilename, bundleReader)
+		}
+
+		bundleSidecar, err = sidecar.WriteBundle(inCtx, s.mergedBlocksStore, inclusiveLowerBlock, bundleReader)
+		if errors.Is(err, sidecar.ErrBuildFailed) {
+			s.logger.Warn("unable to build sidecar of merged bundle", zap.String("filename", bundleFilename), zap.Error(err))
+			return nil
+		}
+		return err
 	})
 	if err != nil {
 		return fmt.Errorf("write object error: %s", err)
 	}
 
+	if s.sidecarStore != nil {
+		if err := s.writeSidecar(ctx, inclusiveLowerBlock, bundleSidecar); err != nil {
+			return fmt.Errorf("write sidecar of merged bundle %s: %w", bundleFilename, err)
+		}
+	}
+
+	for _, mirror := range s.mirrors {
+		mirror.enqueue(inclusiveLowerBlock)
+	}
+
 	s.logger.Info("merged and uploaded", zap.String("filename", fileNameForBlocksBundle(inclusiveLowerBlock)), zap.Duration("merge_time", time.Since(t0)))
 
 	return
 }
 
+// writeSidecar writes the sidecar of the bundle at `baseBlockNum`, nil when it could not be built. A bundle without a
+// sidecar only makes single block fetches slower and it can be backfilled later on, but the sidecar of a previous write
+// of the bundle must not be kept: its checksums would not match the bundle. The error is only returned when that
+// sidecar could not be deleted.
+func (s *DStoreIO) writeSidecar(ctx context.Context, baseBlockNum uint64, bundleSidecar *sidecar.Sidecar) error {
+	if bundleSidecar != nil {
+		err := Retry(s.logger, s.retryAttempts, s.retryCooldown, func() error {
+			inCtx, cancel := context.WithTimeout(ctx, WriteObjectTimeout)
+			defer cancel()
+
+			return sidecar.Write(inCtx, s.sidecarStore, bundleSidecar)
+		})
+		if err == nil {
+			return nil
+		}
+
+		s.logger.Warn("unable to write sidecar of merged bundle, deleting the previous one", zap.Uint64("base_block_num", baseBlockNum), zap.Error(err))
+	}
+
+	return Retry(s.logger, s.retryAttempts, s.retryCooldown, func() error {
+		inCtx, cancel := context.WithTimeout(ctx, WriteObjectTimeout)
+		defer cancel()
+
+		return sidecar.Delete(inCtx, s.sidecarStore, baseBlockNum)
+	})
+}
+
+func (s *DStoreIO) WriteOneBlockFile(ctx context.Context, block *pbbstream.Block) error {
+	buffer := new(bytes.Buffer)
+	writer, err := bstream.NewDBinBlockWriter(buffer)
+	if err != nil {
+		return fmt.Errorf("creating block writer: %w", err)
+	}
+
+	if err := writer.Write(block); err != nil {
+		return fmt.Errorf("writing block: %w", err)
+	}
+
+	filename := bstream.BlockFileNameWithSuffix(block, filledOneBlockFileSuffix)
+	return Retry(s.logger, s.retryAttempts, s.retryCooldown, func() error {
+		ctx, cancel := context.WithTimeout(ctx, WriteObjectTimeout)
+		defer cancel()
+
+		return s.oneBlocksStore.WriteObject(ctx, filename, bytes.NewReader(buffer.Bytes()))
+	})
+}
+
 func (s *DStoreIO) WalkOneBlockFiles(ctx context.Context, lowestBlock uint64, callback func(*bstream.OneBlockFile) error) error {
 	return s.oneBlocksStore.WalkFrom(ctx, "", fileNameForBlocksBundle(lowestBlock), func(filename string) error {
 		if strings.HasSuffix(filename, ".tmp") {
@@ -285,6 +395,9 @@ func (s *DStoreIO) readLastBlockFromMerged(ctx context.Context, baseBlock uint64
 }
 
 func (s *DStoreIO) DeleteAsync(oneBlockFiles []*bstream.OneBlockFile) error {
+	if s.mirrorDeletionPolicy == MirrorDeletionPolicyAll {
+		oneBlockFiles = s.filterMirrorPending(oneBlockFiles)
+	}
 	return s.od.Delete(oneBlockFiles)
 }
 
@@ -330,9 +443,100 @@ func (s *ForkAwareDStoreIO) DeleteForkedBlocksAsync(inclusiveLowBoundary, inclus
 		)
 	}
 
+	if s.forkedBlocksArchiveStore != nil {
+		forkedBlockFiles, err = s.archiveForkedBlocks(context.Background(), forkedBlockFiles)
+		if err != nil {
+			// forked blocks are kept until they are archived, the next pruning run tries again
+			s.logger.Warn("cannot archive forked blocks, not deleting them",
+				zap.Uint64("inclusive_high_boundary", inclusiveHighBoundary),
+				zap.Error(err),
+			)
+			return
+		}
+	}
+
 	s.forkOd.Delete(forkedBlockFiles)
 }
 
+// archiveForkedBlocks packs the forked blocks into forked bundles of the forked blocks archive store, returning the
+// archived ones. Forked blocks that cannot be read are reported and skipped, they are left in the forked blocks
+// store for the operator to look at instead of blocking the archiving of the others.
+func (s *ForkAwareDStoreIO) archiveForkedBlocks(ctx context.Context, forkedBlockFiles []*bstream.OneBlockFile) ([]*bstream.OneBlockFile, error) {
+	var archived []*bstream.OneBlockFile
+	var blocks []*pbbstream.Block
+	for _, obf := range forkedBlockFiles {
+		block, err := s.readForkedBlockFile(ctx, obf)
+		if err != nil {
+			s.logger.Warn("skipping unreadable forked block, not archiving nor deleting it",
+				zap.Uint64("block_num", obf.Num),
+				zap.String("block_id", obf.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		archived = append(archived, obf)
+		blocks = append(blocks, block)
+	}
+
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+
+	if err := forkarchive.Write(ctx, s.forkedBlocksArchiveStore, blocks); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("archived forked blocks",
+		zap.Int("forked_block_count", len(blocks)),
+		zap.Int("skipped_forked_block_count", len(forkedBlockFiles)-len(archived)),
+		zap.Uint64("lowest_block_num", archived[0].Num),
+		zap.Uint64("highest_block_num", archived[len(archived)-1].Num),
+	)
+	return archived, nil
+}
+
+// readForkedBlockFile reads the forked block from one of the files of `obf`, trying the next one when a file
+// cannot be read
+func (s *ForkAwareDStoreIO) readForkedBlockFile(ctx context.Context, obf *bstream.OneBlockFile) (block *pbbstream.Block, err error) {
+	for filename := range obf.Filenames {
+		block, err = s.readForkedBlock(ctx, filename)
+		if err == nil {
+			return block, nil
+		}
+	}
+	return nil, err
+}
+
+func (s *ForkAwareDStoreIO) readForkedBlock(ctx context.Context, filename string) (*pbbstream.Block, error) {
+	reader, err := s.forkedBlocksStore.OpenObject(ctx, filename)
+	if err != nil {
+		return nil, fmt.Errorf("opening forked block %s: %w", filename, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("reading forked block %s: %w", filename, err)
+	}
+
+	block, err := bstream.DecodeOneblockfileData(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding forked block %s: %w", filename, err)
+	}
+	return block, nil
+}
+
+func (s *ForkAwareDStoreIO) CountForkedBlocks(ctx context.Context) (count int, err error) {
+	err = s.forkedBlocksStore.Walk(ctx, "", func(filename string) error {
+		if !strings.HasSuffix(filename, ".tmp") {
+			count++
+		}
+		return nil
+	})
+	return
+}
+
 type oneBlockFilesDeleter struct {
 	sync.Mutex
 	toProcess     chan string
@@ -17,6 +17,7 @@ package merger
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"testing"
 	"time"
@@ -29,6 +30,9 @@ import (
 
 	"github.com/streamingfast/bstream"
 	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/forkarchive"
+	"github.com/streamingfast/firehose-core/sidecar"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -39,6 +43,8 @@ func TestNewDstore(t *testing.T) {
 		dstore.NewMockStore(nil),
 		dstore.NewMockStore(nil),
 		dstore.NewMockStore(nil),
+		nil,
+		nil,
 		1,
 		0,
 		100,
@@ -55,6 +61,8 @@ func TestNewDstore(t *testing.T) {
 		dstore.NewMockStore(nil),
 		dstore.NewMockStore(nil),
 		nil,
+		nil,
+		nil,
 		1,
 		0,
 		100,
@@ -69,7 +77,7 @@ func newTestDStoreIO(
 	oneBlocksStore dstore.Store,
 	mergedBlocksStore dstore.Store,
 ) IOInterface {
-	return NewDStoreIO(testLogger, testTracer, oneBlocksStore, mergedBlocksStore, nil, 0, 0, 100, 0)
+	return NewDStoreIO(testLogger, testTracer, oneBlocksStore, mergedBlocksStore, nil, nil, nil, 0, 0, 100, 0)
 }
 
 func TestMergerIO_MergeUploadPerfect(t *testing.T) {
@@ -233,3 +241,154 @@ func TestMergerIO_MergeUploadFilteredToZero(t *testing.T) {
 	err := mio.MergeAndStore(context.Background(), 114, files)
 	require.NoError(t, err)
 }
+
+// newTestOneBlocksStore returns a one-block files store serving, for any one-block file name, a block matching it
+func newTestOneBlocksStore(t *testing.T) *dstore.MockStore {
+	oneBlockStore := dstore.NewMockStore(nil)
+	oneBlockStore.OpenObjectFunc = func(_ context.Context, name string) (io.ReadCloser, error) {
+		num, id, previousID, _, _, err := bstream.ParseFilename(name)
+		require.NoError(t, err)
+
+		anyB, err := anypb.New(&test.Block{Number: num})
+		require.NoError(t, err)
+
+		out := new(bytes.Buffer)
+		w, err := bstream.NewDBinBlockWriter(out)
+		require.NoError(t, err)
+		require.NoError(t, w.Write(&pbbstream.Block{Number: num, Id: id, ParentNum: num - 1, ParentId: previousID, Payload: anyB}))
+
+		return io.NopCloser(out), nil
+	}
+	return oneBlockStore
+}
+
+func TestMergerIO_MergeUploadWritesSidecar(t *testing.T) {
+	files := []*bstream.OneBlockFile{
+		block100(),
+		block101(),
+	}
+
+	oneBlockStore := newTestOneBlocksStore(t)
+
+	mergedBlocksStore, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	sidecarStore, err := sidecar.NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	mio := NewDStoreIO(testLogger, testTracer, oneBlockStore, mergedBlocksStore, nil, nil, sidecarStore, 0, 0, 100, 0)
+
+	err = mio.MergeAndStore(context.Background(), 100, files)
+	require.NoError(t, err)
+
+	bundleSidecar, err := sidecar.Read(context.Background(), sidecarStore, 100)
+	require.NoError(t, err)
+	require.Len(t, bundleSidecar.Blocks, 2)
+	assert.Equal(t, "0000000000000101a", bundleSidecar.Last().ID)
+
+	block, err := sidecar.FetchBlock(context.Background(), mergedBlocksStore, bundleSidecar, bundleSidecar.Last())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(101), block.Number)
+}
+
+func TestForkAwareDStoreIO_ArchivesForkedBlocks(t *testing.T) {
+	ctx := context.Background()
+
+	forkedBlocksStore, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	for _, block := range []*pbbstream.Block{
+		{Number: 101, Id: "0000000000000101b", ParentNum: 100, ParentId: "0000000000000100a", LibNum: 99},
+		{Number: 102, Id: "0000000000000102b", ParentNum: 101, ParentId: "0000000000000101b", LibNum: 99},
+		{Number: 250, Id: "0000000000000250b", ParentNum: 249, ParentId: "0000000000000249a", LibNum: 248},
+	} {
+		anyB, err := anypb.New(&test.Block{Number: block.Number})
+		require.NoError(t, err)
+		block.Payload = anyB
+
+		out := new(bytes.Buffer)
+		w, err := bstream.NewDBinBlockWriter(out)
+		require.NoError(t, err)
+		require.NoError(t, w.Write(block))
+		require.NoError(t, forkedBlocksStore.WriteObject(ctx, bstream.BlockFileNameWithSuffix(block, "suffix"), out))
+	}
+
+	archiveStore, err := forkarchive.NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	mio := NewDStoreIO(testLogger, testTracer, dstore.NewMockStore(nil), dstore.NewMockStore(nil), forkedBlocksStore, archiveStore, nil, 0, 0, 100, 0)
+	mio.(ForkAwareIOInterface).DeleteForkedBlocksAsync(0, 200)
+
+	block, err := forkarchive.FetchBlock(ctx, archiveStore, 102, "0000000000000102b")
+	require.NoError(t, err)
+	assert.Equal(t, "0000000000000101b", block.ParentId)
+
+	_, err = forkarchive.FetchBlock(ctx, archiveStore, 250, "0000000000000250b")
+	assert.ErrorIs(t, err, dstore.ErrNotFound, "above pruning boundary")
+}
+
+func TestForkAwareDStoreIO_ArchivesForkedBlocksSkippingUnreadableOnes(t *testing.T) {
+	ctx := context.Background()
+
+	forkedBlocksStore, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	block := &pbbstream.Block{Number: 101, Id: "0000000000000101b", ParentNum: 100, ParentId: "0000000000000100a", LibNum: 99}
+	anyB, err := anypb.New(&test.Block{Number: block.Number})
+	require.NoError(t, err)
+	block.Payload = anyB
+
+	out := new(bytes.Buffer)
+	w, err := bstream.NewDBinBlockWriter(out)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(block))
+	require.NoError(t, forkedBlocksStore.WriteObject(ctx, bstream.BlockFileNameWithSuffix(block, "suffix"), out))
+
+	unreadable := "0000000102-0000000000000102b-0000000000000101b-99-suffix"
+	require.NoError(t, forkedBlocksStore.WriteObject(ctx, unreadable, bytes.NewReader([]byte("not a block"))))
+
+	archiveStore, err := forkarchive.NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	mio := NewDStoreIO(testLogger, testTracer, dstore.NewMockStore(nil), dstore.NewMockStore(nil), forkedBlocksStore, archiveStore, nil, 0, 0, 100, 1)
+	mio.(ForkAwareIOInterface).DeleteForkedBlocksAsync(0, 200)
+
+	_, err = forkarchive.FetchBlock(ctx, archiveStore, 101, "0000000000000101b")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		exists, err := forkedBlocksStore.FileExists(ctx, bstream.BlockFileNameWithSuffix(block, "suffix"))
+		return err == nil && !exists
+	}, 5*time.Second, 10*time.Millisecond, "archived forked block is deleted")
+
+	exists, err := forkedBlocksStore.FileExists(ctx, unreadable)
+	require.NoError(t, err)
+	assert.True(t, exists, "unreadable forked block is kept")
+}
+
+// failingWritesStore fails every object write
+type failingWritesStore struct {
+	dstore.Store
+}
+
+func (s *failingWritesStore) WriteObject(_ context.Context, _ string, _ io.Reader) error {
+	return errors.New("write failed")
+}
+
+func TestMergerIO_MergeUploadDeletesStaleSidecar(t *testing.T) {
+	ctx := context.Background()
+
+	mergedBlocksStore, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	sidecarStore, err := sidecar.NewStore(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, sidecar.Write(ctx, sidecarStore, &sidecar.Sidecar{BaseBlockNum: 100, Checksum: "previous"}))
+
+	mio := NewDStoreIO(testLogger, testTracer, newTestOneBlocksStore(t), mergedBlocksStore, nil, nil, &failingWritesStore{Store: sidecarStore}, 0, 0, 100, 0)
+
+	require.NoError(t, mio.MergeAndStore(ctx, 100, []*bstream.OneBlockFile{block100(), block101()}))
+
+	_, err = sidecar.Read(ctx, sidecarStore, 100)
+	assert.ErrorIs(t, err, dstore.ErrNotFound)
+}
@@ -21,3 +21,11 @@ var MetricSet = dmetrics.NewSet()
 var HeadBlockTimeDrift = MetricSet.NewHeadTimeDrift("merger")
 var HeadBlockNumber = MetricSet.NewHeadBlockNumber("merger")
 var AppReadiness = MetricSet.NewAppReadiness("merger")
+
+var MissingBlocks = MetricSet.NewGauge("merger_missing_blocks", "Number of missing or unlinkable one-block files found in the last hole detection")
+var FilledBlocks = MetricSet.NewCounter("merger_filled_blocks", "Number of one-block files fetched from hole fillers")
+var CatchUpMergesInFlight = MetricSet.NewGauge("merger_catch_up_merges_in_flight", "Number of bundles merged concurrently (or waiting for the bundles below them) while the merger catches up")
+
+var MirrorPendingBundles = MetricSet.NewGaugeVec("merger_mirror_pending_bundles", []string{"destination"}, "Number of merged bundles waiting to be copied to a mirror merged blocks store")
+var MirrorLagBlocks = MetricSet.NewGaugeVec("merger_mirror_lag_blocks", []string{"destination"}, "Number of blocks a mirror merged blocks store is behind the primary one")
+var MirrorFailures = MetricSet.NewCounterVec("merger_mirror_failures", []string{"destination"}, "Number of failed copies of merged bundles to a mirror merged blocks store")
@@ -0,0 +1,286 @@
+package merger
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/streamingfast/bstream"
+	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/merger/metrics"
+	"go.uber.org/zap"
+	"gopkg.in/olivere/elastic.v3/backoff"
+)
+
+// MirrorDeletionPolicy decides which merged blocks stores must hold a bundle before its one-block files can be deleted
+type MirrorDeletionPolicy string
+
+const (
+	// MirrorDeletionPolicyPrimary deletes one-block files once their bundle is in the primary merged blocks store
+	MirrorDeletionPolicyPrimary MirrorDeletionPolicy = "primary"
+	// MirrorDeletionPolicyAll keeps one-block files until their bundle is in all the mirror stores too
+	MirrorDeletionPolicyAll MirrorDeletionPolicy = "all"
+)
+
+func ParseMirrorDeletionPolicy(in string) (MirrorDeletionPolicy, error) {
+	switch policy := MirrorDeletionPolicy(in); policy {
+	case MirrorDeletionPolicyPrimary, MirrorDeletionPolicyAll:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid mirror deletion policy %q, accepted values are %q and %q", in, MirrorDeletionPolicyPrimary, MirrorDeletionPolicyAll)
+	}
+}
+
+// EnableMirroring makes the merger copy each bundle written to the primary merged blocks store to the `mirrors`
+// stores, each having its own retry queue. ReconcileMirrors looks at the bundles of the last `reconcileWindow`
+// blocks, 0 disabling reconciliation. Must be called before any bundle is merged.
+func (s *DStoreIO) EnableMirroring(mirrors []dstore.Store, policy MirrorDeletionPolicy, reconcileWindow uint64) {
+	s.mirrorDeletionPolicy = policy
+	s.mirrorReconcileWindow = reconcileWindow
+
+	for _, store := range mirrors {
+		destination := &mirrorDestination{
+			name:          store.BaseURL().Redacted(),
+			store:         store,
+			source:        s.mergedBlocksStore,
+			bundleSize:    s.bundleSize,
+			retryCooldown: s.retryCooldown,
+			pending:       map[uint64]bool{},
+			notify:        make(chan struct{}, 1),
+		}
+		destination.logger = s.logger.With(zap.String("mirror", destination.name))
+
+		s.mirrors = append(s.mirrors, destination)
+		go destination.run(context.Background())
+	}
+}
+
+func (s *DStoreIO) ReconcileMirrors(ctx context.Context, baseBlockNum uint64) error {
+	if len(s.mirrors) == 0 || s.mirrorReconcileWindow == 0 {
+		return nil
+	}
+
+	lowBlockNum := uint64(0)
+	if baseBlockNum > s.mirrorReconcileWindow {
+		lowBlockNum = toBaseNum(baseBlockNum-s.mirrorReconcileWindow, s.bundleSize)
+	}
+
+	primaryBundles, err := listBundles(ctx, s.mergedBlocksStore, lowBlockNum, baseBlockNum)
+	if err != nil {
+		return fmt.Errorf("listing primary merged blocks store bundles: %w", err)
+	}
+
+	for _, mirror := range s.mirrors {
+		mirrorBundles, err := listBundles(ctx, mirror.store, lowBlockNum, baseBlockNum)
+		if err != nil {
+			return fmt.Errorf("listing mirror %s bundles: %w", mirror.name, err)
+		}
+
+		existing := map[uint64]bool{}
+		for _, base := range mirrorBundles {
+			existing[base] = true
+		}
+
+		missing := 0
+		for _, base := range primaryBundles {
+			if !existing[base] {
+				mirror.enqueue(base)
+				missing++
+			}
+		}
+
+		mirror.logger.Info("reconciled mirror with primary merged blocks store",
+			zap.Uint64("low_block_num", lowBlockNum),
+			zap.Uint64("base_block_num", baseBlockNum),
+			zap.Int("missing_bundle_count", missing),
+		)
+	}
+
+	return nil
+}
+
+// reconcileMirrorsAsync reconciles the mirror stores in the background, bundles merged meanwhile being queued as usual
+func (m *Merger) reconcileMirrorsAsync(ctx context.Context, baseBlockNum uint64) {
+	mirroringIO, ok := m.io.(MirroringIOInterface)
+	if !ok {
+		return
+	}
+
+	go func() {
+		if err := mirroringIO.ReconcileMirrors(ctx, baseBlockNum); err != nil {
+			m.logger.Warn("unable to reconcile mirror merged blocks stores", zap.Error(err))
+		}
+	}()
+}
+
+// filterMirrorPending returns the one-block files whose bundle is in all the mirror stores
+func (s *DStoreIO) filterMirrorPending(oneBlockFiles []*bstream.OneBlockFile) []*bstream.OneBlockFile {
+	lowestPending, found := uint64(0), false
+	for _, mirror := range s.mirrors {
+		if base, ok := mirror.lowestPending(); ok && (!found || base < lowestPending) {
+			lowestPending, found = base, true
+		}
+	}
+
+	if !found {
+		return oneBlockFiles
+	}
+
+	var out []*bstream.OneBlockFile
+	for _, obf := range oneBlockFiles {
+		if obf.Num < lowestPending {
+			out = append(out, obf)
+		}
+	}
+	return out
+}
+
+// listBundles returns the base block of the bundles of `store` in [low, high[
+func listBundles(ctx context.Context, store dstore.Store, low, high uint64) (out []uint64, err error) {
+	err = store.WalkFrom(ctx, "", fileNameForBlocksBundle(low), func(filename string) error {
+		base, err := strconv.ParseUint(filename, 10, 64)
+		if err != nil {
+			// not a bundle, like the merged blocks store metadata
+			return nil
+		}
+
+		if base >= high {
+			return dstore.StopIteration
+		}
+
+		out = append(out, base)
+		return nil
+	})
+	return
+}
+
+type mirrorDestination struct {
+	name   string
+	store  dstore.Store
+	source dstore.Store
+	logger *zap.Logger
+
+	bundleSize    uint64
+	retryCooldown time.Duration
+
+	lock sync.Mutex
+	// pending holds the base block of the bundles to copy
+	pending      map[uint64]bool
+	newestQueued uint64
+	notify       chan struct{}
+}
+
+func (d *mirrorDestination) enqueue(baseBlockNum uint64) {
+	d.lock.Lock()
+	d.pending[baseBlockNum] = true
+	if baseBlockNum > d.newestQueued {
+		d.newestQueued = baseBlockNum
+	}
+	d.updateMetrics()
+	d.lock.Unlock()
+
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (d *mirrorDestination) lowestPending() (uint64, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.lowestPendingLocked()
+}
+
+func (d *mirrorDestination) lowestPendingLocked() (lowest uint64, found bool) {
+	for base := range d.pending {
+		if !found || base < lowest {
+			lowest, found = base, true
+		}
+	}
+	return
+}
+
+func (d *mirrorDestination) done(baseBlockNum uint64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	delete(d.pending, baseBlockNum)
+	d.updateMetrics()
+}
+
+// updateMetrics must be called with the lock held
+func (d *mirrorDestination) updateMetrics() {
+	metrics.MirrorPendingBundles.SetInt(len(d.pending), d.name)
+
+	lag := uint64(0)
+	if lowest, found := d.lowestPendingLocked(); found {
+		lag = d.newestQueued + d.bundleSize - lowest
+	}
+	metrics.MirrorLagBlocks.SetUint64(lag, d.name)
+}
+
+// run copies the pending bundles to the mirror in block order, retrying failed copies forever
+func (d *mirrorDestination) run(ctx context.Context) {
+	retryBackoff := backoff.NewExponentialBackoff(max(d.retryCooldown, 100*time.Millisecond), time.Minute)
+	for {
+		base, found := d.lowestPending()
+		if !found {
+			select {
+			case <-ctx.Done():
+				return
+			case <-d.notify:
+				continue
+			}
+		}
+
+		if err := d.copy(ctx, base); err != nil {
+			metrics.MirrorFailures.Inc(d.name)
+			delay := retryBackoff.Next()
+			d.logger.Warn("unable to copy bundle to mirror, retrying", zap.Uint64("base_block_num", base), zap.Duration("retry_in", delay), zap.Error(err))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			continue
+		}
+
+		retryBackoff.Reset()
+		d.done(base)
+		d.logger.Debug("copied bundle to mirror", zap.Uint64("base_block_num", base))
+	}
+}
+
+func (d *mirrorDestination) copy(ctx context.Context, baseBlockNum uint64) error {
+	ctx, cancel := context.WithTimeout(ctx, WriteObjectTimeout)
+	defer cancel()
+
+	filename := fileNameForBlocksBundle(baseBlockNum)
+	reader, err := d.source.OpenObject(ctx, filename)
+	if err != nil {
+		return fmt.Errorf("opening bundle %s from primary merged blocks store: %w", filename, err)
+	}
+	defer reader.Close()
+
+	if err := d.store.WriteObject(ctx, filename, reader); err != nil {
+		return fmt.Errorf("writing bundle %s: %w", filename, err)
+	}
+	return nil
+}
+
+// pendingBundles returns the sorted base block of the bundles still to be copied to the mirror
+func (d *mirrorDestination) pendingBundles() (out []uint64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for base := range d.pending {
+		out = append(out, base)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return
+}
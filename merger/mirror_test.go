@@ -0,0 +1,104 @@
+package merger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/bstream"
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDStoreIO_MirrorsMergedBundles(t *testing.T) {
+	mergedBlocksStore, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	mirrorStore, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	var lock sync.Mutex
+	var flakyAttempts int
+	var flakyWritten []string
+	flakyStore := dstore.NewMockStore(func(base string, f io.Reader) error {
+		lock.Lock()
+		defer lock.Unlock()
+
+		flakyAttempts++
+		if flakyAttempts <= 2 {
+			return fmt.Errorf("mirror unavailable")
+		}
+		flakyWritten = append(flakyWritten, base)
+		return nil
+	})
+
+	mio := NewDStoreIO(testLogger, testTracer, newTestOneBlocksStore(t), mergedBlocksStore, nil, nil, nil, 0, 0, 100, 0)
+	mio.(MirroringIOInterface).EnableMirroring([]dstore.Store{mirrorStore, flakyStore}, MirrorDeletionPolicyPrimary, 0)
+
+	require.NoError(t, mio.MergeAndStore(context.Background(), 100, []*bstream.OneBlockFile{block100(), block101()}))
+
+	require.Eventually(t, func() bool {
+		exists, err := mirrorStore.FileExists(context.Background(), "0000000100")
+		return err == nil && exists
+	}, 2*time.Second, 10*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(flakyWritten) == 1
+	}, 5*time.Second, 10*time.Millisecond, "flaky mirror retried until the copy succeeds")
+	assert.Equal(t, []string{"0000000100"}, flakyWritten)
+}
+
+func TestDStoreIO_ReconcileMirrors(t *testing.T) {
+	ctx := context.Background()
+
+	mergedBlocksStore, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	mirrorStore, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	for _, filename := range []string{"0000000000", "0000000100", "0000000200", "0000000300", "0000000400"} {
+		require.NoError(t, mergedBlocksStore.WriteObject(ctx, filename, strings.NewReader(filename)))
+	}
+	require.NoError(t, mirrorStore.WriteObject(ctx, "0000000200", strings.NewReader("0000000200")))
+
+	mio := NewDStoreIO(testLogger, testTracer, dstore.NewMockStore(nil), mergedBlocksStore, nil, nil, nil, 0, 0, 100, 0)
+	mio.(MirroringIOInterface).EnableMirroring([]dstore.Store{mirrorStore}, MirrorDeletionPolicyPrimary, 250)
+
+	// the window starts at 100 and bundles from 400 on are merged (and queued) by the merger itself
+	require.NoError(t, mio.(MirroringIOInterface).ReconcileMirrors(ctx, 400))
+
+	require.Eventually(t, func() bool {
+		return len(mio.(*DStoreIO).mirrors[0].pendingBundles()) == 0
+	}, 2*time.Second, 10*time.Millisecond)
+
+	bundles, err := listBundles(ctx, mirrorStore, 0, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{100, 200, 300}, bundles)
+}
+
+func TestDStoreIO_MirrorDeletionPolicyAll(t *testing.T) {
+	failingStore := dstore.NewMockStore(func(base string, f io.Reader) error {
+		return fmt.Errorf("mirror unavailable")
+	})
+
+	files := []*bstream.OneBlockFile{
+		bstream.MustNewOneBlockFile("0000000199-0000000000000199a-0000000000000198a-98-suffix"),
+		bstream.MustNewOneBlockFile("0000000200-0000000000000200a-0000000000000199a-99-suffix"),
+		bstream.MustNewOneBlockFile("0000000201-0000000000000201a-0000000000000200a-100-suffix"),
+	}
+
+	mio := NewDStoreIO(testLogger, testTracer, dstore.NewMockStore(nil), dstore.NewMockStore(nil), nil, nil, nil, 0, time.Hour, 100, 0).(*DStoreIO)
+	mio.EnableMirroring([]dstore.Store{failingStore}, MirrorDeletionPolicyAll, 0)
+	assert.Equal(t, files, mio.filterMirrorPending(files), "nothing pending")
+
+	mio.mirrors[0].enqueue(200)
+	assert.Equal(t, files[:1], mio.filterMirrorPending(files), "files of the pending bundle kept")
+}
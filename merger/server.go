@@ -2,6 +2,7 @@ package merger
 
 import (
 	dgrpcfactory "github.com/streamingfast/dgrpc/server/factory"
+	pbmerger "github.com/streamingfast/firehose-core/pb/sf/firehose/merger/v1"
 	pbhealth "google.golang.org/grpc/health/grpc_health_v1"
 )
 
@@ -14,6 +15,7 @@ func (m *Merger) startGRPCServer() {
 		gs.Shutdown(0)
 	})
 	pbhealth.RegisterHealthServer(gs.ServiceRegistrar(), m)
+	pbmerger.RegisterMergerStatusServer(gs.ServiceRegistrar(), &statusServer{merger: m})
 	m.logger.Info("server registered")
 
 	go gs.Launch(m.grpcListenAddr)
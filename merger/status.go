@@ -0,0 +1,123 @@
+package merger
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Status describes the current state of the merger
+type Status struct {
+	// BundlerBaseBlockNum is the base block of the bundle being accumulated
+	BundlerBaseBlockNum uint64              `json:"bundler_base_block_num"`
+	LastMergedBundle    *MergedBundleStatus `json:"last_merged_bundle,omitempty"`
+	// TimeSinceLastMerge is unset until the merger merged a bundle
+	TimeSinceLastMerge string `json:"time_since_last_merge,omitempty"`
+	timeSinceLastMerge time.Duration
+
+	// PendingOneBlockFiles is the number of one-block files at or above the bundler base block, as of the last poll
+	PendingOneBlockFiles int `json:"pending_one_block_files"`
+	// ForkedBlocksAwaitingPruning is the number of forked blocks in the forked blocks store, counted at most once
+	// every [forkedBlocksCountTTL], unset when the merger is not configured with a forked blocks store
+	ForkedBlocksAwaitingPruning *int `json:"forked_blocks_awaiting_pruning,omitempty"`
+
+	// HoldsWriterLock tells if the merger holds the merger writer lock, unset when the writer lock is not enabled
+	HoldsWriterLock *bool `json:"holds_writer_lock,omitempty"`
+
+	LastOneBlockFilesPruning *PruningStatus `json:"last_one_block_files_pruning,omitempty"`
+	LastForkedBlocksPruning  *PruningStatus `json:"last_forked_blocks_pruning,omitempty"`
+}
+
+type MergedBundleStatus struct {
+	BaseBlockNum uint64 `json:"base_block_num"`
+	// LIBNum and LIBID are the last block of the bundle, the LIB the merger resumes from
+	LIBNum uint64 `json:"lib_num"`
+	LIBID  string `json:"lib_id"`
+	// MergedAt is zero when the bundle was merged before the merger started
+	MergedAt time.Time `json:"merged_at,omitempty"`
+}
+
+type PruningStatus struct {
+	At time.Time `json:"at"`
+	// Target is the block below which files were pruned
+	Target uint64 `json:"target"`
+	// DeletedFiles is the number of files scheduled for deletion, unset for forked blocks
+	DeletedFiles *int `json:"deleted_files,omitempty"`
+}
+
+func (m *Merger) Status(ctx context.Context) *Status {
+	status := &Status{
+		BundlerBaseBlockNum: m.bundler.BaseBlockNum(),
+		LastMergedBundle:    m.bundler.lastMergedBundle(),
+	}
+
+	if merged := status.LastMergedBundle; merged != nil && !merged.MergedAt.IsZero() {
+		status.timeSinceLastMerge = time.Since(merged.MergedAt)
+		status.TimeSinceLastMerge = status.timeSinceLastMerge.Round(time.Millisecond).String()
+	}
+
+	if m.writerLockElector != nil {
+		holds := m.writerLockElector.IsLeader()
+		status.HoldsWriterLock = &holds
+	}
+
+	m.statusLock.Lock()
+	status.PendingOneBlockFiles = m.pendingOneBlockFiles
+	status.LastOneBlockFilesPruning = m.lastOneBlockFilesPruning
+	status.LastForkedBlocksPruning = m.lastForkedBlocksPruning
+	m.statusLock.Unlock()
+
+	if forkableIO, ok := m.io.(ForkAwareIOInterface); ok {
+		status.ForkedBlocksAwaitingPruning = m.forkedBlocksCount(ctx, forkableIO)
+	}
+
+	return status
+}
+
+// forkedBlocksCountTTL is how long the count of forked blocks is reused, counting them walks the forked blocks store
+const forkedBlocksCountTTL = 30 * time.Second
+
+// forkedBlocksCount returns the number of forked blocks, counted again once the last count is older than
+// [forkedBlocksCountTTL], nil when they cannot be counted
+func (m *Merger) forkedBlocksCount(ctx context.Context, forkableIO ForkAwareIOInterface) *int {
+	m.forkedBlocksCountLock.Lock()
+	defer m.forkedBlocksCountLock.Unlock()
+
+	if m.lastForkedBlocksCount != nil && m.now().Sub(m.lastForkedBlocksCountAt) < forkedBlocksCountTTL {
+		count := *m.lastForkedBlocksCount
+		return &count
+	}
+
+	count, err := forkableIO.CountForkedBlocks(ctx)
+	if err != nil {
+		m.logger.Warn("unable to count forked blocks", zap.Error(err))
+		return nil
+	}
+
+	m.lastForkedBlocksCount = &count
+	m.lastForkedBlocksCountAt = m.now()
+
+	out := count
+	return &out
+}
+
+func (m *Merger) setPendingOneBlockFiles(count int) {
+	m.statusLock.Lock()
+	defer m.statusLock.Unlock()
+
+	m.pendingOneBlockFiles = count
+}
+
+// recordPruning sets `*last` to a new pruning status, `deletedFiles` being negative when unknown
+func (m *Merger) recordPruning(last **PruningStatus, target uint64, deletedFiles int) {
+	status := &PruningStatus{At: m.now(), Target: target}
+	if deletedFiles >= 0 {
+		status.DeletedFiles = &deletedFiles
+	}
+
+	m.statusLock.Lock()
+	defer m.statusLock.Unlock()
+
+	*last = status
+}
@@ -0,0 +1,69 @@
+package merger
+
+import (
+	"context"
+
+	pbmerger "github.com/streamingfast/firehose-core/pb/sf/firehose/merger/v1"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// statusServer serves the merger [Status] and [Merger.ForcePrune] over gRPC
+type statusServer struct {
+	pbmerger.UnimplementedMergerStatusServer
+
+	merger *Merger
+}
+
+func (s *statusServer) Status(ctx context.Context, _ *pbmerger.StatusRequest) (*pbmerger.StatusResponse, error) {
+	return s.merger.Status(ctx).toProto(), nil
+}
+
+func (s *statusServer) ForcePrune(_ context.Context, _ *pbmerger.ForcePruneRequest) (*pbmerger.ForcePruneResponse, error) {
+	s.merger.ForcePrune()
+	return &pbmerger.ForcePruneResponse{}, nil
+}
+
+func (s *Status) toProto() *pbmerger.StatusResponse {
+	out := &pbmerger.StatusResponse{
+		BundlerBaseBlockNum:      s.BundlerBaseBlockNum,
+		PendingOneBlockFiles:     uint64(s.PendingOneBlockFiles),
+		HoldsWriterLock:          s.HoldsWriterLock,
+		LastOneBlockFilesPruning: s.LastOneBlockFilesPruning.toProto(),
+		LastForkedBlocksPruning:  s.LastForkedBlocksPruning.toProto(),
+	}
+
+	if merged := s.LastMergedBundle; merged != nil {
+		out.LastMergedBundle = &pbmerger.MergedBundle{
+			BaseBlockNum: merged.BaseBlockNum,
+			LibNum:       merged.LIBNum,
+			LibId:        merged.LIBID,
+		}
+
+		if !merged.MergedAt.IsZero() {
+			out.LastMergedBundle.MergedAt = timestamppb.New(merged.MergedAt)
+			out.TimeSinceLastMerge = durationpb.New(s.timeSinceLastMerge)
+		}
+	}
+
+	if s.ForkedBlocksAwaitingPruning != nil {
+		count := uint64(*s.ForkedBlocksAwaitingPruning)
+		out.ForkedBlocksAwaitingPruning = &count
+	}
+
+	return out
+}
+
+func (p *PruningStatus) toProto() *pbmerger.Pruning {
+	if p == nil {
+		return nil
+	}
+
+	out := &pbmerger.Pruning{At: timestamppb.New(p.At), Target: p.Target}
+	if p.DeletedFiles != nil {
+		deletedFiles := uint64(*p.DeletedFiles)
+		out.DeletedFiles = &deletedFiles
+	}
+
+	return out
+}
@@ -0,0 +1,97 @@
+package merger
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/bstream"
+	pbmerger "github.com/streamingfast/firehose-core/pb/sf/firehose/merger/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerger_Status(t *testing.T) {
+	m := NewMerger(testLogger, "", &TestMergerIO{}, 1, 100, 100, time.Second, time.Second, 0)
+	m.bundler.Reset(200, nil)
+	m.bundler.recordMergedBundle(100, 199, "0000000000000199a", time.Now().Add(-time.Minute))
+	m.setPendingOneBlockFiles(12)
+	m.recordPruning(&m.lastOneBlockFilesPruning, 100, 50)
+
+	recorder := httptest.NewRecorder()
+	m.statusHandler(recorder, httptest.NewRequest("GET", "/v1/status", nil))
+
+	status := &Status{}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), status))
+	assert.Equal(t, uint64(200), status.BundlerBaseBlockNum)
+	require.NotNil(t, status.LastMergedBundle)
+	assert.Equal(t, uint64(100), status.LastMergedBundle.BaseBlockNum)
+	assert.Equal(t, uint64(199), status.LastMergedBundle.LIBNum)
+	assert.NotEmpty(t, status.TimeSinceLastMerge)
+	assert.Equal(t, 12, status.PendingOneBlockFiles)
+	assert.Nil(t, status.ForkedBlocksAwaitingPruning)
+	require.NotNil(t, status.LastOneBlockFilesPruning)
+	assert.Equal(t, 50, *status.LastOneBlockFilesPruning.DeletedFiles)
+	assert.Nil(t, status.LastForkedBlocksPruning)
+
+	grpcStatus, err := (&statusServer{merger: m}).Status(context.Background(), &pbmerger.StatusRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(200), grpcStatus.BundlerBaseBlockNum)
+	assert.Equal(t, "0000000000000199a", grpcStatus.LastMergedBundle.LibId)
+	assert.Greater(t, grpcStatus.TimeSinceLastMerge.AsDuration(), 59*time.Second)
+	assert.Equal(t, uint64(50), grpcStatus.LastOneBlockFilesPruning.GetDeletedFiles())
+	assert.Nil(t, grpcStatus.ForkedBlocksAwaitingPruning)
+	assert.Nil(t, grpcStatus.LastForkedBlocksPruning)
+}
+
+type countingForkAwareIO struct {
+	*TestMergerIO
+	counts int
+}
+
+func (io *countingForkAwareIO) DeleteForkedBlocksAsync(_, _ uint64) {}
+
+func (io *countingForkAwareIO) MoveForkedBlocks(_ context.Context, _ []*bstream.OneBlockFile) {}
+
+func (io *countingForkAwareIO) CountForkedBlocks(_ context.Context) (int, error) {
+	io.counts++
+	return 10 + io.counts, nil
+}
+
+func TestMerger_StatusCachesForkedBlocksCount(t *testing.T) {
+	io := &countingForkAwareIO{TestMergerIO: &TestMergerIO{}}
+	m := NewMerger(testLogger, "", io, 1, 100, 100, time.Second, time.Second, 0)
+
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	assert.Equal(t, 11, *m.Status(context.Background()).ForkedBlocksAwaitingPruning)
+	assert.Equal(t, 11, *m.Status(context.Background()).ForkedBlocksAwaitingPruning)
+	assert.Equal(t, 1, io.counts)
+
+	now = now.Add(forkedBlocksCountTTL)
+	assert.Equal(t, 12, *m.Status(context.Background()).ForkedBlocksAwaitingPruning)
+	assert.Equal(t, 2, io.counts)
+}
+
+func TestMerger_ForcePrune(t *testing.T) {
+	m := NewMerger(testLogger, "", &TestMergerIO{}, 1, 100, 100, time.Second, time.Second, 0)
+
+	m.ForcePrune()
+	m.ForcePrune() // already forced, does not block
+
+	done := make(chan struct{})
+	go func() {
+		m.waitForPruning(time.Hour, m.forcePruneOneBlocks)
+		m.waitForPruning(time.Hour, m.forcePruneForkedBlocks)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("forced pruning did not run")
+	}
+}
@@ -0,0 +1,75 @@
+package merger
+
+import (
+	"context"
+	"errors"
+
+	"github.com/streamingfast/firehose-core/internal/lease"
+	"go.uber.org/zap"
+)
+
+// WriterLockObjectName is the name of the lease object of the merged blocks writer lock
+const WriterLockObjectName = "merger-writer.json"
+
+// ErrWriterLockLost is returned when a bundle is about to be written while the merger does not hold the writer lock
+var ErrWriterLockLost = errors.New("merger writer lock lost")
+
+// EnableWriterLock makes the merger merge bundles, fill holes and prune files only while it holds `lock`, renewed
+// every third of its TTL, preventing concurrent mergers from writing to the same stores. Must be called before the
+// merger is run.
+func (m *Merger) EnableWriterLock(lock *lease.Lock) {
+	m.writerLock = lock
+	m.writerLockElector = lease.NewElector(lock, nil, m.logger.With(zap.String("lease", "merger writer lock")))
+	m.bundler.holdsWriterLock = m.writerLockElector.IsLeader
+}
+
+func (m *Merger) startWriterLock() {
+	if m.writerLockElector == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.OnTerminating(func(_ error) { cancel() })
+
+	go m.writerLockElector.Run(ctx)
+}
+
+// holdsWriterLock returns true if the merger may write to its stores, always when the writer lock is not enabled
+func (m *Merger) holdsWriterLock() bool {
+	return m.writerLockElector == nil || m.writerLockElector.IsLeader()
+}
+
+// logWriterLockHolder logs the current holder of the writer lock, at most once per lock TTL
+func (m *Merger) logWriterLockHolder(ctx context.Context) {
+	if m.now().Sub(m.lastWriterLockLog) < m.writerLock.TTL() {
+		return
+	}
+	m.lastWriterLockLog = m.now()
+
+	current, err := m.writerLock.Current(ctx)
+	if err != nil {
+		m.logger.Warn("not holding the merger writer lock, unable to read its current holder", zap.Error(err))
+		return
+	}
+
+	if current == nil {
+		m.logger.Info("not holding the merger writer lock yet, waiting to acquire it", zap.String("holder", m.writerLock.Holder()))
+		return
+	}
+
+	m.logger.Warn("merger writer lock is held by another merger, not merging until it expires",
+		zap.String("holder", m.writerLock.Holder()),
+		zap.String("current_holder", current.Holder),
+		zap.Time("acquired_at", current.AcquiredAt),
+		zap.Time("expires_at", current.ExpiresAt),
+	)
+}
+
+// checkWriterLock returns ErrWriterLockLost if the merger does not hold the writer lock anymore, checked right before
+// writing each bundle as bundles may be merged asynchronously long after the merger checked the lock
+func (b *Bundler) checkWriterLock() error {
+	if b.holdsWriterLock != nil && !b.holdsWriterLock() {
+		return ErrWriterLockLost
+	}
+	return nil
+}
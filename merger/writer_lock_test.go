@@ -0,0 +1,93 @@
+package merger
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/bstream"
+	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/internal/lease"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerger_WriterLock(t *testing.T) {
+	store, err := dstore.NewSimpleStore(t.TempDir())
+	require.NoError(t, err)
+
+	newLockedMerger := func(holder string) *Merger {
+		m := NewMerger(testLogger, "", &TestMergerIO{}, 1, 100, 100, time.Second, time.Second, 0)
+		m.EnableWriterLock(lease.NewLock(store, WriterLockObjectName, holder, time.Second))
+		return m
+	}
+
+	first := newLockedMerger("first")
+	assert.False(t, first.holdsWriterLock(), "lock not acquired before the merger runs")
+
+	first.startWriterLock()
+	require.Eventually(t, first.holdsWriterLock, 2*time.Second, 10*time.Millisecond)
+
+	second := newLockedMerger("second")
+	second.startWriterLock()
+	time.Sleep(500 * time.Millisecond)
+	assert.False(t, second.holdsWriterLock(), "lock held by the first merger")
+
+	status := second.Status(context.Background())
+	require.NotNil(t, status.HoldsWriterLock)
+	assert.False(t, *status.HoldsWriterLock)
+
+	first.Shutdown(nil)
+	require.Eventually(t, second.holdsWriterLock, 3*time.Second, 10*time.Millisecond)
+	second.Shutdown(nil)
+}
+
+func TestMerger_WriterLockDisabled(t *testing.T) {
+	m := NewMerger(testLogger, "", &TestMergerIO{}, 1, 100, 100, time.Second, time.Second, 0)
+	assert.True(t, m.holdsWriterLock())
+	assert.Nil(t, m.Status(context.Background()).HoldsWriterLock)
+}
+
+func TestBundler_ChecksWriterLockBeforeEachMerge(t *testing.T) {
+	for _, catchUp := range []bool{false, true} {
+		t.Run(map[bool]string{false: "live", true: "catch up"}[catchUp], func(t *testing.T) {
+			var lock sync.Mutex
+			var merged []uint64
+			b := NewBundler(100, 0, 2, 2, &TestMergerIO{
+				DownloadOneBlockFileFunc: blockDataAt(t, time.Now().Add(-time.Hour)),
+				MergeAndStoreFunc: func(_ context.Context, inclusiveLowerBlock uint64, _ []*bstream.OneBlockFile) error {
+					lock.Lock()
+					defer lock.Unlock()
+					merged = append(merged, inclusiveLowerBlock)
+					return nil
+				},
+			})
+			if catchUp {
+				b.catchUp = newCatchUpMerger(2, 15*time.Minute, b)
+			}
+
+			var holdsLock atomic.Bool
+			holdsLock.Store(true)
+			b.holdsWriterLock = holdsLock.Load
+
+			for _, blk := range []*bstream.OneBlockFile{block100(), block101(), block102Final100(), block103Final101(), block104Final102()} {
+				require.NoError(t, b.HandleBlockFile(blk))
+			}
+			b.waitForMerges()
+
+			holdsLock.Store(false)
+			for _, blk := range []*bstream.OneBlockFile{block105Final103(), block106Final104()} {
+				require.NoError(t, b.HandleBlockFile(blk))
+			}
+			b.waitForMerges()
+
+			assert.ErrorIs(t, b.HandleBlockFile(block507Final106()), ErrWriterLockLost)
+			assert.Equal(t, []uint64{100}, merged, "bundle 102 not merged without the writer lock")
+
+			b.resumeAfterLastMerged()
+			assert.EqualValues(t, 102, b.BaseBlockNum())
+		})
+	}
+}
@@ -2,6 +2,7 @@ package metering
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -23,11 +24,30 @@ const (
 	MeterFileCompressedReadBytes         = "file_compressed_read_bytes"
 
 	TotalReadBytes = "total_read_bytes"
+
+	// MeterFileCompressedReadTierBytesPrefix followed by the name of a merged blocks store tier is the meter of the
+	// compressed bytes read from this tier, also counted in MeterFileCompressedReadBytes
+	MeterFileCompressedReadTierBytesPrefix = "file_compressed_read_bytes_tier_"
 )
 
+type mergedBlocksTierKey struct{}
+
+// meteredTiers holds the names of the merged blocks store tiers bytes were read from, sent with each event
+var meteredTiers sync.Map
+
+// WithMergedBlocksTier tags the reads of a merged blocks store opened with the returned context as served by `tier`
+func WithMergedBlocksTier(ctx context.Context, tier string) context.Context {
+	return context.WithValue(ctx, mergedBlocksTierKey{}, tier)
+}
+
 func WithBlockBytesReadMeteringOptions(meter dmetering.Meter, logger *zap.Logger) []dstore.Option {
 	return []dstore.Option{dstore.WithCompressedReadCallback(func(ctx context.Context, n int) {
 		meter.CountInc(MeterFileCompressedReadBytes, n)
+
+		if tier, ok := ctx.Value(mergedBlocksTierKey{}).(string); ok {
+			meteredTiers.Store(tier, true)
+			meter.CountInc(MeterFileCompressedReadTierBytesPrefix+tier, n)
+		}
 	})}
 }
 
@@ -81,6 +101,13 @@ func Send(ctx context.Context, meter dmetering.Meter, userID, apiKeyID, ip, user
 		Timestamp: time.Now(),
 	}
 
+	meteredTiers.Range(func(tier, _ any) bool {
+		if n := meter.GetCountAndReset(MeterFileCompressedReadTierBytesPrefix + tier.(string)); n != 0 {
+			event.Metrics[MeterFileCompressedReadTierBytesPrefix+tier.(string)] = float64(n)
+		}
+		return true
+	})
+
 	emitter := reqctx.Emitter(ctx)
 	if emitter == nil {
 		dmetering.Emit(context.WithoutCancel(ctx), event)
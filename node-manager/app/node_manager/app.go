@@ -16,11 +16,13 @@ package nodemanager
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
 	"time"
 
+	"github.com/gorilla/mux"
 	dgrpcserver "github.com/streamingfast/dgrpc/server"
 	dgrpcfactory "github.com/streamingfast/dgrpc/server/factory"
 	"github.com/streamingfast/dmetrics"
@@ -92,10 +94,14 @@ func (a *App) Run() error {
 
 	var httpOptions []operator.HTTPOption
 	if hasMindreader {
-		if err := a.startMindreader(); err != nil {
+		if _, dryRun := a.modules.MindreaderPlugin.DryRunReport(); dryRun {
+			a.zlogger.Info("mindreader is in dry-run mode, not starting mindreader gRPC server")
+			httpOptions = append(httpOptions, func(r *mux.Router) {
+				r.HandleFunc("/v1/dry_run_report", a.dryRunReportHandler).Methods("GET")
+			})
+		} else if err := a.startMindreader(); err != nil {
 			return fmt.Errorf("unable to start mindreader: %w", err)
 		}
-
 	}
 
 	a.zlogger.Info("launching operator")
@@ -130,6 +136,15 @@ func (a *App) IsReady() bool {
 	return res.StatusCode == 200
 }
 
+func (a *App) dryRunReportHandler(w http.ResponseWriter, _ *http.Request) {
+	report, _ := a.modules.MindreaderPlugin.DryRunReport()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		a.zlogger.Warn("unable to write dry run report", zap.Error(err))
+	}
+}
+
 func (a *App) startMindreader() error {
 	a.zlogger.Info("starting mindreader gRPC server")
 	gs := dgrpcfactory.ServerFromOptions(dgrpcserver.WithLogger(a.zlogger))
@@ -44,6 +44,9 @@ type Config struct {
 	LogToZap                   bool
 	DebugDeepMind              bool
 
+	// DryRun validates the blocks read with [Modules.BlockDecoder] without storing nor serving them
+	DryRun bool
+
 	// MaxLineLengthInBytes configures the maximum bytes a single line consumed can be
 	// without any error. If left unspecified or 0, the default is 50 MiB (50 * 1024 * 1024).
 	MaxLineLengthInBytes int64
@@ -53,6 +56,7 @@ type Modules struct {
 	ConsoleReaderFactory       mindreader.ConsolerReaderFactory
 	MetricsAndReadinessManager *nodeManager.MetricsAndReadinessManager
 	RegisterGRPCService        func(server grpc.ServiceRegistrar) error
+	BlockDecoder               mindreader.BlockDecoder
 }
 
 type App struct {
@@ -105,6 +109,10 @@ func (a *App) Run() error {
 		return err
 	}
 
+	if a.Config.DryRun {
+		mindreaderLogPlugin.EnableDryRun(a.modules.BlockDecoder)
+	}
+
 	a.zlogger.Debug("configuring shutter")
 	mindreaderLogPlugin.OnTerminated(a.Shutdown)
 	a.OnTerminating(mindreaderLogPlugin.Shutdown)
@@ -119,8 +127,12 @@ func (a *App) Run() error {
 			return fmt.Errorf("register extra grpc service: %w", err)
 		}
 	}
-	gs.OnTerminated(a.Shutdown)
-	go gs.Launch(a.Config.GRPCAddr)
+	if a.Config.DryRun {
+		a.zlogger.Info("dry-run mode enabled, not starting gRPC server")
+	} else {
+		gs.OnTerminated(a.Shutdown)
+		go gs.Launch(a.Config.GRPCAddr)
+	}
 
 	a.zlogger.Debug("running reader log plugin")
 	mindreaderLogPlugin.Launch()
@@ -0,0 +1,122 @@
+package logplugin
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/streamingfast/firehose-core/node-manager/metrics"
+	"github.com/streamingfast/shutter"
+	"go.uber.org/zap"
+)
+
+type LogPatternAction string
+
+const (
+	LogPatternActionNone        LogPatternAction = "none"
+	LogPatternActionNotReady    LogPatternAction = "not-ready"
+	LogPatternActionRestart     LogPatternAction = "restart"
+	LogPatternActionMaintenance LogPatternAction = "maintenance"
+)
+
+// LogPatternRule is a named regular expression matched against each node log line along with the action
+// to perform when it matches.
+type LogPatternRule struct {
+	Name    string
+	Action  LogPatternAction
+	Pattern *regexp.Regexp
+}
+
+// ParseLogPatternRule parses a rule of the form `<name>:<action>:<regex>`, the regex being everything after the
+// second colon, it can thus contain colons itself.
+func ParseLogPatternRule(in string) (*LogPatternRule, error) {
+	parts := strings.SplitN(in, ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		return nil, fmt.Errorf("invalid log pattern rule %q, expected format is '<name>:<action>:<regex>'", in)
+	}
+
+	action := LogPatternAction(parts[1])
+	switch action {
+	case LogPatternActionNone, LogPatternActionNotReady, LogPatternActionRestart, LogPatternActionMaintenance:
+	case "":
+		action = LogPatternActionNone
+	default:
+		return nil, fmt.Errorf("invalid log pattern rule %q action %q, valid values are 'none', 'not-ready', 'restart' and 'maintenance'", parts[0], parts[1])
+	}
+
+	pattern, err := regexp.Compile(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid log pattern rule %q regex: %w", parts[0], err)
+	}
+
+	return &LogPatternRule{Name: parts[0], Action: action, Pattern: pattern}, nil
+}
+
+// LogPatternAlertPlugin matches every node log line that is not a FIRE (or DMLOG) line against the
+// configured rules. Each match is counted in the `node_log_pattern_matches` metric and, when the rule
+// has an action, `onAction` is called at most once per `actionCooldown` for a given rule.
+type LogPatternAlertPlugin struct {
+	*shutter.Shutter
+
+	rules          []*LogPatternRule
+	actionCooldown time.Duration
+	onAction       func(rule *LogPatternRule, line string)
+	logger         *zap.Logger
+
+	lastActionLock sync.Mutex
+	lastActionAt   map[string]time.Time
+}
+
+func NewLogPatternAlertPlugin(rules []*LogPatternRule, actionCooldown time.Duration, onAction func(rule *LogPatternRule, line string), logger *zap.Logger) *LogPatternAlertPlugin {
+	return &LogPatternAlertPlugin{
+		Shutter:        shutter.New(),
+		rules:          rules,
+		actionCooldown: actionCooldown,
+		onAction:       onAction,
+		logger:         logger,
+		lastActionAt:   map[string]time.Time{},
+	}
+}
+
+func (p *LogPatternAlertPlugin) Name() string {
+	return "LogPatternAlertPlugin"
+}
+
+func (p *LogPatternAlertPlugin) Launch() {}
+func (p *LogPatternAlertPlugin) Stop()   {}
+
+func (p *LogPatternAlertPlugin) LogLine(in string) {
+	if readerInstrumentationPrefixRegex.MatchString(in) {
+		return
+	}
+
+	for _, rule := range p.rules {
+		if !rule.Pattern.MatchString(in) {
+			continue
+		}
+
+		metrics.NodeLogPatternMatches.Inc(rule.Name, string(rule.Action))
+
+		if rule.Action == LogPatternActionNone || p.onAction == nil || !p.shouldAct(rule) {
+			continue
+		}
+
+		p.logger.Warn("node log line matched alert rule, performing action", zap.String("rule", rule.Name), zap.String("action", string(rule.Action)), zap.String("line", in))
+		p.onAction(rule, in)
+	}
+}
+
+func (p *LogPatternAlertPlugin) shouldAct(rule *LogPatternRule) bool {
+	p.lastActionLock.Lock()
+	defer p.lastActionLock.Unlock()
+
+	now := time.Now()
+	if last, found := p.lastActionAt[rule.Name]; found && now.Sub(last) < p.actionCooldown {
+		return false
+	}
+
+	p.lastActionAt[rule.Name] = now
+	return true
+}
@@ -0,0 +1,70 @@
+package logplugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseLogPatternRule(t *testing.T) {
+	tests := []struct {
+		in             string
+		expectedName   string
+		expectedAction LogPatternAction
+		expectedRegex  string
+		expectedErrMsg string
+	}{
+		{"panic:restart:^panic: ", "panic", LogPatternActionRestart, "^panic: ", ""},
+		{"peers::peer count: 0", "peers", LogPatternActionNone, "peer count: 0", ""},
+		{"corruption:not-ready:(?i)database corruption", "corruption", LogPatternActionNotReady, "(?i)database corruption", ""},
+		{"missing", "", "", "", "expected format is '<name>:<action>:<regex>'"},
+		{"bad:explode:abc", "", "", "", "valid values are"},
+		{"bad:none:(", "", "", "", "regex"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			rule, err := ParseLogPatternRule(tt.in)
+			if tt.expectedErrMsg != "" {
+				assert.ErrorContains(t, err, tt.expectedErrMsg)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedName, rule.Name)
+			assert.Equal(t, tt.expectedAction, rule.Action)
+			assert.Equal(t, tt.expectedRegex, rule.Pattern.String())
+		})
+	}
+}
+
+func TestLogPatternAlertPlugin(t *testing.T) {
+	rules := []*LogPatternRule{
+		mustParseLogPatternRule(t, "panic:restart:^panic: "),
+		mustParseLogPatternRule(t, "peers:none:peer count: 0"),
+	}
+
+	var actions []string
+	plugin := NewLogPatternAlertPlugin(rules, time.Hour, func(rule *LogPatternRule, line string) {
+		actions = append(actions, rule.Name+"="+line)
+	}, zap.NewNop())
+
+	plugin.LogLine("peer count: 0")
+	plugin.LogLine("FIRE panic: not a node line")
+	plugin.LogLine("panic: first")
+	plugin.LogLine("panic: second, within cooldown")
+
+	assert.Equal(t, []string{"panic=panic: first"}, actions)
+}
+
+func mustParseLogPatternRule(t *testing.T, in string) *LogPatternRule {
+	t.Helper()
+
+	rule, err := ParseLogPatternRule(in)
+	require.NoError(t, err)
+
+	return rule
+}
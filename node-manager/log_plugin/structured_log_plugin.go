@@ -0,0 +1,322 @@
+package logplugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/streamingfast/shutter"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// LineParser parses a node log line into its key/value pairs, returning `false` if the line is
+// not in the expected format.
+type LineParser func(in string) (map[string]any, bool)
+
+var (
+	structuredLevelKeys   = []string{"level", "lvl", "severity"}
+	structuredTimeKeys    = []string{"time", "ts", "timestamp", "t"}
+	structuredModuleKeys  = []string{"module", "logger", "component", "target"}
+	structuredMessageKeys = []string{"msg", "message"}
+)
+
+var structuredTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// NewLineParser returns the LineParser for `format` which is one of `json`, `logfmt` or `regex`, `regex`
+// being a regular expression with named groups (required for the `regex` format only).
+func NewLineParser(format string, regex string) (LineParser, error) {
+	switch format {
+	case "json":
+		return JSONLineParser, nil
+	case "logfmt":
+		return LogfmtLineParser, nil
+	case "regex":
+		return NewRegexLineParser(regex)
+	default:
+		return nil, fmt.Errorf("unknown log format %q, valid values are 'json', 'logfmt' and 'regex'", format)
+	}
+}
+
+// JSONLineParser parses lines that are JSON objects.
+func JSONLineParser(in string) (map[string]any, bool) {
+	if !strings.HasPrefix(in, "{") {
+		return nil, false
+	}
+
+	var out map[string]any
+	if err := json.Unmarshal([]byte(in), &out); err != nil {
+		return nil, false
+	}
+
+	return out, true
+}
+
+// LogfmtLineParser parses lines in the `key=value key="quoted value"` format, every element of
+// the line must be a key/value pair for the line to be considered in this format.
+func LogfmtLineParser(in string) (map[string]any, bool) {
+	out := map[string]any{}
+
+	for i := 0; i < len(in); {
+		if in[i] == ' ' {
+			i++
+			continue
+		}
+
+		keyEnd := strings.IndexAny(in[i:], "= ")
+		if keyEnd <= 0 || in[i+keyEnd] != '=' {
+			return nil, false
+		}
+
+		key := in[i : i+keyEnd]
+		i += keyEnd + 1
+
+		if i < len(in) && in[i] == '"' {
+			value, length, ok := unquoteLogfmtValue(in[i:])
+			if !ok {
+				return nil, false
+			}
+
+			out[key] = value
+			i += length
+			continue
+		}
+
+		valueEnd := strings.IndexByte(in[i:], ' ')
+		if valueEnd == -1 {
+			valueEnd = len(in) - i
+		}
+
+		out[key] = in[i : i+valueEnd]
+		i += valueEnd
+	}
+
+	return out, len(out) > 0
+}
+
+// unquoteLogfmtValue reads the quoted value at the start of `in`, returning the unescaped value and
+// the length of the quoted value.
+func unquoteLogfmtValue(in string) (string, int, bool) {
+	var value bytes.Buffer
+	for i := 1; i < len(in); i++ {
+		switch in[i] {
+		case '\\':
+			if i+1 >= len(in) {
+				return "", 0, false
+			}
+
+			i++
+			switch in[i] {
+			case 'n':
+				value.WriteByte('\n')
+			case 't':
+				value.WriteByte('\t')
+			default:
+				value.WriteByte(in[i])
+			}
+		case '"':
+			return value.String(), i + 1, true
+		default:
+			value.WriteByte(in[i])
+		}
+	}
+
+	return "", 0, false
+}
+
+// NewRegexLineParser returns a LineParser extracting the named groups of `expr` as key/value pairs,
+// groups named `level`, `time`, `module` and `msg` are used for the associated log element.
+func NewRegexLineParser(expr string) (LineParser, error) {
+	if expr == "" {
+		return nil, fmt.Errorf("a regular expression is required for the 'regex' log format")
+	}
+
+	regex, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid log regex %q: %w", expr, err)
+	}
+
+	names := regex.SubexpNames()
+	hasNamedGroup := false
+	for _, name := range names {
+		hasNamedGroup = hasNamedGroup || name != ""
+	}
+
+	if !hasNamedGroup {
+		return nil, fmt.Errorf("log regex %q must have at least one named group like '(?P<msg>.*)'", expr)
+	}
+
+	return func(in string) (map[string]any, bool) {
+		match := regex.FindStringSubmatch(in)
+		if match == nil {
+			return nil, false
+		}
+
+		out := map[string]any{}
+		for i, name := range names {
+			if name != "" && match[i] != "" {
+				out[name] = match[i]
+			}
+		}
+
+		return out, true
+	}, nil
+}
+
+// StructuredLogPlugin takes a line, and if it's not a FIRE (or DMLOG) line or if we are actively
+// debugging deep mind, parses it with the configured LineParser and logs it to the received logger
+// with the parsed level, message, module, timestamp (as `node_time`) and extra fields.
+//
+// Lines that cannot be parsed are logged as-is at info level, as are parsed lines without a level.
+type StructuredLogPlugin struct {
+	*shutter.Shutter
+
+	logger        *zap.Logger
+	parser        LineParser
+	debugDeepMind bool
+}
+
+func NewStructuredLogPlugin(parser LineParser, debugDeepMind bool, logger *zap.Logger) *StructuredLogPlugin {
+	return &StructuredLogPlugin{
+		Shutter:       shutter.New(),
+		logger:        logger,
+		parser:        parser,
+		debugDeepMind: debugDeepMind,
+	}
+}
+
+func (p *StructuredLogPlugin) Launch() {}
+func (p *StructuredLogPlugin) Stop()   {}
+
+func (p *StructuredLogPlugin) Name() string {
+	return "StructuredLogPlugin"
+}
+
+func (p *StructuredLogPlugin) DebugDeepMind(enabled bool) {
+	p.debugDeepMind = enabled
+}
+
+func (p *StructuredLogPlugin) LogLine(in string) {
+	if readerInstrumentationPrefixRegex.MatchString(in) {
+		if p.debugDeepMind {
+			p.logger.Info(in)
+		}
+
+		return
+	}
+
+	values, ok := p.parser(in)
+	if !ok {
+		p.logger.Info(in)
+		return
+	}
+
+	level := zap.InfoLevel
+	if raw, found := takeValue(values, structuredLevelKeys); found {
+		level = parseNodeLogLevel(fmt.Sprint(raw))
+	}
+
+	message := in
+	if raw, found := takeValue(values, structuredMessageKeys); found {
+		message = fmt.Sprint(raw)
+	}
+
+	fields := make([]zap.Field, 0, len(values)+2)
+	if raw, found := takeValue(values, structuredModuleKeys); found {
+		fields = append(fields, zap.String("module", fmt.Sprint(raw)))
+	}
+
+	if raw, found := takeValue(values, structuredTimeKeys); found {
+		if timestamp, ok := parseNodeLogTime(raw); ok {
+			fields = append(fields, zap.Time("node_time", timestamp))
+		} else {
+			fields = append(fields, zap.String("node_time", fmt.Sprint(raw)))
+		}
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fields = append(fields, zap.Any(key, values[key]))
+	}
+
+	if ce := p.logger.Check(level, message); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// takeValue returns and removes from `values` the value of the first key of `keys` found in it
+func takeValue(values map[string]any, keys []string) (any, bool) {
+	for _, key := range keys {
+		if value, found := values[key]; found {
+			delete(values, key)
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+// parseNodeLogLevel maps the usual level names found in node logs to a zap level, fatal levels are
+// mapped to error as logging at fatal level would exit the process.
+func parseNodeLogLevel(in string) zapcore.Level {
+	switch strings.ToLower(in) {
+	case "trace", "trce", "debug", "dbug", "dbg":
+		return zap.DebugLevel
+	case "warn", "warning", "wrn":
+		return zap.WarnLevel
+	case "error", "eror", "err", "crit", "critical", "fatal", "panic":
+		return zap.ErrorLevel
+	default:
+		return zap.InfoLevel
+	}
+}
+
+func parseNodeLogTime(in any) (time.Time, bool) {
+	switch value := in.(type) {
+	case float64:
+		return numericNodeLogTime(value), true
+	case string:
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return numericNodeLogTime(number), true
+		}
+	}
+
+	raw := fmt.Sprint(in)
+	for _, layout := range structuredTimeLayouts {
+		if timestamp, err := time.Parse(layout, raw); err == nil {
+			return timestamp, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// numericNodeLogTime converts a Unix timestamp whose unit is inferred from its number of integer digits: seconds
+// (up to 11), milliseconds (up to 14), microseconds (up to 17) or nanoseconds
+func numericNodeLogTime(value float64) time.Time {
+	switch {
+	case value < 1e11:
+		return time.Unix(0, int64(value*float64(time.Second))).UTC()
+	case value < 1e14:
+		return time.UnixMilli(int64(value)).UTC()
+	case value < 1e17:
+		return time.UnixMicro(int64(value)).UTC()
+	default:
+		return time.Unix(0, int64(value)).UTC()
+	}
+}
@@ -0,0 +1,124 @@
+package logplugin
+
+import (
+	"testing"
+
+	"github.com/streamingfast/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructuredLogPlugin(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		regex  string
+		in     []string
+		out    []string
+	}{
+		{
+			"json, all elements",
+			"json",
+			"",
+			[]string{`{"level":"warn","ts":"2024-01-02T03:04:05Z","module":"p2p","msg":"peer dropped","peer":"abc","count":2}`},
+			[]string{`{"level":"warn","msg":"peer dropped","module":"p2p","node_time":"2024-01-02T03:04:05.000Z","count":2,"peer":"abc"}`},
+		},
+		{
+			"json, numeric timestamp in seconds",
+			"json",
+			"",
+			[]string{`{"lvl":"EROR","time":1704164645,"message":"failure"}`},
+			[]string{`{"level":"error","msg":"failure","node_time":"2024-01-02T03:04:05.000Z"}`},
+		},
+		{
+			"json, numeric timestamp in milliseconds",
+			"json",
+			"",
+			[]string{`{"lvl":"EROR","time":1704164645123,"message":"failure"}`},
+			[]string{`{"level":"error","msg":"failure","node_time":"2024-01-02T03:04:05.123Z"}`},
+		},
+		{
+			"json, numeric timestamp in microseconds",
+			"json",
+			"",
+			[]string{`{"lvl":"EROR","time":1704164645123456,"message":"failure"}`},
+			[]string{`{"level":"error","msg":"failure","node_time":"2024-01-02T03:04:05.123Z"}`},
+		},
+		{
+			"json, numeric timestamp in nanoseconds",
+			"json",
+			"",
+			[]string{`{"lvl":"EROR","time":1704164645123456789,"message":"failure"}`},
+			[]string{`{"level":"error","msg":"failure","node_time":"2024-01-02T03:04:05.123Z"}`},
+		},
+		{
+			"logfmt, numeric timestamp in microseconds",
+			"logfmt",
+			"",
+			[]string{`ts=1704164645123456 msg=failure`},
+			[]string{`{"level":"info","msg":"failure","node_time":"2024-01-02T03:04:05.123Z"}`},
+		},
+		{
+			"json, not json",
+			"json",
+			"",
+			[]string{`Starting node`},
+			[]string{`{"level":"info","msg":"Starting node"}`},
+		},
+		{
+			"logfmt, quoted values",
+			"logfmt",
+			"",
+			[]string{`t=2024-01-02T03:04:05+0000 lvl=dbug msg="Imported \"new\" block" number=10`},
+			[]string{`{"level":"debug","msg":"Imported \"new\" block","node_time":"2024-01-02T03:04:05+0000","number":"10"}`},
+		},
+		{
+			"logfmt, not logfmt",
+			"logfmt",
+			"",
+			[]string{`Starting node version=1.0`},
+			[]string{`{"level":"info","msg":"Starting node version=1.0"}`},
+		},
+		{
+			"regex, named groups",
+			"regex",
+			`^(?P<level>[A-Z]+) \[(?P<module>[^\]]+)\] (?P<msg>.*)$`,
+			[]string{`WARN [sync] fell behind`, `unmatched line`},
+			[]string{`{"level":"warn","msg":"fell behind","module":"sync"}`, `{"level":"info","msg":"unmatched line"}`},
+		},
+		{
+			"instrumentation lines are skipped",
+			"json",
+			"",
+			[]string{`FIRE BLOCK 1 {"level":"warn"}`},
+			[]string(nil),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			testLogger := logging.NewTestLogger(t)
+
+			parser, err := NewLineParser(test.format, test.regex)
+			require.NoError(t, err)
+
+			plugin := NewStructuredLogPlugin(parser, false, testLogger.Instance())
+			for _, in := range test.in {
+				plugin.LogLine(in)
+			}
+
+			assert.Equal(t, test.out, testLogger.RecordedLines(t))
+		})
+	}
+}
+
+func TestNewLineParser_Invalid(t *testing.T) {
+	_, err := NewLineParser("xml", "")
+	assert.ErrorContains(t, err, "unknown log format")
+
+	_, err = NewLineParser("regex", "")
+	assert.ErrorContains(t, err, "regular expression is required")
+
+	_, err = NewLineParser("regex", `^\w+$`)
+	assert.ErrorContains(t, err, "must have at least one named group")
+}
@@ -0,0 +1,334 @@
+package logplugin
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/streamingfast/shutter"
+	"go.uber.org/zap"
+)
+
+const (
+	toFileCurrentName     = "node.log"
+	toFileRotatedPrefix   = "node-"
+	toFileRotatedTimeFmt  = "20060102T150405.000000000"
+	toFileCompressedExt   = ".gz"
+	toFileRotatedExt      = ".log"
+	toFileDefaultMaxSize  = 100 * 1024 * 1024
+	toFileDefaultMaxFiles = 10
+)
+
+type ToFileLogPluginOption interface {
+	apply(p *ToFileLogPlugin)
+}
+
+type toFileLogPluginOptionFunc func(p *ToFileLogPlugin)
+
+func (s toFileLogPluginOptionFunc) apply(p *ToFileLogPlugin) {
+	s(p)
+}
+
+// ToFileLogPluginMaxSize is the option that defines the size in bytes at which the current log file
+// is rotated, 0 disables size based rotation.
+func ToFileLogPluginMaxSize(maxSize uint64) ToFileLogPluginOption {
+	return toFileLogPluginOptionFunc(func(p *ToFileLogPlugin) {
+		p.maxSize = maxSize
+	})
+}
+
+// ToFileLogPluginRotationInterval is the option that defines the time after which the current log file
+// is rotated, 0 disables time based rotation.
+func ToFileLogPluginRotationInterval(interval time.Duration) ToFileLogPluginOption {
+	return toFileLogPluginOptionFunc(func(p *ToFileLogPlugin) {
+		p.rotationInterval = interval
+	})
+}
+
+// ToFileLogPluginCompress is the option that defines if rotated log files are compressed with gzip.
+func ToFileLogPluginCompress(compress bool) ToFileLogPluginOption {
+	return toFileLogPluginOptionFunc(func(p *ToFileLogPlugin) {
+		p.compress = compress
+	})
+}
+
+// ToFileLogPluginRetention is the option that defines how many rotated log files are kept and for how long,
+// a value of 0 disables the associated limit.
+func ToFileLogPluginRetention(maxFiles int, maxAge time.Duration) ToFileLogPluginOption {
+	return toFileLogPluginOptionFunc(func(p *ToFileLogPlugin) {
+		p.maxFiles = maxFiles
+		p.maxAge = maxAge
+	})
+}
+
+// ToFileLogPlugin takes a line, and if it's not a FIRE (or DMLOG) line or if we are actively
+// debugging deep mind, writes it as-is to the `node.log` file in the configured directory.
+//
+// The file is rotated when it reaches the configured size or age, rotated files are named
+// `node-<timestamp>.log` (optionally gzip compressed) and pruned according to the configured
+// retention.
+type ToFileLogPlugin struct {
+	*shutter.Shutter
+
+	dir    string
+	logger *zap.Logger
+
+	maxSize          uint64
+	rotationInterval time.Duration
+	compress         bool
+	maxFiles         int
+	maxAge           time.Duration
+
+	lock          sync.Mutex
+	file          *os.File
+	size          uint64
+	openedAt      time.Time
+	failing       bool
+	debugDeepMind bool
+
+	// background tracks compression and pruning of rotated files
+	background sync.WaitGroup
+}
+
+func NewToFileLogPlugin(dir string, debugDeepMind bool, logger *zap.Logger, options ...ToFileLogPluginOption) (*ToFileLogPlugin, error) {
+	plugin := &ToFileLogPlugin{
+		Shutter:       shutter.New(),
+		dir:           dir,
+		logger:        logger,
+		maxSize:       toFileDefaultMaxSize,
+		maxFiles:      toFileDefaultMaxFiles,
+		debugDeepMind: debugDeepMind,
+	}
+
+	for _, opt := range options {
+		opt.apply(plugin)
+	}
+
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("create log directory %q: %w", dir, err)
+	}
+
+	if err := plugin.open(); err != nil {
+		return nil, err
+	}
+
+	return plugin, nil
+}
+
+func (p *ToFileLogPlugin) Name() string {
+	return "ToFileLogPlugin"
+}
+
+// Launch is a no-op, the file is opened on creation as Launch is called each time the process starts
+func (p *ToFileLogPlugin) Launch() {}
+
+func (p *ToFileLogPlugin) Stop() {
+	p.lock.Lock()
+	if p.file != nil {
+		if err := p.file.Close(); err != nil {
+			p.logger.Warn("unable to close node log file", zap.Error(err))
+		}
+		p.file = nil
+	}
+	p.lock.Unlock()
+
+	p.background.Wait()
+}
+
+func (p *ToFileLogPlugin) DebugDeepMind(enabled bool) {
+	p.debugDeepMind = enabled
+}
+
+func (p *ToFileLogPlugin) LogLine(in string) {
+	if !p.debugDeepMind && readerInstrumentationPrefixRegex.MatchString(in) {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.file == nil {
+		return
+	}
+
+	if p.shouldRotate(uint64(len(in)) + 1) {
+		if err := p.rotate(); err != nil {
+			p.reportError("unable to rotate node log file", err)
+			if p.file == nil {
+				return
+			}
+		}
+	}
+
+	n, err := io.WriteString(p.file, in+"\n")
+	p.size += uint64(n)
+	if err != nil {
+		p.reportError("unable to write to node log file", err)
+		return
+	}
+
+	p.failing = false
+}
+
+func (p *ToFileLogPlugin) shouldRotate(incoming uint64) bool {
+	if p.size == 0 {
+		return false
+	}
+
+	if p.maxSize > 0 && p.size+incoming > p.maxSize {
+		return true
+	}
+
+	return p.rotationInterval > 0 && time.Since(p.openedAt) >= p.rotationInterval
+}
+
+// reportError logs the error only on the first failure so that a full disk doesn't flood the logs
+func (p *ToFileLogPlugin) reportError(message string, err error) {
+	if !p.failing {
+		p.logger.Warn(message+", subsequent errors are not reported until it succeeds again", zap.String("dir", p.dir), zap.Error(err))
+	}
+	p.failing = true
+}
+
+func (p *ToFileLogPlugin) currentPath() string {
+	return filepath.Join(p.dir, toFileCurrentName)
+}
+
+func (p *ToFileLogPlugin) open() error {
+	file, err := os.OpenFile(p.currentPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open node log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat node log file: %w", err)
+	}
+
+	p.file = file
+	p.size = uint64(info.Size())
+	p.openedAt = time.Now()
+
+	return nil
+}
+
+// rotate must be called with the lock held
+func (p *ToFileLogPlugin) rotate() error {
+	if err := p.file.Close(); err != nil {
+		p.logger.Warn("unable to close node log file before rotation", zap.Error(err))
+	}
+	p.file = nil
+
+	rotatedPath := filepath.Join(p.dir, toFileRotatedPrefix+time.Now().UTC().Format(toFileRotatedTimeFmt)+toFileRotatedExt)
+	renameErr := os.Rename(p.currentPath(), rotatedPath)
+
+	// We re-open even if rename failed so that we continue logging in the current file
+	if err := p.open(); err != nil {
+		return err
+	}
+
+	if renameErr != nil {
+		return fmt.Errorf("rename node log file: %w", renameErr)
+	}
+
+	p.background.Add(1)
+	go func() {
+		defer p.background.Done()
+
+		if p.compress {
+			if err := compressFile(rotatedPath); err != nil {
+				p.logger.Warn("unable to compress rotated node log file", zap.String("path", rotatedPath), zap.Error(err))
+			}
+		}
+
+		p.prune()
+	}()
+
+	return nil
+}
+
+func compressFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	tempPath := path + toFileCompressedExt + ".tmp"
+	destination, err := os.Create(tempPath)
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(destination)
+	if _, err := io.Copy(writer, source); err != nil {
+		destination.Close()
+		os.Remove(tempPath)
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		destination.Close()
+		os.Remove(tempPath)
+		return err
+	}
+
+	if err := destination.Close(); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	if err := os.Rename(tempPath, path+toFileCompressedExt); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// prune deletes the rotated files above the configured retention, the current file is never deleted
+func (p *ToFileLogPlugin) prune() {
+	if p.maxFiles <= 0 && p.maxAge <= 0 {
+		return
+	}
+
+	entries, err := os.ReadDir(p.dir)
+	if err != nil {
+		p.logger.Warn("unable to list node log files for pruning", zap.Error(err))
+		return
+	}
+
+	var rotated []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, toFileRotatedPrefix) && (strings.HasSuffix(name, toFileRotatedExt) || strings.HasSuffix(name, toFileRotatedExt+toFileCompressedExt)) {
+			rotated = append(rotated, name)
+		}
+	}
+
+	// Names embed the rotation time, newest files are first once sorted in reverse order
+	sort.Sort(sort.Reverse(sort.StringSlice(rotated)))
+
+	for i, name := range rotated {
+		path := filepath.Join(p.dir, name)
+
+		expired := false
+		if p.maxAge > 0 {
+			if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > p.maxAge {
+				expired = true
+			}
+		}
+
+		if (p.maxFiles > 0 && i >= p.maxFiles) || expired {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				p.logger.Warn("unable to delete old node log file", zap.String("path", path), zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package logplugin
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestToFileLogPlugin(t *testing.T) {
+	dir := t.TempDir()
+
+	plugin, err := NewToFileLogPlugin(dir, false, zap.NewNop(), ToFileLogPluginMaxSize(20), ToFileLogPluginCompress(true))
+	require.NoError(t, err)
+
+	plugin.LogLine("line 1")
+	plugin.LogLine("FIRE BLOCK 1")
+	plugin.LogLine("line 2")
+	plugin.LogLine("line 3")
+	plugin.LogLine("line 4")
+	plugin.Stop()
+
+	current, err := os.ReadFile(filepath.Join(dir, "node.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "line 3\nline 4\n", string(current))
+
+	rotated := rotatedLogFiles(t, dir)
+	require.Len(t, rotated, 1)
+	assert.True(t, strings.HasSuffix(rotated[0], ".log.gz"), "rotated file %q should be compressed", rotated[0])
+	assert.Equal(t, "line 1\nline 2\n", readGzipFile(t, filepath.Join(dir, rotated[0])))
+}
+
+func TestToFileLogPlugin_Retention(t *testing.T) {
+	dir := t.TempDir()
+
+	plugin, err := NewToFileLogPlugin(dir, false, zap.NewNop(), ToFileLogPluginMaxSize(1), ToFileLogPluginRetention(2, 0))
+	require.NoError(t, err)
+
+	for _, line := range []string{"1", "2", "3", "4", "5"} {
+		plugin.LogLine(line)
+	}
+	plugin.Stop()
+
+	rotated := rotatedLogFiles(t, dir)
+	require.Len(t, rotated, 2)
+
+	var contents []string
+	for _, name := range rotated {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		contents = append(contents, string(content))
+	}
+
+	assert.Equal(t, []string{"3\n", "4\n"}, contents)
+}
+
+func TestToFileLogPlugin_AppendsToExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "node.log"), []byte("previous\n"), 0644))
+
+	plugin, err := NewToFileLogPlugin(dir, false, zap.NewNop())
+	require.NoError(t, err)
+
+	plugin.LogLine("next")
+	plugin.Stop()
+
+	current, err := os.ReadFile(filepath.Join(dir, "node.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "previous\nnext\n", string(current))
+}
+
+func rotatedLogFiles(t *testing.T, dir string) (out []string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	for _, entry := range entries {
+		if entry.Name() != "node.log" {
+			out = append(out, entry.Name())
+		}
+	}
+
+	sort.Strings(out)
+	return
+}
+
+func readGzipFile(t *testing.T, path string) string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	require.NoError(t, err)
+
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+
+	return string(content)
+}
@@ -20,6 +20,19 @@ import (
 
 var Metricset = dmetrics.NewSet()
 
+var MindreaderSpillQueueDepth = Metricset.NewGauge("mindreader_spill_queue_depth", "Number of blocks currently spilled to disk waiting to be archived")
+var MindreaderSpillQueueBytes = Metricset.NewGauge("mindreader_spill_queue_bytes", "Size in bytes of the blocks currently spilled to disk")
+var MindreaderSpilledBytes = Metricset.NewCounter("mindreader_spilled_bytes", "Total size in bytes of blocks spilled to disk")
+
+var OneBlockUploadDuration = Metricset.NewHistogram("one_block_upload_duration", "Time taken to upload a one-block file to the one-block store, including retries")
+var OneBlockUploadFailures = Metricset.NewCounter("one_block_upload_failures", "Number of failed one-block file upload attempts")
+
+var ReaderNodeLeader = Metricset.NewGauge("reader_node_leader", "Set to 1 when leader election is enabled and this reader node is the leader uploading one-block files, 0 otherwise")
+
+var NodeLogPatternMatches = Metricset.NewCounterVec("node_log_pattern_matches", []string{"rule", "action"}, "Number of node log lines matching a log pattern alert rule")
+
+var NodeStallEvents = Metricset.NewCounterVec("node_stall_events", []string{"kind", "action"}, "Number of times the managed node process was detected as stalled")
+
 func NewHeadBlockTimeDrift(serviceName string) *dmetrics.HeadTimeDrift {
 	return Metricset.NewHeadTimeDrift(serviceName)
 }
@@ -15,9 +15,11 @@
 package mindreader
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"time"
 
 	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
 
@@ -36,9 +38,11 @@ type Archiver struct {
 
 	localOneBlocksStore dstore.Store
 
-	fileUploader *FileUploader
-	logger       *zap.Logger
-	tracer       logging.Tracer
+	fileUploader   *FileUploader
+	direct         bool
+	catchUpBundler *catchUpBundler
+	logger         *zap.Logger
+	tracer         logging.Tracer
 }
 
 func NewArchiver(
@@ -68,14 +72,44 @@ func NewArchiver(
 	return a
 }
 
+// SetUploadOptions configures the upload of one-block files, must be called before the archiver is started
+func (a *Archiver) SetUploadOptions(options UploadOptions) {
+	a.direct = options.Direct
+	a.fileUploader.SetOptions(options)
+}
+
+// EnableCatchUpBundles makes the archiver write blocks below LIB as `bundleSize` merged blocks bundles straight to
+// `mergedBlocksStore` (with their sidecar in `sidecarStore` if not nil) until blocks are younger than `liveThreshold`,
+// must be called before the archiver is started
+func (a *Archiver) EnableCatchUpBundles(mergedBlocksStore, sidecarStore dstore.Store, bundleSize uint64, liveThreshold time.Duration) {
+	a.catchUpBundler = newCatchUpBundler(mergedBlocksStore, sidecarStore, bundleSize, liveThreshold, a.logger)
+}
+
 func (a *Archiver) Start(ctx context.Context) {
 	a.OnTerminating(func(err error) {
 		a.logger.Info("archiver selector is terminating", zap.Error(err))
+
+		if a.catchUpBundler != nil {
+			for _, block := range a.catchUpBundler.close() {
+				if err := a.storeOneBlock(context.Background(), block); err != nil {
+					a.logger.Error("unable to store buffered catch up block, you will need to reprocess over this block", zap.Uint64("block_num", block.Number), zap.Error(err))
+				}
+			}
+		}
+
+		if a.direct {
+			a.logger.Info("waiting for queued direct uploads to complete")
+			a.fileUploader.CloseDirect()
+		}
 	})
 
 	a.OnTerminated(func(err error) {
 		a.logger.Info("archiver selector is terminated", zap.Error(err))
 	})
+
+	if a.catchUpBundler != nil {
+		a.catchUpBundler.isLeader = a.fileUploader.isLeader
+	}
 	go a.fileUploader.Start(ctx)
 }
 
@@ -85,6 +119,29 @@ func (a *Archiver) StoreBlock(ctx context.Context, block *pbbstream.Block) error
 		return nil
 	}
 
+	if a.catchUpBundler == nil {
+		return a.storeOneBlock(ctx, block)
+	}
+
+	oneBlocks, err := a.catchUpBundler.process(ctx, block)
+	if err != nil {
+		return err
+	}
+
+	for _, oneBlock := range oneBlocks {
+		if err := a.storeOneBlock(ctx, oneBlock); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *Archiver) storeOneBlock(ctx context.Context, block *pbbstream.Block) error {
+	if a.direct {
+		return a.storeBlockDirect(ctx, block)
+	}
+
 	pipeRead, pipeWrite := io.Pipe()
 
 	// We are in a pipe context and `a.blockWriterFactory.New(pipeWrite)` writes some bytes to the writer when called.
@@ -113,3 +170,17 @@ func (a *Archiver) StoreBlock(ctx context.Context, block *pbbstream.Block) error
 
 	return nil
 }
+
+func (a *Archiver) storeBlockDirect(ctx context.Context, block *pbbstream.Block) error {
+	buffer := bytes.NewBuffer(nil)
+	blockWriter, err := bstream.NewDBinBlockWriter(buffer)
+	if err != nil {
+		return fmt.Errorf("write block factory: %w", err)
+	}
+
+	if err := blockWriter.Write(block); err != nil {
+		return fmt.Errorf("write block: %w", err)
+	}
+
+	return a.fileUploader.Enqueue(ctx, bstream.BlockFileNameWithSuffix(block, a.oneblockSuffix), buffer.Bytes())
+}
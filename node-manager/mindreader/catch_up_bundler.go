@@ -0,0 +1,232 @@
+package mindreader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/streamingfast/bstream"
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/sidecar"
+	"github.com/streamingfast/firehose-core/types"
+	"go.uber.org/zap"
+)
+
+// maxCatchUpBufferedBundles bounds the blocks kept in memory while waiting for a bundle to become final, in
+// bundles, the bundler switches to live mode when it's reached (for example when the node does not report LIB)
+const maxCatchUpBufferedBundles = 10
+
+// catchUpBundler accumulates blocks into `bundleSize` bundles and writes them straight to the merged blocks
+// store once all of their blocks are below LIB, skipping one-block files and the merger while the reader
+// is catching up.
+//
+// Blocks are handed back to be written as one-block files until the first bundle boundary is reached (the
+// merger needs the full bundle), and for good once the live segment is reached: a block is younger than
+// `liveThreshold`, too many blocks are waiting for finality or the bundle's blocks could not be linked. With
+// leader election, bundles are only written while holding the lease, the blocks being handed to the one-block
+// files uploader (which applies the same lease check) for good otherwise.
+//
+// Bundles are written like the merger writes them: along with their sidecar when `sidecarStore` is set, the merged
+// blocks store head pointer being moved to each written bundle.
+type catchUpBundler struct {
+	store         dstore.Store
+	sidecarStore  dstore.Store
+	bundleSize    uint64
+	liveThreshold time.Duration
+	logger        *zap.Logger
+
+	// headStore is created on the first head write, see types.NewMergedBlocksStoreHeadStore
+	headStore dstore.Store
+
+	// isLeader is nil unless leader election is enabled
+	isLeader func() bool
+
+	started      bool
+	live         bool
+	baseBlockNum uint64
+	blocks       []*pbbstream.Block
+}
+
+func newCatchUpBundler(store, sidecarStore dstore.Store, bundleSize uint64, liveThreshold time.Duration, logger *zap.Logger) *catchUpBundler {
+	return &catchUpBundler{
+		store:         store,
+		sidecarStore:  sidecarStore,
+		bundleSize:    bundleSize,
+		liveThreshold: liveThreshold,
+		logger:        logger,
+	}
+}
+
+// process returns the blocks that must be written as one-block files, which is `block` itself in live mode
+func (b *catchUpBundler) process(ctx context.Context, block *pbbstream.Block) ([]*pbbstream.Block, error) {
+	if b.live {
+		return []*pbbstream.Block{block}, nil
+	}
+
+	if !b.started {
+		if block.Number%b.bundleSize != 0 && block.Number != bstream.GetProtocolFirstStreamableBlock {
+			return []*pbbstream.Block{block}, nil
+		}
+
+		b.started = true
+		b.baseBlockNum = block.Number - block.Number%b.bundleSize
+		b.logger.Info("writing merged blocks bundles directly while catching up", zap.Uint64("base_block_num", b.baseBlockNum))
+	}
+
+	if age := time.Since(block.Time()); age < b.liveThreshold {
+		return b.switchToLive(block, "reached live segment", zap.Duration("block_age", age))
+	}
+
+	if uint64(len(b.blocks)) >= maxCatchUpBufferedBundles*b.bundleSize {
+		return b.switchToLive(block, "too many blocks waiting for finality", zap.Uint64("lib_num", block.LibNum))
+	}
+
+	b.blocks = append(b.blocks, block)
+
+	for b.bundleComplete(block) {
+		canonical, linked := b.canonicalBlocks()
+		if !linked {
+			return b.switchToLive(nil, "unable to link buffered blocks together")
+		}
+
+		if b.isLeader != nil && !b.isLeader() {
+			return b.switchToLive(nil, "not holding the reader leader lease")
+		}
+
+		if err := b.writeBundle(ctx, canonical); err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// close returns the buffered blocks that were not bundled, they must be written as one-block files
+func (b *catchUpBundler) close() []*pbbstream.Block {
+	b.live = true
+
+	out := b.blocks
+	b.blocks = nil
+
+	return out
+}
+
+func (b *catchUpBundler) switchToLive(block *pbbstream.Block, reason string, fields ...zap.Field) ([]*pbbstream.Block, error) {
+	b.logger.Info("switching to one-block files output, the merger takes over from here", append(fields,
+		zap.String("reason", reason),
+		zap.Uint64("base_block_num", b.baseBlockNum),
+		zap.Int("buffered_blocks", len(b.blocks)),
+	)...)
+
+	out := b.close()
+	if block != nil {
+		out = append(out, block)
+	}
+
+	return out, nil
+}
+
+// bundleComplete returns true when a block past the current bundle was seen and the bundle's last block is final
+func (b *catchUpBundler) bundleComplete(latest *pbbstream.Block) bool {
+	lastBundleBlock := b.baseBlockNum + b.bundleSize - 1
+	if latest.LibNum < lastBundleBlock {
+		return false
+	}
+
+	for _, blk := range b.blocks {
+		if blk.Number > lastBundleBlock {
+			return true
+		}
+	}
+
+	return false
+}
+
+// canonicalBlocks walks the parent links from the latest buffered block and returns the blocks of the
+// current bundle that are part of this chain, forked blocks being dropped. It returns false when the
+// chain does not reach the lowest buffered block.
+func (b *catchUpBundler) canonicalBlocks() ([]*pbbstream.Block, bool) {
+	byID := make(map[string]*pbbstream.Block, len(b.blocks))
+	lowest := b.blocks[0].Number
+	for _, blk := range b.blocks {
+		byID[blk.Id] = blk
+		lowest = min(lowest, blk.Number)
+	}
+
+	canonical := map[string]bool{}
+	current := b.blocks[len(b.blocks)-1]
+	for {
+		canonical[current.Id] = true
+
+		parent, found := byID[current.ParentId]
+		if !found {
+			break
+		}
+		current = parent
+	}
+
+	if current.Number != lowest {
+		return nil, false
+	}
+
+	var out []*pbbstream.Block
+	for _, blk := range b.blocks {
+		if canonical[blk.Id] && blk.Number < b.baseBlockNum+b.bundleSize {
+			out = append(out, blk)
+		}
+	}
+
+	return out, true
+}
+
+func (b *catchUpBundler) writeBundle(ctx context.Context, blocks []*pbbstream.Block) error {
+	filename := sidecar.Filename(b.baseBlockNum)
+	err := sidecar.WriteBlocks(ctx, b.store, b.sidecarStore, b.baseBlockNum, blocks)
+	if errors.Is(err, sidecar.ErrBuildFailed) {
+		b.logger.Warn("unable to build sidecar of merged blocks bundle, previous one deleted", zap.String("filename", filename), zap.Error(err))
+	} else if err != nil {
+		return fmt.Errorf("write merged blocks bundle %q: %w", filename, err)
+	}
+
+	b.logger.Debug("wrote merged blocks bundle", zap.String("filename", filename), zap.Int("block_count", len(blocks)))
+
+	last := blocks[len(blocks)-1]
+	b.writeHead(ctx, &types.MergedBlocksStoreHead{
+		LastBundleBaseBlockNum: b.baseBlockNum,
+		LIBNum:                 last.Number,
+		LIBID:                  last.Id,
+		UpdatedAt:              time.Now(),
+	})
+
+	nextBaseBlockNum := b.baseBlockNum + b.bundleSize
+	remaining := b.blocks[:0]
+	for _, blk := range b.blocks {
+		if blk.Number >= nextBaseBlockNum {
+			remaining = append(remaining, blk)
+		}
+	}
+
+	b.blocks = remaining
+	b.baseBlockNum = nextBaseBlockNum
+
+	return nil
+}
+
+// writeHead points the head of the merged blocks store to the written bundle, readers falling back to probing the
+// store when it cannot be written
+func (b *catchUpBundler) writeHead(ctx context.Context, head *types.MergedBlocksStoreHead) {
+	if b.headStore == nil {
+		store, err := types.NewMergedBlocksStoreHeadStore(ctx, b.store)
+		if err != nil {
+			b.logger.Warn("unable to write merged blocks store head", zap.Uint64("base_block_num", head.LastBundleBaseBlockNum), zap.Error(err))
+			return
+		}
+		b.headStore = store
+	}
+
+	if err := types.WriteMergedBlocksStoreHead(ctx, b.headStore, head); err != nil {
+		b.logger.Warn("unable to write merged blocks store head", zap.Uint64("base_block_num", head.LastBundleBaseBlockNum), zap.Error(err))
+	}
+}
@@ -0,0 +1,167 @@
+package mindreader
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/bstream"
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/sidecar"
+	"github.com/streamingfast/firehose-core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestCatchUpBundler_WritesFinalBundles(t *testing.T) {
+	store := dstore.NewMockStore(nil)
+	bundler := newCatchUpBundler(store, nil, 100, time.Minute, testLogger)
+	blockTime := time.Now().Add(-time.Hour)
+
+	var oneBlocks []uint64
+	process := func(block *pbbstream.Block) {
+		out, err := bundler.process(context.Background(), block)
+		require.NoError(t, err)
+		for _, blk := range out {
+			oneBlocks = append(oneBlocks, blk.Number)
+		}
+	}
+
+	for num := uint64(95); num <= 350; num++ {
+		if num == 151 {
+			// Forked block, its canonical sibling is the parent of the next block
+			process(testCatchUpBlock(num, "b", num-1, blockTime))
+		}
+		process(testCatchUpBlock(num, "a", num-10, blockTime))
+	}
+
+	assert.Equal(t, []uint64{95, 96, 97, 98, 99}, oneBlocks)
+	assert.Len(t, store.Files, 2)
+
+	bundle := readTestBundle(t, store, "0000000100")
+	require.Len(t, bundle, 100)
+	for i, blk := range bundle {
+		assert.Equal(t, testCatchUpBlockID(uint64(100+i), "a"), blk.Id)
+	}
+	assert.Len(t, readTestBundle(t, store, "0000000200"), 100)
+
+	remaining := bundler.close()
+	require.Len(t, remaining, 51)
+	assert.Equal(t, uint64(300), remaining[0].Number)
+	assert.Equal(t, uint64(350), remaining[50].Number)
+}
+
+func TestCatchUpBundler_WritesSidecarsAndHead(t *testing.T) {
+	ctx := context.Background()
+	store, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+	sidecarStore, err := sidecar.NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	bundler := newCatchUpBundler(store, sidecarStore, 100, time.Minute, testLogger)
+	for num := uint64(100); num <= 310; num++ {
+		_, err := bundler.process(ctx, testCatchUpBlock(num, "a", num-5, time.Now().Add(-time.Hour)))
+		require.NoError(t, err)
+	}
+
+	for _, baseBlockNum := range []uint64{100, 200} {
+		bundleSidecar, err := sidecar.Read(ctx, sidecarStore, baseBlockNum)
+		require.NoError(t, err)
+		require.Len(t, bundleSidecar.Blocks, 100)
+
+		built, err := sidecar.BuildFromBundle(ctx, store, baseBlockNum)
+		require.NoError(t, err)
+		assert.Equal(t, built.Checksum, bundleSidecar.Checksum)
+	}
+
+	head, err := types.ReadMergedBlocksStoreHead(ctx, store)
+	require.NoError(t, err)
+	require.NotNil(t, head)
+	assert.Equal(t, uint64(200), head.LastBundleBaseBlockNum)
+	assert.Equal(t, uint64(299), head.LIBNum)
+	assert.Equal(t, testCatchUpBlockID(299, "a"), head.LIBID)
+}
+
+func TestCatchUpBundler_SwitchesToLive(t *testing.T) {
+	store := dstore.NewMockStore(nil)
+	bundler := newCatchUpBundler(store, nil, 100, time.Minute, testLogger)
+
+	for num := uint64(100); num < 105; num++ {
+		out, err := bundler.process(context.Background(), testCatchUpBlock(num, "a", num-1, time.Now().Add(-time.Hour)))
+		require.NoError(t, err)
+		assert.Empty(t, out)
+	}
+
+	out, err := bundler.process(context.Background(), testCatchUpBlock(105, "a", 104, time.Now()))
+	require.NoError(t, err)
+	require.Len(t, out, 6)
+	assert.Equal(t, uint64(100), out[0].Number)
+	assert.Equal(t, uint64(105), out[5].Number)
+
+	out, err = bundler.process(context.Background(), testCatchUpBlock(106, "a", 105, time.Now().Add(-time.Hour)))
+	require.NoError(t, err)
+	assert.Len(t, out, 1)
+	assert.Empty(t, store.Files)
+}
+
+func TestCatchUpBundler_WritesBundlesOnlyAsLeader(t *testing.T) {
+	store := dstore.NewMockStore(nil)
+	bundler := newCatchUpBundler(store, nil, 100, time.Minute, testLogger)
+	bundler.isLeader = func() bool { return false }
+
+	var oneBlocks []uint64
+	for num := uint64(100); num <= 210; num++ {
+		out, err := bundler.process(context.Background(), testCatchUpBlock(num, "a", num-5, time.Now().Add(-time.Hour)))
+		require.NoError(t, err)
+		for _, blk := range out {
+			oneBlocks = append(oneBlocks, blk.Number)
+		}
+	}
+
+	assert.Empty(t, store.Files)
+	require.Len(t, oneBlocks, 111, "all blocks handed to the one-block files uploader")
+	assert.Equal(t, uint64(100), oneBlocks[0])
+	assert.Equal(t, uint64(210), oneBlocks[110])
+}
+
+func testCatchUpBlock(num uint64, fork string, libNum uint64, blockTime time.Time) *pbbstream.Block {
+	return &pbbstream.Block{
+		Id:        testCatchUpBlockID(num, fork),
+		Number:    num,
+		ParentId:  testCatchUpBlockID(num-1, "a"),
+		ParentNum: num - 1,
+		LibNum:    libNum,
+		Timestamp: timestamppb.New(blockTime),
+		Payload:   &anypb.Any{TypeUrl: "type.googleapis.com/sf.test.Block"},
+	}
+}
+
+func testCatchUpBlockID(num uint64, fork string) string {
+	return fmt.Sprintf("%08d%s", num, fork)
+}
+
+func readTestBundle(t *testing.T, store *dstore.MockStore, filename string) (out []*pbbstream.Block) {
+	t.Helper()
+
+	content, found := store.Files[filename]
+	require.True(t, found, "bundle %q not found", filename)
+
+	reader, err := bstream.NewDBinBlockReader(bytes.NewReader(content))
+	require.NoError(t, err)
+
+	for {
+		block, err := reader.Read()
+		if err == io.EOF {
+			return
+		}
+		require.NoError(t, err)
+
+		out = append(out, block)
+	}
+}
@@ -0,0 +1,166 @@
+package mindreader
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"go.uber.org/zap"
+)
+
+const dryRunMaxReportedErrors = 20
+
+// BlockDecoder decodes the block's payload into the chain specific block, returning an error if it's not possible
+type BlockDecoder func(block *pbbstream.Block) error
+
+// DryRunReport summarizes the blocks read in dry-run mode
+type DryRunReport struct {
+	StartedAt       time.Time `json:"started_at"`
+	Duration        string    `json:"duration"`
+	BlockCount      uint64    `json:"block_count"`
+	BlocksPerSecond float64   `json:"blocks_per_second"`
+	FirstBlock      string    `json:"first_block,omitempty"`
+	LastBlock       string    `json:"last_block,omitempty"`
+
+	// ReadErrors counts the errors returned by the console reader while reading blocks out of the node logs
+	ReadErrors uint64 `json:"read_errors"`
+	// DecodeErrors counts the blocks whose payload could not be decoded into the chain's block
+	DecodeErrors uint64 `json:"decode_errors"`
+
+	// Gaps counts the times a block number skipped ahead of the previous block + 1, MissingBlocks being
+	// the total count of skipped block numbers
+	Gaps          uint64 `json:"gaps"`
+	MissingBlocks uint64 `json:"missing_blocks"`
+	// ParentMismatches counts the blocks following the previous block number without referencing it as parent
+	ParentMismatches uint64 `json:"parent_mismatches"`
+	// Rewinds counts the blocks whose number is lower than or equal to the previous block's one
+	Rewinds uint64 `json:"rewinds"`
+
+	LastErrors []string `json:"last_errors,omitempty"`
+}
+
+// dryRun validates the blocks read in dry-run mode and accumulates the report, blocks are not stored nor served
+type dryRun struct {
+	decode BlockDecoder
+	logger *zap.Logger
+
+	lock      sync.Mutex
+	report    DryRunReport
+	lastBlock *pbbstream.Block
+}
+
+func newDryRun(decode BlockDecoder, logger *zap.Logger) *dryRun {
+	return &dryRun{
+		decode: decode,
+		logger: logger,
+		report: DryRunReport{StartedAt: time.Now()},
+	}
+}
+
+func (d *dryRun) observeReadError(err error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.report.ReadErrors++
+	d.recordError(fmt.Sprintf("read block: %s", err))
+}
+
+func (d *dryRun) observe(block *pbbstream.Block) {
+	var decodeErr error
+	if d.decode != nil {
+		decodeErr = d.decode(block)
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.report.BlockCount++
+	d.report.LastBlock = blockRefString(block)
+	if d.report.FirstBlock == "" {
+		d.report.FirstBlock = d.report.LastBlock
+	}
+
+	if decodeErr != nil {
+		d.report.DecodeErrors++
+		d.recordError(fmt.Sprintf("decode block %s: %s", blockRefString(block), decodeErr))
+	}
+
+	if previous := d.lastBlock; previous != nil {
+		switch {
+		case block.Number <= previous.Number:
+			d.report.Rewinds++
+			d.recordError(fmt.Sprintf("block %s rewinds from previous block %s", blockRefString(block), blockRefString(previous)))
+
+		case block.Number > previous.Number+1:
+			d.report.Gaps++
+			d.report.MissingBlocks += block.Number - previous.Number - 1
+			d.recordError(fmt.Sprintf("block %s skips ahead of previous block %s", blockRefString(block), blockRefString(previous)))
+
+		case block.ParentId != previous.Id:
+			d.report.ParentMismatches++
+			d.recordError(fmt.Sprintf("block %s parent %q is not previous block %s", blockRefString(block), block.ParentId, blockRefString(previous)))
+		}
+	}
+
+	d.lastBlock = block
+}
+
+// recordError must be called with the lock held
+func (d *dryRun) recordError(msg string) {
+	d.logger.Warn("dry run issue", zap.String("issue", msg))
+
+	d.report.LastErrors = append(d.report.LastErrors, msg)
+	if len(d.report.LastErrors) > dryRunMaxReportedErrors {
+		d.report.LastErrors = d.report.LastErrors[1:]
+	}
+}
+
+// Report returns a snapshot of the current report
+func (d *dryRun) Report() DryRunReport {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	report := d.report
+	report.LastErrors = append([]string(nil), d.report.LastErrors...)
+
+	elapsed := time.Since(report.StartedAt)
+	report.Duration = elapsed.Round(time.Millisecond).String()
+	if seconds := elapsed.Seconds(); seconds > 0 {
+		report.BlocksPerSecond = float64(report.BlockCount) / seconds
+	}
+
+	return report
+}
+
+func (d *dryRun) logReport(msg string) {
+	report := d.Report()
+	d.logger.Info(msg,
+		zap.String("duration", report.Duration),
+		zap.Uint64("block_count", report.BlockCount),
+		zap.Float64("blocks_per_second", report.BlocksPerSecond),
+		zap.String("first_block", report.FirstBlock),
+		zap.String("last_block", report.LastBlock),
+		zap.Uint64("read_errors", report.ReadErrors),
+		zap.Uint64("decode_errors", report.DecodeErrors),
+		zap.Uint64("gaps", report.Gaps),
+		zap.Uint64("missing_blocks", report.MissingBlocks),
+		zap.Uint64("parent_mismatches", report.ParentMismatches),
+		zap.Uint64("rewinds", report.Rewinds),
+	)
+}
+
+func (d *dryRun) writeReport(path string) error {
+	content, err := json.MarshalIndent(d.Report(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal report: %w", err)
+	}
+
+	return os.WriteFile(path, content, 0644)
+}
+
+func blockRefString(block *pbbstream.Block) string {
+	return fmt.Sprintf("#%d (%s)", block.Number, block.Id)
+}
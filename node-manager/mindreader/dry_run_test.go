@@ -0,0 +1,46 @@
+package mindreader
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRun_Report(t *testing.T) {
+	dryRun := newDryRun(func(block *pbbstream.Block) error {
+		if block.Number == 3 {
+			return fmt.Errorf("invalid payload")
+		}
+		return nil
+	}, testLogger)
+
+	block := func(num uint64, id, parentID string) *pbbstream.Block {
+		return &pbbstream.Block{Number: num, Id: id, ParentId: parentID}
+	}
+
+	dryRun.observe(block(1, "1a", "0a"))
+	dryRun.observe(block(2, "2a", "1a"))
+	dryRun.observe(block(3, "3a", "2a"))
+	dryRun.observe(block(6, "6a", "5a"))
+	dryRun.observe(block(7, "7b", "6b"))
+	dryRun.observe(block(7, "7a", "6a"))
+	dryRun.observeReadError(fmt.Errorf("unexpected line"))
+
+	report := dryRun.Report()
+	assert.Equal(t, uint64(6), report.BlockCount)
+	assert.Equal(t, "#1 (1a)", report.FirstBlock)
+	assert.Equal(t, "#7 (7a)", report.LastBlock)
+	assert.Equal(t, uint64(1), report.ReadErrors)
+	assert.Equal(t, uint64(1), report.DecodeErrors)
+	assert.Equal(t, uint64(1), report.Gaps)
+	assert.Equal(t, uint64(2), report.MissingBlocks)
+	assert.Equal(t, uint64(1), report.ParentMismatches)
+	assert.Equal(t, uint64(1), report.Rewinds)
+	assert.Len(t, report.LastErrors, 5)
+
+	require.NoError(t, dryRun.writeReport(filepath.Join(t.TempDir(), "report.json")))
+}
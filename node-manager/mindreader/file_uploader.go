@@ -1,17 +1,47 @@
 package mindreader
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/abourget/llerrgroup"
+	"github.com/streamingfast/bstream"
 	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/node-manager/metrics"
 	"github.com/streamingfast/shutter"
 	"go.uber.org/zap"
 )
 
+const maxUploadRetryBackoff = 30 * time.Second
+
+// UploadOptions configures how one-block files are uploaded to the remote one-block store.
+type UploadOptions struct {
+	// Workers is the number of one-block files uploaded concurrently
+	Workers int
+	// MaxAttempts is the number of times a file upload is attempted before giving up on it, a file
+	// that failed is retried on the next upload pass
+	MaxAttempts int
+	// RetryBackoff is the delay before retrying a failed upload, doubled at each subsequent attempt
+	RetryBackoff time.Duration
+	// Direct uploads one-block files straight from memory to the remote store, skipping the local store
+	// hop. Files failing all attempts fall back to the local store and are uploaded from there.
+	Direct bool
+}
+
+var DefaultUploadOptions = UploadOptions{
+	Workers:      200,
+	MaxAttempts:  5,
+	RetryBackoff: 500 * time.Millisecond,
+}
+
+type directUpload struct {
+	filename string
+	content  []byte
+}
+
 type FileUploader struct {
 	*shutter.Shutter
 	mutex            sync.Mutex
@@ -19,6 +49,17 @@ type FileUploader struct {
 	destinationStore dstore.Store
 	logger           *zap.Logger
 	complete         chan struct{}
+
+	options UploadOptions
+	deduped bool
+
+	// isLeader is nil unless leader election is enabled, followers do not upload and only retain the
+	// last `followerRetainedBlocks` blocks locally to upload them in case they take over
+	isLeader               func() bool
+	followerRetainedBlocks uint64
+
+	directQueue       chan *directUpload
+	directWorkersDone sync.WaitGroup
 }
 
 func NewFileUploader(localStore dstore.Store, destinationStore dstore.Store, logger *zap.Logger) *FileUploader {
@@ -28,7 +69,35 @@ func NewFileUploader(localStore dstore.Store, destinationStore dstore.Store, log
 		localStore:       localStore,
 		destinationStore: destinationStore,
 		logger:           logger,
+		options:          DefaultUploadOptions,
+	}
+}
+
+// SetOptions must be called before the uploader is started
+func (fu *FileUploader) SetOptions(options UploadOptions) {
+	if options.Workers <= 0 {
+		options.Workers = 1
+	}
+
+	if options.MaxAttempts <= 0 {
+		options.MaxAttempts = 1
 	}
+
+	fu.options = options
+	if options.Direct {
+		fu.directQueue = make(chan *directUpload, options.Workers)
+	}
+}
+
+// SetLeaderElection makes the uploader upload files only while `isLeader` returns true, must be called before
+// the uploader is started
+func (fu *FileUploader) SetLeaderElection(isLeader func() bool, followerRetainedBlocks uint64) {
+	fu.isLeader = isLeader
+	fu.followerRetainedBlocks = followerRetainedBlocks
+}
+
+func (fu *FileUploader) leader() bool {
+	return fu.isLeader == nil || fu.isLeader()
 }
 
 func (fu *FileUploader) Start(ctx context.Context) {
@@ -42,6 +111,10 @@ func (fu *FileUploader) Start(ctx context.Context) {
 		return
 	}
 
+	if fu.options.Direct {
+		fu.startDirectWorkers()
+	}
+
 	var terminating bool
 	for {
 		err := fu.uploadFiles(ctx)
@@ -66,27 +139,193 @@ func (fu *FileUploader) uploadFiles(ctx context.Context) error {
 	fu.mutex.Lock()
 	defer fu.mutex.Unlock()
 
-	eg := llerrgroup.New(200)
+	if !fu.leader() {
+		// Files uploaded by the leader meanwhile are deduped if we take over
+		fu.deduped = false
+		return fu.pruneFollowerFiles(ctx)
+	}
+
+	if !fu.deduped {
+		if err := fu.dedupeUploadedFiles(ctx); err != nil {
+			fu.logger.Warn("unable to dedupe already uploaded files, they are going to be uploaded again", zap.Error(err))
+		}
+		fu.deduped = true
+	}
+
+	eg := llerrgroup.New(fu.options.Workers)
 	_ = fu.localStore.Walk(ctx, "", func(filename string) (err error) {
 		if eg.Stop() {
 			return nil
 		}
 		eg.Go(func() error {
-			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
-			defer cancel()
+			return fu.upload(filename, func(ctx context.Context) error {
+				return fu.destinationStore.PushLocalFile(ctx, fu.localStore.ObjectPath(filename), filename)
+			})
+		})
 
-			if traceEnabled {
-				fu.logger.Debug("uploading file to storage", zap.String("local_file", filename))
+		return nil
+	})
+
+	return eg.Wait()
+}
+
+// dedupeUploadedFiles deletes the local files that are already present in the destination store, which happens
+// when the process stopped after a file was uploaded but before it was deleted locally or when another reader
+// uploaded the same block (files are compared without their suffix).
+func (fu *FileUploader) dedupeUploadedFiles(ctx context.Context) error {
+	localFiles := map[string][]string{}
+	if err := fu.localStore.Walk(ctx, "", func(filename string) error {
+		canonicalName := canonicalOneBlockName(filename)
+		localFiles[canonicalName] = append(localFiles[canonicalName], filename)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("list local files: %w", err)
+	}
+
+	if len(localFiles) == 0 {
+		return nil
+	}
+
+	deduped := 0
+	err := fu.destinationStore.Walk(ctx, "", func(filename string) error {
+		canonicalName := canonicalOneBlockName(filename)
+		for _, localFile := range localFiles[canonicalName] {
+			if err := fu.localStore.DeleteObject(ctx, localFile); err != nil {
+				return fmt.Errorf("delete already uploaded file %q: %w", localFile, err)
 			}
 
-			if err = fu.destinationStore.PushLocalFile(ctx, fu.localStore.ObjectPath(filename), filename); err != nil {
-				return fmt.Errorf("moving file %q to storage: %w", filename, err)
+			deduped++
+		}
+
+		delete(localFiles, canonicalName)
+		return nil
+	})
+
+	fu.logger.Info("deduped already uploaded one-block files", zap.Int("local_files", len(localFiles)), zap.Int("deduped", deduped))
+	return err
+}
+
+// pruneFollowerFiles deletes the local files more than `followerRetainedBlocks` blocks behind the most recent one
+func (fu *FileUploader) pruneFollowerFiles(ctx context.Context) error {
+	var filenames []string
+	var highestBlockNum uint64
+	if err := fu.localStore.Walk(ctx, "", func(filename string) error {
+		if blockNum, _, _, _, _, err := bstream.ParseFilename(filename); err == nil {
+			highestBlockNum = max(highestBlockNum, blockNum)
+		}
+
+		filenames = append(filenames, filename)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("list local files: %w", err)
+	}
+
+	for _, filename := range filenames {
+		blockNum, _, _, _, _, err := bstream.ParseFilename(filename)
+		if err != nil || blockNum+fu.followerRetainedBlocks >= highestBlockNum {
+			continue
+		}
+
+		if err := fu.localStore.DeleteObject(ctx, filename); err != nil {
+			return fmt.Errorf("delete file %q: %w", filename, err)
+		}
+	}
+
+	return nil
+}
+
+// canonicalOneBlockName returns the one-block file name without its suffix, the file name itself if it cannot be parsed
+func canonicalOneBlockName(filename string) string {
+	if _, _, _, _, canonicalName, err := bstream.ParseFilename(filename); err == nil {
+		return canonicalName
+	}
+
+	return filename
+}
+
+// upload performs `push` with retries, reporting the latency of the successful upload
+func (fu *FileUploader) upload(filename string, push func(ctx context.Context) error) (err error) {
+	start := time.Now()
+	backoff := fu.options.RetryBackoff
+
+	for attempt := 1; attempt <= fu.options.MaxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Minute)
+		err = push(ctx)
+		cancel()
+
+		if err == nil {
+			metrics.OneBlockUploadDuration.ObserveSince(start)
+			if traceEnabled {
+				fu.logger.Debug("uploaded file to storage", zap.String("file", filename), zap.Int("attempt", attempt), zap.Duration("latency", time.Since(start)))
 			}
 			return nil
-		})
+		}
+
+		metrics.OneBlockUploadFailures.Inc()
+		if attempt < fu.options.MaxAttempts {
+			fu.logger.Debug("failed to upload file, retrying", zap.String("file", filename), zap.Int("attempt", attempt), zap.Duration("backoff", backoff), zap.Error(err))
+			time.Sleep(backoff)
+			backoff = min(backoff*2, maxUploadRetryBackoff)
+		}
+	}
+
+	return fmt.Errorf("moving file %q to storage after %d attempt(s): %w", filename, fu.options.MaxAttempts, err)
+}
 
+// Enqueue queues the file for direct upload, blocking while all workers are busy and the queue is full
+func (fu *FileUploader) Enqueue(ctx context.Context, filename string, content []byte) error {
+	select {
+	case fu.directQueue <- &directUpload{filename: filename, content: content}:
 		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (fu *FileUploader) startDirectWorkers() {
+	fu.logger.Info("starting direct upload workers", zap.Int("workers", fu.options.Workers))
+
+	for i := 0; i < fu.options.Workers; i++ {
+		fu.directWorkersDone.Add(1)
+		go func() {
+			defer fu.directWorkersDone.Done()
+
+			for item := range fu.directQueue {
+				fu.uploadDirect(item)
+			}
+		}()
+	}
+}
+
+// CloseDirect stops accepting direct uploads and waits until all queued ones are completed
+func (fu *FileUploader) CloseDirect() {
+	close(fu.directQueue)
+	fu.directWorkersDone.Wait()
+}
+
+func (fu *FileUploader) uploadDirect(item *directUpload) {
+	if !fu.leader() {
+		// Written locally so that it's retained and uploaded in case we take over
+		fu.writeLocal(item)
+		return
+	}
+
+	err := fu.upload(item.filename, func(ctx context.Context) error {
+		return fu.destinationStore.WriteObject(ctx, item.filename, bytes.NewReader(item.content))
 	})
+	if err == nil {
+		return
+	}
 
-	return eg.Wait()
+	fu.logger.Warn("direct upload failed, falling back to local store", zap.String("file", item.filename), zap.Error(err))
+	fu.writeLocal(item)
+}
+
+func (fu *FileUploader) writeLocal(item *directUpload) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	if err := fu.localStore.WriteObject(ctx, item.filename, bytes.NewReader(item.content)); err != nil {
+		fu.logger.Error("unable to write one-block file to local store, you will need to reprocess over this block", zap.String("file", item.filename), zap.Error(err))
+	}
 }
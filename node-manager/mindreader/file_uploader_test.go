@@ -2,10 +2,12 @@ package mindreader
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -41,3 +43,93 @@ func TestFileUploader(t *testing.T) {
 		t.Error("took took long")
 	}
 }
+
+func TestFileUploader_DedupesAlreadyUploadedFiles(t *testing.T) {
+	localStore := dstore.NewMockStore(nil)
+	localStore.SetFile("test1", nil)
+	localStore.SetFile("test2", nil)
+
+	destinationStore := dstore.NewMockStore(nil)
+	destinationStore.SetFile("test1", nil)
+
+	var pushed []string
+	destinationStore.PushLocalFileFunc = func(_ context.Context, _, name string) (err error) {
+		pushed = append(pushed, name)
+		return nil
+	}
+
+	uploader := NewFileUploader(localStore, destinationStore, testLogger)
+	uploader.SetOptions(UploadOptions{Workers: 1, MaxAttempts: 1})
+	require.NoError(t, uploader.uploadFiles(context.Background()))
+
+	assert.Equal(t, []string{"test2"}, pushed)
+}
+
+func TestFileUploader_RetriesFailedUploads(t *testing.T) {
+	localStore := dstore.NewMockStore(nil)
+	localStore.SetFile("test1", nil)
+
+	attempts := 0
+	destinationStore := dstore.NewMockStore(nil)
+	destinationStore.PushLocalFileFunc = func(_ context.Context, _, _ string) (err error) {
+		attempts++
+		if attempts < 3 {
+			return fmt.Errorf("transient failure")
+		}
+		return nil
+	}
+
+	uploader := NewFileUploader(localStore, destinationStore, testLogger)
+	uploader.SetOptions(UploadOptions{Workers: 1, MaxAttempts: 3, RetryBackoff: time.Millisecond})
+	require.NoError(t, uploader.uploadFiles(context.Background()))
+	assert.Equal(t, 3, attempts)
+
+	attempts = -10
+	err := uploader.uploadFiles(context.Background())
+	assert.ErrorContains(t, err, "after 3 attempt(s): transient failure")
+}
+
+func TestFileUploader_Direct(t *testing.T) {
+	localStore := dstore.NewMockStore(nil)
+	destinationStore := dstore.NewMockStore(nil)
+
+	uploader := NewFileUploader(localStore, destinationStore, testLogger)
+	uploader.SetOptions(UploadOptions{Workers: 2, MaxAttempts: 1, Direct: true})
+	uploader.startDirectWorkers()
+
+	require.NoError(t, uploader.Enqueue(context.Background(), "test1", []byte("content1")))
+	require.NoError(t, uploader.Enqueue(context.Background(), "test2", []byte("content2")))
+	uploader.CloseDirect()
+
+	assert.Equal(t, []byte("content1"), destinationStore.Files["test1"])
+	assert.Equal(t, []byte("content2"), destinationStore.Files["test2"])
+	assert.Empty(t, localStore.Files)
+}
+
+func TestFileUploader_Follower(t *testing.T) {
+	localStore := dstore.NewMockStore(nil)
+	localStore.SetFile("0000000100-aa-99-90-default", nil)
+	localStore.SetFile("0000000101-ab-aa-90-default", nil)
+	localStore.SetFile("0000000102-ac-ab-90-default", nil)
+
+	var pushed []string
+	destinationStore := dstore.NewMockStore(nil)
+	destinationStore.SetFile("0000000101-ab-aa-90-other", nil)
+	destinationStore.PushLocalFileFunc = func(_ context.Context, _, name string) (err error) {
+		pushed = append(pushed, name)
+		return nil
+	}
+
+	leader := false
+	uploader := NewFileUploader(localStore, destinationStore, testLogger)
+	uploader.SetOptions(UploadOptions{Workers: 1, MaxAttempts: 1})
+	uploader.SetLeaderElection(func() bool { return leader }, 1)
+
+	require.NoError(t, uploader.uploadFiles(context.Background()))
+	assert.Empty(t, pushed)
+	assert.NotContains(t, localStore.Files, "0000000100-aa-99-90-default")
+
+	leader = true
+	require.NoError(t, uploader.uploadFiles(context.Background()))
+	assert.Equal(t, []string{"0000000102-ac-ab-90-default"}, pushed, "block 101 was already uploaded by the other reader")
+}
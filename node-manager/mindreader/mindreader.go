@@ -22,13 +22,19 @@ import (
 	"path"
 	"regexp"
 	"sync"
+	"time"
 
 	"github.com/streamingfast/bstream"
 	"github.com/streamingfast/bstream/blockstream"
 	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
 	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/encryptedstore"
+	"github.com/streamingfast/firehose-core/internal/lease"
 	"github.com/streamingfast/firehose-core/internal/utils"
 	nodeManager "github.com/streamingfast/firehose-core/node-manager"
+	"github.com/streamingfast/firehose-core/node-manager/metrics"
+	"github.com/streamingfast/firehose-core/sidecar"
+	"github.com/streamingfast/firehose-core/types"
 	"github.com/streamingfast/logging"
 	"github.com/streamingfast/shutter"
 	"go.uber.org/zap"
@@ -58,6 +64,12 @@ type MindReaderPlugin struct {
 	consoleReaderFactory     ConsolerReaderFactory
 	stopBlock                uint64 // if set, call shutdownFunc(nil) when we hit this number
 	channelCapacity          int    // transformed blocks are buffered in a channel
+	workingDirectory         string
+	spillMaxBytes            uint64 // if set, blocks not fitting in the channel are spilled to disk up to this size
+	spillQueue               *spillQueue
+	dryRun                   *dryRun
+	elector                  *lease.Elector
+	stopElection             context.CancelFunc
 	forceFinalityAfterBlocks *uint64
 
 	lastSeenBlock     bstream.BlockRef
@@ -120,7 +132,7 @@ func NewMindReaderPlugin(
 		return nil, fmt.Errorf("new local one block store: %w", err)
 	}
 
-	remoteOneBlocksStore, err := dstore.NewStore(oneBlocksStoreURL, "dbin.zst", "zstd", false)
+	remoteOneBlocksStore, err := encryptedstore.NewStore(oneBlocksStoreURL, "dbin.zst", "zstd", false)
 	if err != nil {
 		return nil, fmt.Errorf("new remote one block store: %w", err)
 	}
@@ -141,6 +153,7 @@ func NewMindReaderPlugin(
 		consoleReaderFactory:     consoleReaderFactory,
 		stopBlock:                stopBlockNum,
 		channelCapacity:          channelCapacity,
+		workingDirectory:         workingDirectory,
 		headBlockUpdater:         headBlockUpdater,
 		blockStreamServer:        blockStreamServer,
 		forceFinalityAfterBlocks: utils.GetEnvForceFinalityAfterBlocks(),
@@ -159,6 +172,75 @@ func validateOneBlockSuffix(suffix string) error {
 	return nil
 }
 
+// EnableSpillQueue makes blocks that do not fit in the blocks channel spill to disk in the working directory instead
+// of blocking the node, up to `maxBytes` after which the plugin shuts down. Must be called before the plugin is launched.
+func (p *MindReaderPlugin) EnableSpillQueue(maxBytes uint64) {
+	p.spillMaxBytes = maxBytes
+}
+
+// SetUploadOptions configures how one-block files are uploaded to the one-block store, must be called before
+// the plugin is launched.
+func (p *MindReaderPlugin) SetUploadOptions(options UploadOptions) {
+	p.archiver.SetUploadOptions(options)
+}
+
+// EnableMergedBlocksCatchUp makes the plugin write blocks below LIB as bundles straight to the merged blocks store
+// instead of one-block files, switching to one-block files once blocks are younger than `liveThreshold`. The bundle
+// size is the store's one, `bundleSize` being used for a new store (0 for the default). Bundles get a sidecar in the
+// store at `sidecarStoreURL` if not empty. Must be called before the plugin is launched.
+func (p *MindReaderPlugin) EnableMergedBlocksCatchUp(mergedBlocksStoreURL, sidecarStoreURL string, bundleSize uint64, liveThreshold time.Duration) error {
+	mergedBlocksStore, err := encryptedstore.NewDBinStore(mergedBlocksStoreURL)
+	if err != nil {
+		return fmt.Errorf("new merged blocks store: %w", err)
+	}
+
+	bundleSize, err = types.EnsureBundleSize(context.Background(), mergedBlocksStore, bundleSize)
+	if err != nil {
+		return fmt.Errorf("merged blocks bundle size: %w", err)
+	}
+
+	var sidecarStore dstore.Store
+	if sidecarStoreURL != "" {
+		sidecarStore, err = sidecar.NewStore(sidecarStoreURL)
+		if err != nil {
+			return fmt.Errorf("new sidecar store: %w", err)
+		}
+	}
+
+	p.archiver.EnableCatchUpBundles(mergedBlocksStore, sidecarStore, bundleSize, liveThreshold)
+	return nil
+}
+
+// EnableLeaderElection makes the plugin upload one-block files only while it holds `lock`, followers retaining
+// their last `followerRetainedBlocks` blocks locally to upload them when taking over. Must be called before the
+// plugin is launched.
+func (p *MindReaderPlugin) EnableLeaderElection(lock *lease.Lock, followerRetainedBlocks uint64) {
+	p.elector = lease.NewElector(lock, func(leader bool) {
+		if leader {
+			metrics.ReaderNodeLeader.SetUint64(1)
+		} else {
+			metrics.ReaderNodeLeader.SetUint64(0)
+		}
+	}, p.zlogger)
+
+	p.archiver.fileUploader.SetLeaderElection(p.elector.IsLeader, followerRetainedBlocks)
+}
+
+// EnableDryRun makes the plugin validate the blocks read (decoding them with `decode` and checking their continuity)
+// without storing nor serving them, see [MindReaderPlugin.DryRunReport]. Must be called before the plugin is launched.
+func (p *MindReaderPlugin) EnableDryRun(decode BlockDecoder) {
+	p.dryRun = newDryRun(decode, p.zlogger)
+}
+
+// DryRunReport returns the current dry-run report, false if dry-run mode is not enabled
+func (p *MindReaderPlugin) DryRunReport() (DryRunReport, bool) {
+	if p.dryRun == nil {
+		return DryRunReport{}, false
+	}
+
+	return p.dryRun.Report(), true
+}
+
 func (p *MindReaderPlugin) Name() string {
 	return "MindReaderPlugin"
 }
@@ -186,37 +268,98 @@ func (p *MindReaderPlugin) Launch() {
 		p.OnTerminating(func(_ error) { closer.Close() })
 	}
 
-	p.zlogger.Debug("starting archiver")
-	p.archiver.Start(ctx)
+	if p.dryRun != nil {
+		p.zlogger.Info("dry-run mode enabled, blocks are validated but not stored nor served")
+		go p.logDryRunReports()
+	} else {
+		if p.elector != nil {
+			// The lease is held until the archiver completed, not only until the plugin starts terminating
+			electionCtx, stopElection := context.WithCancel(context.Background())
+			p.stopElection = stopElection
+			go p.elector.Run(electionCtx)
+		}
+
+		p.zlogger.Debug("starting archiver")
+		p.archiver.Start(ctx)
+	}
+
 	p.launch()
 
 }
 func (p *MindReaderPlugin) launch() {
 	blocks := make(chan *pbbstream.Block, p.channelCapacity)
-	p.zlogger.Info("launching blocks reading loop", zap.Int("capacity", p.channelCapacity))
+	p.zlogger.Info("launching blocks reading loop", zap.Int("capacity", p.channelCapacity), zap.Uint64("spill_max_bytes", p.spillMaxBytes))
+
+	if p.spillMaxBytes > 0 {
+		queue, err := newSpillQueue(path.Join(p.workingDirectory, "spill"), p.spillMaxBytes, blocks, p.zlogger)
+		if err != nil {
+			p.Shutdown(fmt.Errorf("new spill queue: %w", err))
+		} else {
+			p.spillQueue = queue
+		}
+	}
+
 	go p.consumeReadFlow(blocks)
 
 	go func() {
 		for {
 			err := p.readOneMessage(blocks)
 			if err != nil {
+				if p.dryRun != nil && err != io.EOF && !p.IsTerminating() {
+					p.dryRun.observeReadError(err)
+					continue
+				}
+
 				if err == io.EOF {
 					p.zlogger.Info("reached end of console reader stream, nothing more to do")
-					close(blocks)
+					p.closeBlocks(blocks)
 					return
 				}
 				p.zlogger.Error("reading from console logs", zap.Error(err))
 				p.Shutdown(err)
 				// Always read messages otherwise you'll stall the shutdown lifecycle of the managed process, leading to corrupted database if exit uncleanly afterward
 				p.drainMessages()
-				close(blocks)
+				p.closeBlocks(blocks)
 				return
 			}
 		}
 	}()
 }
 
-func (p MindReaderPlugin) Stop() {
+func (p *MindReaderPlugin) logDryRunReports() {
+	for {
+		select {
+		case <-p.Terminating():
+			return
+		case <-time.After(30 * time.Second):
+			p.dryRun.logReport("dry run progress")
+		}
+	}
+}
+
+func (p *MindReaderPlugin) completeDryRun() {
+	p.dryRun.logReport("dry run completed")
+
+	reportPath := path.Join(p.workingDirectory, "dry-run-report.json")
+	if err := p.dryRun.writeReport(reportPath); err != nil {
+		p.zlogger.Warn("unable to write dry run report", zap.String("path", reportPath), zap.Error(err))
+		return
+	}
+
+	p.zlogger.Info("dry run report written", zap.String("path", reportPath))
+}
+
+// closeBlocks closes the blocks channel, through the spill queue if enabled so that spilled blocks are drained first
+func (p *MindReaderPlugin) closeBlocks(blocks chan *pbbstream.Block) {
+	if p.spillQueue != nil {
+		p.spillQueue.Close()
+		return
+	}
+
+	close(blocks)
+}
+
+func (p *MindReaderPlugin) Stop() {
 	p.zlogger.Info("mindreader is stopping")
 	if p.lines == nil {
 		// If the `lines` channel was not created yet, it means everything was shut down very rapidly
@@ -249,16 +392,29 @@ func (p *MindReaderPlugin) consumeReadFlow(blocks <-chan *pbbstream.Block) {
 		block, ok := <-blocks
 		if !ok {
 			p.zlogger.Info("all blocks in channel were drained, exiting read flow")
+			if p.dryRun != nil {
+				p.completeDryRun()
+			}
+
 			p.archiver.Shutdown(nil)
 
 			<-p.archiver.Terminated()
 			p.zlogger.Info("archiver termination code completed")
 
+			if p.stopElection != nil {
+				p.stopElection()
+			}
+
 			return
 		}
 
 		p.zlogger.Debug("got one block", zap.Uint64("block_num", block.Number))
 
+		if p.dryRun != nil {
+			p.dryRun.observe(block)
+			continue
+		}
+
 		err := p.archiver.StoreBlock(ctx, block)
 		if err != nil {
 			p.zlogger.Error("failed storing block in archiver, shutting down and trying to send next blocks individually. You will need to reprocess over this range.", zap.Error(err), zap.String("received_block", block.Id), zap.Uint64("received_block_num", block.Number))
@@ -335,7 +491,13 @@ func (p *MindReaderPlugin) readOneMessage(blocks chan<- *pbbstream.Block) error
 		}
 	}
 
-	blocks <- block
+	if p.spillQueue != nil {
+		if err := p.spillQueue.Push(block); err != nil {
+			return err
+		}
+	} else {
+		blocks <- block
+	}
 
 	if p.stopBlock != 0 && block.Number >= p.stopBlock && !p.IsTerminating() {
 		p.zlogger.Info("shutting down because requested end block reached", zap.Stringer("block", block))
@@ -0,0 +1,230 @@
+package mindreader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/streamingfast/firehose-core/node-manager/metrics"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+const spillFileExtension = ".block"
+
+var errSpillBudgetExhausted = errors.New("spill queue disk budget exhausted")
+
+// spillQueue sits in front of the blocks channel: blocks are sent directly to the channel while it has
+// room and are spilled to disk (one file per block) as soon as it's full. Once a block has been spilled,
+// all following blocks are spilled too until the queue is fully drained so that order is preserved.
+//
+// Spilled blocks are drained in order to the channel by a background goroutine. Blocks left on disk
+// by a previous run are picked up and drained first.
+type spillQueue struct {
+	dir      string
+	maxBytes uint64
+	out      chan<- *pbbstream.Block
+	logger   *zap.Logger
+
+	lock    sync.Mutex
+	pending int
+	bytes   uint64
+	// readSeq is the sequence of the next block to drain and writeSeq the one of the next block to spill
+	readSeq  uint64
+	writeSeq uint64
+	closed   bool
+
+	wake chan struct{}
+}
+
+func newSpillQueue(dir string, maxBytes uint64, out chan<- *pbbstream.Block, logger *zap.Logger) (*spillQueue, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("create spill directory: %w", err)
+	}
+
+	q := &spillQueue{
+		dir:      dir,
+		maxBytes: maxBytes,
+		out:      out,
+		logger:   logger,
+		wake:     make(chan struct{}, 1),
+	}
+
+	if err := q.loadExisting(); err != nil {
+		return nil, err
+	}
+
+	q.updateMetrics()
+	go q.drain()
+
+	return q, nil
+}
+
+func (q *spillQueue) loadExisting() error {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return fmt.Errorf("list spill directory: %w", err)
+	}
+
+	var sequences []uint64
+	for _, entry := range entries {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), spillFileExtension), 10, 64)
+		if err != nil || !strings.HasSuffix(entry.Name(), spillFileExtension) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("stat spilled block %q: %w", entry.Name(), err)
+		}
+
+		sequences = append(sequences, seq)
+		q.bytes += uint64(info.Size())
+	}
+
+	if len(sequences) == 0 {
+		return nil
+	}
+
+	sort.Slice(sequences, func(i, j int) bool { return sequences[i] < sequences[j] })
+
+	// Sequences are contiguous as blocks are drained from the lowest one
+	q.readSeq = sequences[0]
+	q.writeSeq = sequences[len(sequences)-1] + 1
+	q.pending = int(q.writeSeq - q.readSeq)
+
+	q.logger.Info("found blocks spilled by a previous run, they are going to be drained first", zap.Int("count", q.pending), zap.Uint64("bytes", q.bytes))
+	return nil
+}
+
+// Push sends the block to the channel if nothing is spilled and the channel has room, spills it to
+// disk otherwise. It returns errSpillBudgetExhausted if spilling the block would exceed the budget.
+func (q *spillQueue) Push(block *pbbstream.Block) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.pending == 0 {
+		select {
+		case q.out <- block:
+			return nil
+		default:
+		}
+	}
+
+	content, err := proto.Marshal(block)
+	if err != nil {
+		return fmt.Errorf("marshal block to spill: %w", err)
+	}
+
+	if q.bytes+uint64(len(content)) > q.maxBytes {
+		return fmt.Errorf("%w: %d bytes spilled, budget is %d bytes", errSpillBudgetExhausted, q.bytes, q.maxBytes)
+	}
+
+	if err := os.WriteFile(q.path(q.writeSeq), content, 0644); err != nil {
+		return fmt.Errorf("spill block #%d: %w", block.Number, err)
+	}
+
+	if q.pending == 0 {
+		q.logger.Info("blocks channel is full, spilling blocks to disk", zap.Uint64("block_num", block.Number))
+	}
+
+	q.writeSeq++
+	q.pending++
+	q.bytes += uint64(len(content))
+	metrics.MindreaderSpilledBytes.AddInt(len(content))
+	q.updateMetrics()
+
+	q.signal()
+	return nil
+}
+
+// Close signals that no more blocks are pushed, the channel is closed once all spilled blocks are drained
+func (q *spillQueue) Close() {
+	q.lock.Lock()
+	q.closed = true
+	q.lock.Unlock()
+
+	q.signal()
+}
+
+func (q *spillQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *spillQueue) drain() {
+	for {
+		q.lock.Lock()
+		if q.pending == 0 {
+			closed := q.closed
+			q.lock.Unlock()
+
+			if closed {
+				close(q.out)
+				return
+			}
+
+			<-q.wake
+			continue
+		}
+
+		seq := q.readSeq
+		q.lock.Unlock()
+
+		// The size accounted in the budget is the one of the file, released even if it cannot be read
+		path := q.path(seq)
+		var size uint64
+		var content []byte
+		info, err := os.Stat(path)
+		if err == nil {
+			size = uint64(info.Size())
+			content, err = os.ReadFile(path)
+		}
+
+		var block *pbbstream.Block
+		if err == nil {
+			block = &pbbstream.Block{}
+			err = proto.Unmarshal(content, block)
+		}
+
+		if err != nil {
+			// Nothing better can be done than skipping it, the hole is going to be visible in one-block files
+			q.logger.Error("unable to read spilled block, skipping it", zap.String("path", path), zap.Error(err))
+		} else {
+			// Pending count is decremented only once the block is sent so that pushed blocks wait behind it
+			q.out <- block
+		}
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			q.logger.Warn("unable to delete drained spilled block", zap.String("path", path), zap.Error(err))
+		}
+
+		q.lock.Lock()
+		q.readSeq++
+		q.pending--
+		q.bytes -= min(q.bytes, size)
+		if q.pending == 0 {
+			q.logger.Info("spilled blocks fully drained")
+		}
+		q.updateMetrics()
+		q.lock.Unlock()
+	}
+}
+
+// updateMetrics must be called with the lock held
+func (q *spillQueue) updateMetrics() {
+	metrics.MindreaderSpillQueueDepth.SetUint64(uint64(q.pending))
+	metrics.MindreaderSpillQueueBytes.SetUint64(q.bytes)
+}
+
+func (q *spillQueue) path(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, spillFileExtension))
+}
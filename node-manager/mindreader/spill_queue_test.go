@@ -0,0 +1,103 @@
+package mindreader
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestSpillQueue_PreservesOrder(t *testing.T) {
+	blocks := make(chan *pbbstream.Block, 2)
+	queue, err := newSpillQueue(t.TempDir(), 1024*1024, blocks, testLogger)
+	require.NoError(t, err)
+
+	for i := uint64(1); i <= 10; i++ {
+		require.NoError(t, queue.Push(&pbbstream.Block{Number: i}))
+	}
+	queue.Close()
+
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, readBlockNums(t, blocks))
+}
+
+func TestSpillQueue_BudgetExhausted(t *testing.T) {
+	blocks := make(chan *pbbstream.Block, 1)
+	queue, err := newSpillQueue(t.TempDir(), 20, blocks, testLogger)
+	require.NoError(t, err)
+
+	require.NoError(t, queue.Push(&pbbstream.Block{Number: 1}))
+	require.NoError(t, queue.Push(&pbbstream.Block{Number: 2, Id: "00000002a"}))
+
+	err = queue.Push(&pbbstream.Block{Number: 3, Id: "00000003a"})
+	assert.ErrorIs(t, err, errSpillBudgetExhausted)
+
+	queue.Close()
+	assert.Equal(t, []uint64{1, 2}, readBlockNums(t, blocks))
+}
+
+func TestSpillQueue_DrainsPreviousRun(t *testing.T) {
+	dir := t.TempDir()
+
+	previous := &spillQueue{dir: dir}
+	for seq, blockNum := range map[uint64]uint64{5: 1, 6: 2} {
+		content, err := proto.Marshal(&pbbstream.Block{Number: blockNum})
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(previous.path(seq), content, 0644))
+	}
+
+	blocks := make(chan *pbbstream.Block, 10)
+	queue, err := newSpillQueue(dir, 1024*1024, blocks, testLogger)
+	require.NoError(t, err)
+	require.NoError(t, queue.Push(&pbbstream.Block{Number: 3}))
+	queue.Close()
+
+	assert.Equal(t, []uint64{1, 2, 3}, readBlockNums(t, blocks))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestSpillQueue_ReleasesBudgetOfUnreadableBlocks(t *testing.T) {
+	dir := t.TempDir()
+
+	// a spilled block that can be listed but not read
+	previous := &spillQueue{dir: dir}
+	require.NoError(t, os.Mkdir(previous.path(5), 0755))
+
+	content, err := proto.Marshal(&pbbstream.Block{Number: 2})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(previous.path(6), content, 0644))
+
+	blocks := make(chan *pbbstream.Block, 10)
+	queue, err := newSpillQueue(dir, 1024*1024, blocks, testLogger)
+	require.NoError(t, err)
+	queue.Close()
+
+	assert.Equal(t, []uint64{2}, readBlockNums(t, blocks))
+
+	queue.lock.Lock()
+	defer queue.lock.Unlock()
+	assert.Zero(t, queue.bytes)
+}
+
+func readBlockNums(t *testing.T, blocks <-chan *pbbstream.Block) (out []uint64) {
+	t.Helper()
+
+	for {
+		select {
+		case block, ok := <-blocks:
+			if !ok {
+				return
+			}
+			out = append(out, block.Number)
+		case <-time.After(5 * time.Second):
+			t.Fatal("blocks channel not closed before timeout")
+			return
+		}
+	}
+}
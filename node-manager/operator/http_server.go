@@ -114,6 +114,11 @@ func (o *Operator) healthzHandler(w http.ResponseWriter, _ *http.Request) {
 		return
 	}
 
+	if reason, notReady := o.markedNotReady(); notReady {
+		http.Error(w, "not ready: "+reason, http.StatusServiceUnavailable)
+		return
+	}
+
 	if o.aboutToStop.Load() || derr.IsShuttingDown() {
 		http.Error(w, "not ready: chain about to stop", http.StatusServiceUnavailable)
 		return
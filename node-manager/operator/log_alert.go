@@ -0,0 +1,50 @@
+package operator
+
+import (
+	"fmt"
+	"time"
+
+	logplugin "github.com/streamingfast/firehose-core/node-manager/log_plugin"
+	"go.uber.org/zap"
+)
+
+// LogPatternActionHandler returns the function to give to [logplugin.NewLogPatternAlertPlugin] performing
+// the rule's action through the operator, `notReadyDuration` being for how long the `not-ready` action
+// marks the node as not ready.
+func (o *Operator) LogPatternActionHandler(notReadyDuration time.Duration) func(rule *logplugin.LogPatternRule, line string) {
+	return func(rule *logplugin.LogPatternRule, line string) {
+		switch rule.Action {
+		case logplugin.LogPatternActionNotReady:
+			o.MarkNotReady(fmt.Sprintf("log alert %q matched", rule.Name), notReadyDuration)
+
+		case logplugin.LogPatternActionRestart:
+			o.commandChan <- &Command{cmd: "reload", logger: o.zlogger}
+
+		case logplugin.LogPatternActionMaintenance:
+			o.commandChan <- &Command{cmd: "maintenance", logger: o.zlogger}
+		}
+	}
+}
+
+// MarkNotReady makes the operator health check report not ready for `duration`, a subsequent call
+// replaces the reason and the deadline.
+func (o *Operator) MarkNotReady(reason string, duration time.Duration) {
+	o.zlogger.Info("marking node as not ready", zap.String("reason", reason), zap.Duration("duration", duration))
+
+	o.notReadyLock.Lock()
+	defer o.notReadyLock.Unlock()
+
+	o.notReadyReason = reason
+	o.notReadyUntil = time.Now().Add(duration)
+}
+
+func (o *Operator) markedNotReady() (string, bool) {
+	o.notReadyLock.Lock()
+	defer o.notReadyLock.Unlock()
+
+	if time.Now().Before(o.notReadyUntil) {
+		return o.notReadyReason, true
+	}
+
+	return "", false
+}
@@ -46,6 +46,10 @@ type Operator struct {
 
 	aboutToStop *atomic.Bool
 	zlogger     *zap.Logger
+
+	notReadyLock   sync.Mutex
+	notReadyReason string
+	notReadyUntil  time.Time
 }
 
 type Options struct {
@@ -55,6 +59,9 @@ type Options struct {
 
 	// Delay before sending Stop() to superviser, during which we return NotReady
 	ShutdownDelay time.Duration
+
+	// StallDetection when non-nil restarts or shuts down the node process when it stops producing output or blocks
+	StallDetection *StallDetectionOptions
 }
 
 type Command struct {
@@ -128,6 +135,7 @@ func (o *Operator) Launch(httpListenAddr string, options ...HTTPOption) error {
 	}
 
 	o.LaunchBackupSchedules()
+	o.enableStallDetection()
 
 	if o.options.Bootstrapper != nil {
 		o.zlogger.Info("operator calling bootstrap function")
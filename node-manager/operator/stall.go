@@ -0,0 +1,67 @@
+package operator
+
+import (
+	"fmt"
+	"time"
+
+	nodeManager "github.com/streamingfast/firehose-core/node-manager"
+	"github.com/streamingfast/firehose-core/node-manager/metrics"
+	"go.uber.org/zap"
+)
+
+type StallAction string
+
+const (
+	// StallActionRestart restarts the node process through a `reload` command
+	StallActionRestart StallAction = "restart"
+	// StallActionShutdown shuts down the operator (and the node process with it)
+	StallActionShutdown StallAction = "shutdown"
+)
+
+func ParseStallAction(in string) (StallAction, error) {
+	switch action := StallAction(in); action {
+	case StallActionRestart, StallActionShutdown:
+		return action, nil
+	default:
+		return "", fmt.Errorf("invalid stall action %q, valid values are %q and %q", in, StallActionRestart, StallActionShutdown)
+	}
+}
+
+type StallDetectionOptions struct {
+	// OutputTimeout is the maximum time without any line printed by the node process, 0 disables the check
+	OutputTimeout time.Duration
+	// BlockTimeout is the maximum time without any `FIRE BLOCK` line printed by the node process, 0 disables the check
+	BlockTimeout time.Duration
+	// Action is what the operator does when the node process is detected as stalled
+	Action StallAction
+}
+
+func (o *Operator) enableStallDetection() {
+	options := o.options.StallDetection
+	if options == nil || (options.OutputTimeout <= 0 && options.BlockTimeout <= 0) {
+		return
+	}
+
+	detectable, ok := o.Superviser.(nodeManager.StallDetectableChainSuperviser)
+	if !ok {
+		o.zlogger.Warn("stall detection requested but the chain superviser does not support it, ignoring")
+		return
+	}
+
+	detectable.EnableStallDetection(options.OutputTimeout, options.BlockTimeout, o.onStall)
+}
+
+func (o *Operator) onStall(event *nodeManager.StallEvent) {
+	action := o.options.StallDetection.Action
+	metrics.NodeStallEvents.Inc(string(event.Kind), string(action))
+
+	switch action {
+	case StallActionShutdown:
+		o.zlogger.Info("shutting down because node process stalled", zap.String("kind", string(event.Kind)), zap.Duration("since", event.Since))
+		go o.Shutdown(fmt.Errorf("instance %q stalled, no %s activity for %s", o.Superviser.GetName(), event.Kind, event.Since.Round(time.Second)))
+
+	default:
+		o.zlogger.Info("restarting node process because it stalled", zap.String("kind", string(event.Kind)), zap.Duration("since", event.Since))
+		o.commandChan <- &Command{cmd: "reload", logger: o.zlogger}
+	}
+}
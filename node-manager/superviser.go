@@ -80,6 +80,30 @@ type MonitorableChainSuperviser interface {
 	Monitor()
 }
 
+// StallDetectableChainSuperviser is implemented by chain supervisers able to detect that the managed
+// process stopped producing output or blocks for too long.
+type StallDetectableChainSuperviser interface {
+	// EnableStallDetection must be called before the process is started, a timeout of 0 disables the
+	// associated check. The `onStall` callback is called at most once per process run.
+	EnableStallDetection(outputTimeout, blockTimeout time.Duration, onStall func(event *StallEvent))
+}
+
+type StallKind string
+
+const (
+	// StallKindOutput is when the process did not output any line on stdout/stderr for too long
+	StallKindOutput StallKind = "output"
+	// StallKindBlock is when the process did not output any `FIRE BLOCK` line for too long
+	StallKindBlock StallKind = "block"
+)
+
+type StallEvent struct {
+	Kind StallKind
+	// Since is the time elapsed since the last line (or last block line) was seen
+	Since        time.Duration
+	LastLogLines []string
+}
+
 type ProducerChainSuperviser interface {
 	IsProducing() (bool, error)
 	IsActiveProducer() bool
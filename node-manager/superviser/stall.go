@@ -0,0 +1,143 @@
+package superviser
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ShinyTrinkets/overseer"
+	nodeManager "github.com/streamingfast/firehose-core/node-manager"
+	logplugin "github.com/streamingfast/firehose-core/node-manager/log_plugin"
+	"go.uber.org/zap"
+)
+
+const stallLastLineCount = 50
+
+// stallWatchdog tracks the time at which the supervised process last printed a line and
+// a `FIRE BLOCK` line so that a stalled process can be detected.
+type stallWatchdog struct {
+	outputTimeout time.Duration
+	blockTimeout  time.Duration
+	onStall       func(event *nodeManager.StallEvent)
+
+	// lastLines is kept by the watchdog itself since the regular last lines are not kept
+	// when the node logs are printed to the console
+	lastLines     *logplugin.KeepLastLinesLogPlugin
+	lastLinesLock sync.Mutex
+
+	lastLineAt  atomic.Int64
+	lastBlockAt atomic.Int64
+}
+
+// EnableStallDetection enables the detection of a stalled process, `onStall` is called when the process did not print
+// any line for `outputTimeout` or any `FIRE BLOCK` line for `blockTimeout`, a timeout of 0 disables the associated check.
+//
+// Detection is done per process run, `onStall` is called at most once for a given run and the timers are reset each time
+// the process is started. It must be called before the process is started.
+func (s *Superviser) EnableStallDetection(outputTimeout, blockTimeout time.Duration, onStall func(event *nodeManager.StallEvent)) {
+	if outputTimeout <= 0 && blockTimeout <= 0 {
+		return
+	}
+
+	s.Logger.Info("enabling node stall detection", zap.Duration("output_timeout", outputTimeout), zap.Duration("block_timeout", blockTimeout))
+	s.stallWatchdog = &stallWatchdog{
+		outputTimeout: outputTimeout,
+		blockTimeout:  blockTimeout,
+		onStall:       onStall,
+		lastLines:     logplugin.NewKeepLastLinesLogPlugin(stallLastLineCount, false),
+	}
+}
+
+func (w *stallWatchdog) reset(now time.Time) {
+	w.lastLineAt.Store(now.UnixNano())
+	w.lastBlockAt.Store(now.UnixNano())
+}
+
+func (w *stallWatchdog) observeLine(line string) {
+	now := time.Now().UnixNano()
+
+	w.lastLineAt.Store(now)
+	if strings.HasPrefix(line, "FIRE BLOCK") {
+		w.lastBlockAt.Store(now)
+	}
+
+	w.lastLinesLock.Lock()
+	w.lastLines.LogLine(line)
+	w.lastLinesLock.Unlock()
+}
+
+func (w *stallWatchdog) lastLogLines() []string {
+	w.lastLinesLock.Lock()
+	defer w.lastLinesLock.Unlock()
+
+	return w.lastLines.LastLines()
+}
+
+// check returns the stall event if one of the timeout is exceeded at `now`, nil otherwise
+func (w *stallWatchdog) check(now time.Time) *nodeManager.StallEvent {
+	if w.outputTimeout > 0 {
+		if since := now.Sub(time.Unix(0, w.lastLineAt.Load())); since > w.outputTimeout {
+			return &nodeManager.StallEvent{Kind: nodeManager.StallKindOutput, Since: since, LastLogLines: w.lastLogLines()}
+		}
+	}
+
+	if w.blockTimeout > 0 {
+		if since := now.Sub(time.Unix(0, w.lastBlockAt.Load())); since > w.blockTimeout {
+			return &nodeManager.StallEvent{Kind: nodeManager.StallKindBlock, Since: since, LastLogLines: w.lastLogLines()}
+		}
+	}
+
+	return nil
+}
+
+func (w *stallWatchdog) checkInterval() time.Duration {
+	interval := w.outputTimeout
+	if interval <= 0 || (w.blockTimeout > 0 && w.blockTimeout < interval) {
+		interval = w.blockTimeout
+	}
+
+	// Checking a few times per timeout period is precise enough
+	return interval / 4
+}
+
+// watchStall runs until `cmd` completes or a stall is detected, whichever comes first.
+func (s *Superviser) watchStall(cmd *overseer.Cmd) {
+	watchdog := s.stallWatchdog
+
+	ticker := time.NewTicker(watchdog.checkInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-cmd.Done():
+			return
+		case <-s.Terminating():
+			return
+		case now := <-ticker.C:
+			event := watchdog.check(now)
+			if event == nil {
+				continue
+			}
+
+			s.Logger.Error(fmt.Sprintf("node process stalled, no %s seen for %s, last log lines:\n%s\n", stallKindDescription(event.Kind), event.Since.Round(time.Second), formatLogLines(event.LastLogLines)),
+				zap.String("kind", string(event.Kind)),
+				zap.Duration("since", event.Since),
+			)
+
+			if watchdog.onStall != nil {
+				watchdog.onStall(event)
+			}
+			return
+		}
+	}
+}
+
+func stallKindDescription(kind nodeManager.StallKind) string {
+	if kind == nodeManager.StallKindBlock {
+		return "block"
+	}
+
+	return "output line"
+}
@@ -55,6 +55,8 @@ type Superviser struct {
 	logPluginsLock sync.RWMutex
 
 	enableDeepMind bool
+
+	stallWatchdog *stallWatchdog
 }
 
 func New(logger *zap.Logger, binary string, arguments []string) *Superviser {
@@ -209,6 +211,11 @@ func (s *Superviser) Start(options ...nodeManager.StartOption) error {
 
 	go s.start(s.cmd)
 
+	if s.stallWatchdog != nil {
+		s.stallWatchdog.reset(time.Now())
+		go s.watchStall(s.cmd)
+	}
+
 	return nil
 }
 
@@ -393,6 +400,10 @@ func (s *Superviser) endLogPlugins() {
 }
 
 func (s *Superviser) processLogLine(line string) {
+	if s.stallWatchdog != nil {
+		s.stallWatchdog.observeLine(line)
+	}
+
 	s.logPluginsLock.Lock()
 	defer s.logPluginsLock.Unlock()
 
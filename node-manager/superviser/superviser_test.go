@@ -19,9 +19,11 @@ import (
 	"testing"
 	"time"
 
+	nodeManager "github.com/streamingfast/firehose-core/node-manager"
 	logplugin "github.com/streamingfast/firehose-core/node-manager/log_plugin"
 	"github.com/streamingfast/logging"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
 
@@ -107,6 +109,60 @@ func TestSuperviser_CapturesStdoutCorrectly(t *testing.T) {
 	assert.Equal(t, []string{"first", "second"}, lines)
 }
 
+func TestSuperviser_StallDetection(t *testing.T) {
+	tests := []struct {
+		name          string
+		script        string
+		outputTimeout time.Duration
+		blockTimeout  time.Duration
+		expectedKind  nodeManager.StallKind
+	}{
+		{"silent process", "echo first; sleep 5", 200 * time.Millisecond, 0, nodeManager.StallKindOutput},
+		{"no block", "echo first; " + infiniteScript, 0, 200 * time.Millisecond, nodeManager.StallKindBlock},
+		{"block then no block", "echo first; echo 'FIRE BLOCK 1'; " + infiniteScript, 500 * time.Millisecond, 200 * time.Millisecond, nodeManager.StallKindBlock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			superviser := testSuperviserSh(tt.script)
+			defer superviser.Stop()
+
+			stallChan := make(chan *nodeManager.StallEvent, 1)
+			superviser.EnableStallDetection(tt.outputTimeout, tt.blockTimeout, func(event *nodeManager.StallEvent) {
+				stallChan <- event
+			})
+
+			require.NoError(t, superviser.Start())
+
+			select {
+			case event := <-stallChan:
+				assert.Equal(t, tt.expectedKind, event.Kind)
+				assert.Contains(t, event.LastLogLines, "first")
+			case <-time.After(2 * time.Second):
+				t.Fatal("no stall detected before timeout")
+			}
+		})
+	}
+}
+
+func TestSuperviser_NoStallWhenActive(t *testing.T) {
+	superviser := testSuperviserSh(`while true; do echo "FIRE BLOCK 1"; sleep 0.05; done`)
+	defer superviser.Stop()
+
+	stallChan := make(chan *nodeManager.StallEvent, 1)
+	superviser.EnableStallDetection(200*time.Millisecond, 200*time.Millisecond, func(event *nodeManager.StallEvent) {
+		stallChan <- event
+	})
+
+	require.NoError(t, superviser.Start())
+
+	select {
+	case event := <-stallChan:
+		t.Fatalf("unexpected stall detected: %s", event.Kind)
+	case <-time.After(time.Second):
+	}
+}
+
 func testSuperviserBash(script string) *Superviser {
 	return New(zlog, "bash", []string{"-c", script})
 }
@@ -0,0 +1,16 @@
+#!/usr/bin/env bash
+
+set -e
+
+ROOT="$( cd "$( dirname "${BASH_SOURCE[0]}" )" && cd .. && pwd )"
+
+main() {
+  cd "$ROOT"
+
+  protoc -I proto \
+    --go_out=pb --go_opt=paths=source_relative \
+    --go-grpc_out=pb --go-grpc_opt=paths=source_relative,require_unimplemented_servers=false \
+    sf/firehose/merger/v1/merger.proto
+}
+
+main "$@"
@@ -0,0 +1,622 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        v4.24.2
+// source: sf/firehose/merger/v1/merger.proto
+
+package pbmerger
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StatusRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *StatusRequest) Reset() {
+	*x = StatusRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sf_firehose_merger_v1_merger_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusRequest) ProtoMessage() {}
+
+func (x *StatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sf_firehose_merger_v1_merger_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusRequest.ProtoReflect.Descriptor instead.
+func (*StatusRequest) Descriptor() ([]byte, []int) {
+	return file_sf_firehose_merger_v1_merger_proto_rawDescGZIP(), []int{0}
+}
+
+type StatusResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// bundler_base_block_num is the base block of the bundle being accumulated
+	BundlerBaseBlockNum uint64 `protobuf:"varint,1,opt,name=bundler_base_block_num,json=bundlerBaseBlockNum,proto3" json:"bundler_base_block_num,omitempty"`
+	// last_merged_bundle is unset until the merger knows about a merged bundle
+	LastMergedBundle *MergedBundle `protobuf:"bytes,2,opt,name=last_merged_bundle,json=lastMergedBundle,proto3" json:"last_merged_bundle,omitempty"`
+	// time_since_last_merge is unset until the merger merged a bundle
+	TimeSinceLastMerge *durationpb.Duration `protobuf:"bytes,3,opt,name=time_since_last_merge,json=timeSinceLastMerge,proto3" json:"time_since_last_merge,omitempty"`
+	// pending_one_block_files is the number of one-block files at or above the bundler base block, as of the last poll
+	PendingOneBlockFiles uint64 `protobuf:"varint,4,opt,name=pending_one_block_files,json=pendingOneBlockFiles,proto3" json:"pending_one_block_files,omitempty"`
+	// forked_blocks_awaiting_pruning is the number of forked blocks in the forked blocks store, unset when the merger
+	// is not configured with a forked blocks store
+	ForkedBlocksAwaitingPruning *uint64 `protobuf:"varint,5,opt,name=forked_blocks_awaiting_pruning,json=forkedBlocksAwaitingPruning,proto3,oneof" json:"forked_blocks_awaiting_pruning,omitempty"`
+	// holds_writer_lock tells if the merger holds the merger writer lock, unset when the writer lock is not enabled
+	HoldsWriterLock          *bool    `protobuf:"varint,6,opt,name=holds_writer_lock,json=holdsWriterLock,proto3,oneof" json:"holds_writer_lock,omitempty"`
+	LastOneBlockFilesPruning *Pruning `protobuf:"bytes,7,opt,name=last_one_block_files_pruning,json=lastOneBlockFilesPruning,proto3" json:"last_one_block_files_pruning,omitempty"`
+	LastForkedBlocksPruning  *Pruning `protobuf:"bytes,8,opt,name=last_forked_blocks_pruning,json=lastForkedBlocksPruning,proto3" json:"last_forked_blocks_pruning,omitempty"`
+}
+
+func (x *StatusResponse) Reset() {
+	*x = StatusResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sf_firehose_merger_v1_merger_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StatusResponse) ProtoMessage() {}
+
+func (x *StatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sf_firehose_merger_v1_merger_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StatusResponse.ProtoReflect.Descriptor instead.
+func (*StatusResponse) Descriptor() ([]byte, []int) {
+	return file_sf_firehose_merger_v1_merger_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *StatusResponse) GetBundlerBaseBlockNum() uint64 {
+	if x != nil {
+		return x.BundlerBaseBlockNum
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetLastMergedBundle() *MergedBundle {
+	if x != nil {
+		return x.LastMergedBundle
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetTimeSinceLastMerge() *durationpb.Duration {
+	if x != nil {
+		return x.TimeSinceLastMerge
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetPendingOneBlockFiles() uint64 {
+	if x != nil {
+		return x.PendingOneBlockFiles
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetForkedBlocksAwaitingPruning() uint64 {
+	if x != nil && x.ForkedBlocksAwaitingPruning != nil {
+		return *x.ForkedBlocksAwaitingPruning
+	}
+	return 0
+}
+
+func (x *StatusResponse) GetHoldsWriterLock() bool {
+	if x != nil && x.HoldsWriterLock != nil {
+		return *x.HoldsWriterLock
+	}
+	return false
+}
+
+func (x *StatusResponse) GetLastOneBlockFilesPruning() *Pruning {
+	if x != nil {
+		return x.LastOneBlockFilesPruning
+	}
+	return nil
+}
+
+func (x *StatusResponse) GetLastForkedBlocksPruning() *Pruning {
+	if x != nil {
+		return x.LastForkedBlocksPruning
+	}
+	return nil
+}
+
+type MergedBundle struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BaseBlockNum uint64 `protobuf:"varint,1,opt,name=base_block_num,json=baseBlockNum,proto3" json:"base_block_num,omitempty"`
+	// lib_num and lib_id are the last block of the bundle, the LIB the merger resumes from
+	LibNum uint64 `protobuf:"varint,2,opt,name=lib_num,json=libNum,proto3" json:"lib_num,omitempty"`
+	LibId  string `protobuf:"bytes,3,opt,name=lib_id,json=libId,proto3" json:"lib_id,omitempty"`
+	// merged_at is unset when the bundle was merged before the merger started
+	MergedAt *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=merged_at,json=mergedAt,proto3" json:"merged_at,omitempty"`
+}
+
+func (x *MergedBundle) Reset() {
+	*x = MergedBundle{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sf_firehose_merger_v1_merger_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MergedBundle) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MergedBundle) ProtoMessage() {}
+
+func (x *MergedBundle) ProtoReflect() protoreflect.Message {
+	mi := &file_sf_firehose_merger_v1_merger_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MergedBundle.ProtoReflect.Descriptor instead.
+func (*MergedBundle) Descriptor() ([]byte, []int) {
+	return file_sf_firehose_merger_v1_merger_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *MergedBundle) GetBaseBlockNum() uint64 {
+	if x != nil {
+		return x.BaseBlockNum
+	}
+	return 0
+}
+
+func (x *MergedBundle) GetLibNum() uint64 {
+	if x != nil {
+		return x.LibNum
+	}
+	return 0
+}
+
+func (x *MergedBundle) GetLibId() string {
+	if x != nil {
+		return x.LibId
+	}
+	return ""
+}
+
+func (x *MergedBundle) GetMergedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.MergedAt
+	}
+	return nil
+}
+
+type Pruning struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	At *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=at,proto3" json:"at,omitempty"`
+	// target is the block below which files were pruned
+	Target uint64 `protobuf:"varint,2,opt,name=target,proto3" json:"target,omitempty"`
+	// deleted_files is the number of files scheduled for deletion, unset for forked blocks
+	DeletedFiles *uint64 `protobuf:"varint,3,opt,name=deleted_files,json=deletedFiles,proto3,oneof" json:"deleted_files,omitempty"`
+}
+
+func (x *Pruning) Reset() {
+	*x = Pruning{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sf_firehose_merger_v1_merger_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Pruning) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pruning) ProtoMessage() {}
+
+func (x *Pruning) ProtoReflect() protoreflect.Message {
+	mi := &file_sf_firehose_merger_v1_merger_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pruning.ProtoReflect.Descriptor instead.
+func (*Pruning) Descriptor() ([]byte, []int) {
+	return file_sf_firehose_merger_v1_merger_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Pruning) GetAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+func (x *Pruning) GetTarget() uint64 {
+	if x != nil {
+		return x.Target
+	}
+	return 0
+}
+
+func (x *Pruning) GetDeletedFiles() uint64 {
+	if x != nil && x.DeletedFiles != nil {
+		return *x.DeletedFiles
+	}
+	return 0
+}
+
+type ForcePruneRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ForcePruneRequest) Reset() {
+	*x = ForcePruneRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sf_firehose_merger_v1_merger_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ForcePruneRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForcePruneRequest) ProtoMessage() {}
+
+func (x *ForcePruneRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_sf_firehose_merger_v1_merger_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForcePruneRequest.ProtoReflect.Descriptor instead.
+func (*ForcePruneRequest) Descriptor() ([]byte, []int) {
+	return file_sf_firehose_merger_v1_merger_proto_rawDescGZIP(), []int{4}
+}
+
+type ForcePruneResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ForcePruneResponse) Reset() {
+	*x = ForcePruneResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_sf_firehose_merger_v1_merger_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ForcePruneResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForcePruneResponse) ProtoMessage() {}
+
+func (x *ForcePruneResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_sf_firehose_merger_v1_merger_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForcePruneResponse.ProtoReflect.Descriptor instead.
+func (*ForcePruneResponse) Descriptor() ([]byte, []int) {
+	return file_sf_firehose_merger_v1_merger_proto_rawDescGZIP(), []int{5}
+}
+
+var File_sf_firehose_merger_v1_merger_proto protoreflect.FileDescriptor
+
+var file_sf_firehose_merger_v1_merger_proto_rawDesc = []byte{
+	0x0a, 0x22, 0x73, 0x66, 0x2f, 0x66, 0x69, 0x72, 0x65, 0x68, 0x6f, 0x73, 0x65, 0x2f, 0x6d, 0x65,
+	0x72, 0x67, 0x65, 0x72, 0x2f, 0x76, 0x31, 0x2f, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x72, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x15, 0x73, 0x66, 0x2e, 0x66, 0x69, 0x72, 0x65, 0x68, 0x6f, 0x73,
+	0x65, 0x2e, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x1a, 0x1e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x75, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1f, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x0f, 0x0a, 0x0d,
+	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x8e, 0x05,
+	0x0a, 0x0e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x33, 0x0a, 0x16, 0x62, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x72, 0x5f, 0x62, 0x61, 0x73, 0x65,
+	0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x6e, 0x75, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x13, 0x62, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x72, 0x42, 0x61, 0x73, 0x65, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x4e, 0x75, 0x6d, 0x12, 0x51, 0x0a, 0x12, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x6d, 0x65,
+	0x72, 0x67, 0x65, 0x64, 0x5f, 0x62, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x23, 0x2e, 0x73, 0x66, 0x2e, 0x66, 0x69, 0x72, 0x65, 0x68, 0x6f, 0x73, 0x65, 0x2e,
+	0x6d, 0x65, 0x72, 0x67, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x4d, 0x65, 0x72, 0x67, 0x65, 0x64,
+	0x42, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x52, 0x10, 0x6c, 0x61, 0x73, 0x74, 0x4d, 0x65, 0x72, 0x67,
+	0x65, 0x64, 0x42, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x12, 0x4c, 0x0a, 0x15, 0x74, 0x69, 0x6d, 0x65,
+	0x5f, 0x73, 0x69, 0x6e, 0x63, 0x65, 0x5f, 0x6c, 0x61, 0x73, 0x74, 0x5f, 0x6d, 0x65, 0x72, 0x67,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x52, 0x12, 0x74, 0x69, 0x6d, 0x65, 0x53, 0x69, 0x6e, 0x63, 0x65, 0x4c, 0x61, 0x73,
+	0x74, 0x4d, 0x65, 0x72, 0x67, 0x65, 0x12, 0x35, 0x0a, 0x17, 0x70, 0x65, 0x6e, 0x64, 0x69, 0x6e,
+	0x67, 0x5f, 0x6f, 0x6e, 0x65, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x66, 0x69, 0x6c, 0x65,
+	0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x14, 0x70, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67,
+	0x4f, 0x6e, 0x65, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x48, 0x0a,
+	0x1e, 0x66, 0x6f, 0x72, 0x6b, 0x65, 0x64, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x5f, 0x61,
+	0x77, 0x61, 0x69, 0x74, 0x69, 0x6e, 0x67, 0x5f, 0x70, 0x72, 0x75, 0x6e, 0x69, 0x6e, 0x67, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x1b, 0x66, 0x6f, 0x72, 0x6b, 0x65, 0x64, 0x42,
+	0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x41, 0x77, 0x61, 0x69, 0x74, 0x69, 0x6e, 0x67, 0x50, 0x72, 0x75,
+	0x6e, 0x69, 0x6e, 0x67, 0x88, 0x01, 0x01, 0x12, 0x2f, 0x0a, 0x11, 0x68, 0x6f, 0x6c, 0x64, 0x73,
+	0x5f, 0x77, 0x72, 0x69, 0x74, 0x65, 0x72, 0x5f, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x08, 0x48, 0x01, 0x52, 0x0f, 0x68, 0x6f, 0x6c, 0x64, 0x73, 0x57, 0x72, 0x69, 0x74, 0x65,
+	0x72, 0x4c, 0x6f, 0x63, 0x6b, 0x88, 0x01, 0x01, 0x12, 0x5e, 0x0a, 0x1c, 0x6c, 0x61, 0x73, 0x74,
+	0x5f, 0x6f, 0x6e, 0x65, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x73,
+	0x5f, 0x70, 0x72, 0x75, 0x6e, 0x69, 0x6e, 0x67, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e,
+	0x2e, 0x73, 0x66, 0x2e, 0x66, 0x69, 0x72, 0x65, 0x68, 0x6f, 0x73, 0x65, 0x2e, 0x6d, 0x65, 0x72,
+	0x67, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x72, 0x75, 0x6e, 0x69, 0x6e, 0x67, 0x52, 0x18,
+	0x6c, 0x61, 0x73, 0x74, 0x4f, 0x6e, 0x65, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x46, 0x69, 0x6c, 0x65,
+	0x73, 0x50, 0x72, 0x75, 0x6e, 0x69, 0x6e, 0x67, 0x12, 0x5b, 0x0a, 0x1a, 0x6c, 0x61, 0x73, 0x74,
+	0x5f, 0x66, 0x6f, 0x72, 0x6b, 0x65, 0x64, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x5f, 0x70,
+	0x72, 0x75, 0x6e, 0x69, 0x6e, 0x67, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x73,
+	0x66, 0x2e, 0x66, 0x69, 0x72, 0x65, 0x68, 0x6f, 0x73, 0x65, 0x2e, 0x6d, 0x65, 0x72, 0x67, 0x65,
+	0x72, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x72, 0x75, 0x6e, 0x69, 0x6e, 0x67, 0x52, 0x17, 0x6c, 0x61,
+	0x73, 0x74, 0x46, 0x6f, 0x72, 0x6b, 0x65, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x50, 0x72,
+	0x75, 0x6e, 0x69, 0x6e, 0x67, 0x42, 0x21, 0x0a, 0x1f, 0x5f, 0x66, 0x6f, 0x72, 0x6b, 0x65, 0x64,
+	0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x73, 0x5f, 0x61, 0x77, 0x61, 0x69, 0x74, 0x69, 0x6e, 0x67,
+	0x5f, 0x70, 0x72, 0x75, 0x6e, 0x69, 0x6e, 0x67, 0x42, 0x14, 0x0a, 0x12, 0x5f, 0x68, 0x6f, 0x6c,
+	0x64, 0x73, 0x5f, 0x77, 0x72, 0x69, 0x74, 0x65, 0x72, 0x5f, 0x6c, 0x6f, 0x63, 0x6b, 0x22, 0x9d,
+	0x01, 0x0a, 0x0c, 0x4d, 0x65, 0x72, 0x67, 0x65, 0x64, 0x42, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x12,
+	0x24, 0x0a, 0x0e, 0x62, 0x61, 0x73, 0x65, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x6e, 0x75,
+	0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c, 0x62, 0x61, 0x73, 0x65, 0x42, 0x6c, 0x6f,
+	0x63, 0x6b, 0x4e, 0x75, 0x6d, 0x12, 0x17, 0x0a, 0x07, 0x6c, 0x69, 0x62, 0x5f, 0x6e, 0x75, 0x6d,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6c, 0x69, 0x62, 0x4e, 0x75, 0x6d, 0x12, 0x15,
+	0x0a, 0x06, 0x6c, 0x69, 0x62, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x6c, 0x69, 0x62, 0x49, 0x64, 0x12, 0x37, 0x0a, 0x09, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x64, 0x5f,
+	0x61, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c,
+	0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x52, 0x08, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x64, 0x41, 0x74, 0x22, 0x89,
+	0x01, 0x0a, 0x07, 0x50, 0x72, 0x75, 0x6e, 0x69, 0x6e, 0x67, 0x12, 0x2a, 0x0a, 0x02, 0x61, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x52, 0x02, 0x61, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x28,
+	0x0a, 0x0d, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x0c, 0x64, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x64,
+	0x46, 0x69, 0x6c, 0x65, 0x73, 0x88, 0x01, 0x01, 0x42, 0x10, 0x0a, 0x0e, 0x5f, 0x64, 0x65, 0x6c,
+	0x65, 0x74, 0x65, 0x64, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x22, 0x13, 0x0a, 0x11, 0x46, 0x6f,
+	0x72, 0x63, 0x65, 0x50, 0x72, 0x75, 0x6e, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22,
+	0x14, 0x0a, 0x12, 0x46, 0x6f, 0x72, 0x63, 0x65, 0x50, 0x72, 0x75, 0x6e, 0x65, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32, 0xc8, 0x01, 0x0a, 0x0c, 0x4d, 0x65, 0x72, 0x67, 0x65, 0x72,
+	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x55, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x12, 0x24, 0x2e, 0x73, 0x66, 0x2e, 0x66, 0x69, 0x72, 0x65, 0x68, 0x6f, 0x73, 0x65, 0x2e, 0x6d,
+	0x65, 0x72, 0x67, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x25, 0x2e, 0x73, 0x66, 0x2e, 0x66, 0x69, 0x72, 0x65,
+	0x68, 0x6f, 0x73, 0x65, 0x2e, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x53,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x61, 0x0a,
+	0x0a, 0x46, 0x6f, 0x72, 0x63, 0x65, 0x50, 0x72, 0x75, 0x6e, 0x65, 0x12, 0x28, 0x2e, 0x73, 0x66,
+	0x2e, 0x66, 0x69, 0x72, 0x65, 0x68, 0x6f, 0x73, 0x65, 0x2e, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x72,
+	0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6f, 0x72, 0x63, 0x65, 0x50, 0x72, 0x75, 0x6e, 0x65, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x29, 0x2e, 0x73, 0x66, 0x2e, 0x66, 0x69, 0x72, 0x65, 0x68,
+	0x6f, 0x73, 0x65, 0x2e, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x72, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x6f,
+	0x72, 0x63, 0x65, 0x50, 0x72, 0x75, 0x6e, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x42, 0x4a, 0x5a, 0x48, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x73,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x69, 0x6e, 0x67, 0x66, 0x61, 0x73, 0x74, 0x2f, 0x66, 0x69, 0x72,
+	0x65, 0x68, 0x6f, 0x73, 0x65, 0x2d, 0x63, 0x6f, 0x72, 0x65, 0x2f, 0x70, 0x62, 0x2f, 0x73, 0x66,
+	0x2f, 0x66, 0x69, 0x72, 0x65, 0x68, 0x6f, 0x73, 0x65, 0x2f, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x72,
+	0x2f, 0x76, 0x31, 0x3b, 0x70, 0x62, 0x6d, 0x65, 0x72, 0x67, 0x65, 0x72, 0x62, 0x06, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_sf_firehose_merger_v1_merger_proto_rawDescOnce sync.Once
+	file_sf_firehose_merger_v1_merger_proto_rawDescData = file_sf_firehose_merger_v1_merger_proto_rawDesc
+)
+
+func file_sf_firehose_merger_v1_merger_proto_rawDescGZIP() []byte {
+	file_sf_firehose_merger_v1_merger_proto_rawDescOnce.Do(func() {
+		file_sf_firehose_merger_v1_merger_proto_rawDescData = protoimpl.X.CompressGZIP(file_sf_firehose_merger_v1_merger_proto_rawDescData)
+	})
+	return file_sf_firehose_merger_v1_merger_proto_rawDescData
+}
+
+var file_sf_firehose_merger_v1_merger_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_sf_firehose_merger_v1_merger_proto_goTypes = []interface{}{
+	(*StatusRequest)(nil),         // 0: sf.firehose.merger.v1.StatusRequest
+	(*StatusResponse)(nil),        // 1: sf.firehose.merger.v1.StatusResponse
+	(*MergedBundle)(nil),          // 2: sf.firehose.merger.v1.MergedBundle
+	(*Pruning)(nil),               // 3: sf.firehose.merger.v1.Pruning
+	(*ForcePruneRequest)(nil),     // 4: sf.firehose.merger.v1.ForcePruneRequest
+	(*ForcePruneResponse)(nil),    // 5: sf.firehose.merger.v1.ForcePruneResponse
+	(*durationpb.Duration)(nil),   // 6: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil), // 7: google.protobuf.Timestamp
+}
+var file_sf_firehose_merger_v1_merger_proto_depIdxs = []int32{
+	2, // 0: sf.firehose.merger.v1.StatusResponse.last_merged_bundle:type_name -> sf.firehose.merger.v1.MergedBundle
+	6, // 1: sf.firehose.merger.v1.StatusResponse.time_since_last_merge:type_name -> google.protobuf.Duration
+	3, // 2: sf.firehose.merger.v1.StatusResponse.last_one_block_files_pruning:type_name -> sf.firehose.merger.v1.Pruning
+	3, // 3: sf.firehose.merger.v1.StatusResponse.last_forked_blocks_pruning:type_name -> sf.firehose.merger.v1.Pruning
+	7, // 4: sf.firehose.merger.v1.MergedBundle.merged_at:type_name -> google.protobuf.Timestamp
+	7, // 5: sf.firehose.merger.v1.Pruning.at:type_name -> google.protobuf.Timestamp
+	0, // 6: sf.firehose.merger.v1.MergerStatus.Status:input_type -> sf.firehose.merger.v1.StatusRequest
+	4, // 7: sf.firehose.merger.v1.MergerStatus.ForcePrune:input_type -> sf.firehose.merger.v1.ForcePruneRequest
+	1, // 8: sf.firehose.merger.v1.MergerStatus.Status:output_type -> sf.firehose.merger.v1.StatusResponse
+	5, // 9: sf.firehose.merger.v1.MergerStatus.ForcePrune:output_type -> sf.firehose.merger.v1.ForcePruneResponse
+	8, // [8:10] is the sub-list for method output_type
+	6, // [6:8] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_sf_firehose_merger_v1_merger_proto_init() }
+func file_sf_firehose_merger_v1_merger_proto_init() {
+	if File_sf_firehose_merger_v1_merger_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_sf_firehose_merger_v1_merger_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StatusRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sf_firehose_merger_v1_merger_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StatusResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sf_firehose_merger_v1_merger_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MergedBundle); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sf_firehose_merger_v1_merger_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Pruning); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sf_firehose_merger_v1_merger_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ForcePruneRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_sf_firehose_merger_v1_merger_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ForcePruneResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_sf_firehose_merger_v1_merger_proto_msgTypes[1].OneofWrappers = []interface{}{}
+	file_sf_firehose_merger_v1_merger_proto_msgTypes[3].OneofWrappers = []interface{}{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_sf_firehose_merger_v1_merger_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_sf_firehose_merger_v1_merger_proto_goTypes,
+		DependencyIndexes: file_sf_firehose_merger_v1_merger_proto_depIdxs,
+		MessageInfos:      file_sf_firehose_merger_v1_merger_proto_msgTypes,
+	}.Build()
+	File_sf_firehose_merger_v1_merger_proto = out.File
+	file_sf_firehose_merger_v1_merger_proto_rawDesc = nil
+	file_sf_firehose_merger_v1_merger_proto_goTypes = nil
+	file_sf_firehose_merger_v1_merger_proto_depIdxs = nil
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -53,15 +54,40 @@ func DefaultReaderNodeBootstrapDataURLFlagDescription() string {
 		Security note: The script is executed as the same user as the reader node process, so it has the same
 		permissions as the reader node process. You **are** responsible of ensuring the script you execute is safe.
 
-		If the bootstrap URL ends with 'tar.zst' or 'tar.zstd', the archive is read and extracted into the
-		'reader-node-data-dir' location. The archive is expected to contain the full content of the 'reader-node-data-dir'
-		and is expanded as is.
+		If the bootstrap URL ends with 'tar.zst', 'tar.zstd', 'tar.gz', 'tar.lz4' or 'tar', the archive is read and
+		extracted into the 'reader-node-data-dir' location. The archive is expected to contain the full content of the
+		'reader-node-data-dir' and is expanded as is. File permissions are preserved and symlinks are re-created as long
+		as they point within the 'reader-node-data-dir' location. The archive can be fetched from any 'dstore' supported
+		location (local path, 'file://', 'gs://', 's3://', 'az://') as well as from 'http://' and 'https://' URLs.
+
+		When 'reader-node-bootstrap-staging-dir' is set, the archive is first downloaded to this directory before being
+		extracted. If the download is interrupted, it's resumed from where it stopped (using range reads) instead of
+		restarting from zero, which is strongly recommended for large archives. The staged archive is deleted once
+		extracted successfully.
+
+		The archive integrity is verified if a SHA-256 checksum is provided, either directly in the URL fragment
+		in the form '<url>#sha256=<hex>' or through a sidecar file '<url>.sha256' next to the archive (in the
+		same format as 'sha256sum' output). If the checksum doesn't match, the extracted content is removed and
+		bootstrapping fails.
+
+		If the bootstrap URL is of the form 'latest+<store-url>', the snapshot archives (any of the archive format
+		above) found under '<store-url>' are listed and the most recent one whose block number is not above the
+		resolved start block ('reader-node-start-block-num' or the one inferred from the stores, no bound if it
+		resolves to 0) is extracted like above. The block number of a snapshot is parsed from its name, the longest
+		group of digits delimited by '-', '_' or '.' (e.g. 'mainnet-0012345600.tar.zst' or 'geth-1.13-snapshot-18000000.tar.zst',
+		the last one on ties). Alternatively, a
+		'manifest.json' file can be placed at '<store-url>' root listing the snapshots in the form
+		'{"snapshots":[{"name":"<file name>","block_num":<number>}]}'. The chosen snapshot is logged and recorded
+		in '{data-dir}/reader/bootstrap-snapshot.json'.
+
+		If the bootstrap URL is of the form 'genesis+json://<file-url>', the genesis file at '<file-url>' (any location
+		supported for archives above) is copied into 'reader-node-data-dir' as 'genesis.json'. Use the URL fragment
+		'#dest=<relative/path.json>' to change where the file is written relative to 'reader-node-data-dir'.
 
 		Security note: The archive must be found a trusted source. The archive is uncompressed using the same
-		privileges as the reader node process. The paths in the archive are not sanitized and are extracted as is
-		relative to the 'reader-node-data-dir' location. A security consideration here is that it can unpack
-		file in parent directories, so the archive you unpack must be trusted. You **are** responsible of ensuring
-		the script you execute is safe.
+		privileges as the reader node process. Entries whose path (or symlink target) would end up outside the
+		'reader-node-data-dir' location are rejected and make bootstrapping fail, but you **are** still responsible
+		of ensuring the archive you unpack is safe.
 	`)
 }
 
@@ -100,21 +126,54 @@ func DefaultReaderNodeBootstrapper(
 			return bootstrapper, nil
 		}
 
+		// The URL fragment is used to pass extra information (like the archive's checksum), so it must
+		// not be considered when inferring the bootstrapper to use
+		bootstrapDataPath, _, _ := strings.Cut(bootstrapDataURL, "#")
+
 		// Otherwise apply the default logic
 		switch {
-		case strings.HasSuffix(bootstrapDataURL, "tar.zst") || strings.HasSuffix(bootstrapDataURL, "tar.zstd"):
-			// There could be a mistmatch here if the user override `--datadir` manually, we live it for now
-			return NewTarballReaderNodeBootstrapper(bootstrapDataURL, nodeDataDir, logger), nil
-
 		case strings.HasPrefix(bootstrapDataURL, "bash://"):
 			return NewBashNodeReaderBootstrapper(cmd, bootstrapDataURL, resolver, resolvedNodeArguments, logger), nil
 
+		case strings.HasPrefix(bootstrapDataURL, genesisJSONBootstrapScheme):
+			return NewGenesisReaderNodeBootstrapper(bootstrapDataURL, nodeDataDir, logger), nil
+
+		case strings.HasPrefix(bootstrapDataURL, latestSnapshotBootstrapScheme):
+			maxBlockNum, err := strconv.ParseUint(resolver("{start-block-num}"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parse resolved start block num: %w", err)
+			}
+
+			recordPath := filepath.Join(resolver("{data-dir}"), "reader", "bootstrap-snapshot.json")
+			return NewLatestSnapshotReaderNodeBootstrapper(bootstrapDataURL, nodeDataDir, maxBlockNum, recordPath, logger, tarballBootstrapperOptions(cmd, resolver)...), nil
+
+		case isTarballURL(bootstrapDataPath):
+			// There could be a mistmatch here if the user override `--datadir` manually, we live it for now
+			return NewTarballReaderNodeBootstrapper(bootstrapDataURL, nodeDataDir, logger, tarballBootstrapperOptions(cmd, resolver)...), nil
+
 		default:
-			return nil, fmt.Errorf("'reader-node-bootstrap-data-url' config should point to either an archive ending in '.tar.zstd' or a genesis file ending in '.json', not %s", bootstrapDataURL)
+			return nil, fmt.Errorf("'reader-node-bootstrap-data-url' config should point to either an archive ending in '.tar.zst', '.tar.zstd', '.tar.gz', '.tar.lz4' or '.tar', a 'latest+<store-url>' snapshot store, a 'bash://' script or a 'genesis+json://' genesis file, not %s", bootstrapDataURL)
 		}
 	}
 }
 
+func tarballBootstrapperOptions(cmd *cobra.Command, resolver ReaderNodeArgumentResolver) (opts []TarballBootstrapperOption) {
+	if stagingDir, err := sflags.GetString(cmd, "reader-node-bootstrap-staging-dir"); err == nil && stagingDir != "" {
+		opts = append(opts, TarballBootstrapperWithStagingDir(resolver(stagingDir)))
+	}
+
+	return
+}
+
+func isTarballURL(path string) bool {
+	_, ok := tarballCompression(path)
+	return ok
+}
+
+// bootstrapTempSuffix is the suffix of the files bootstrappers write to the node data directory before renaming them
+// once complete, they do not make the data directory bootstrapped
+const bootstrapTempSuffix = ".tmp"
+
 func isBootstrapped(dataDir string, logger *zap.Logger) bool {
 	var foundFile bool
 	err := filepath.Walk(dataDir,
@@ -122,7 +181,7 @@ func isBootstrapped(dataDir string, logger *zap.Logger) bool {
 			if err != nil {
 				return err
 			}
-			if info.IsDir() {
+			if info.IsDir() || strings.HasSuffix(info.Name(), bootstrapTempSuffix) {
 				return nil
 			}
 
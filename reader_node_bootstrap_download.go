@@ -0,0 +1,456 @@
+package firecore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/dustin/go-humanize"
+	"github.com/streamingfast/dstore"
+	"go.uber.org/zap"
+)
+
+// bootstrapDownloadMaxAttempts is the maximum number of consecutive download attempts that are
+// allowed to fail without making any progress before giving up on the download.
+var bootstrapDownloadMaxAttempts = 10
+
+var bootstrapDownloadRetryDelay = 5 * time.Second
+
+// errObjectChanged is returned when resuming the download of an object that changed since the download started
+var errObjectChanged = errors.New("object changed since the download started")
+
+// openObjectRange opens the object at `objectURL` and returns a reader starting at `offset` bytes
+// within the object. The content is returned as is, no decompression is performed.
+//
+// Range reads are natively supported for `http(s)://`, `gs://`, `s3://` and local files. For any
+// other scheme supported by `dstore`, the object is opened from the start and the first `offset`
+// bytes are discarded.
+//
+// When the object doesn't exist, the returned error wraps `dstore.ErrNotFound`.
+func openObjectRange(ctx context.Context, objectURL string, offset int64) (io.ReadCloser, error) {
+	reader, _, err := openObjectVersionRange(ctx, objectURL, offset, "")
+	return reader, err
+}
+
+// openObjectVersionRange is [openObjectRange] also returning the version of the object (ETag, generation or
+// size and modification time depending on the scheme, empty if unknown). When `version` is set, the returned
+// error is `errObjectChanged` if the object is not at this version anymore.
+func openObjectVersionRange(ctx context.Context, objectURL string, offset int64, version string) (io.ReadCloser, string, error) {
+	if !strings.Contains(objectURL, "://") {
+		return openLocalFileRange(objectURL, offset, version)
+	}
+
+	parsed, err := url.Parse(objectURL)
+	if err != nil {
+		return nil, "", fmt.Errorf("parse object url %q: %w", objectURL, err)
+	}
+
+	switch parsed.Scheme {
+	case "file":
+		return openLocalFileRange(parsed.Path, offset, version)
+
+	case "http", "https":
+		return openHTTPRange(ctx, objectURL, offset, version)
+
+	case "gs":
+		client, err := storage.NewClient(ctx)
+		if err != nil {
+			return nil, "", fmt.Errorf("new gs client: %w", err)
+		}
+
+		reader, err := client.Bucket(parsed.Host).Object(strings.TrimPrefix(parsed.Path, "/")).NewRangeReader(ctx, offset, -1)
+		if err != nil {
+			client.Close()
+			if errors.Is(err, storage.ErrObjectNotExist) {
+				return nil, "", fmt.Errorf("open %q: %w", objectURL, dstore.ErrNotFound)
+			}
+
+			return nil, "", err
+		}
+
+		readCloser := &onCloseReadCloser{ReadCloser: reader, onClose: client.Close}
+		return checkVersion(readCloser, strconv.FormatInt(reader.Attrs.Generation, 10), version)
+
+	case "s3":
+		config, bucket, key, err := dstore.ParseS3URL(parsed)
+		if err != nil {
+			return nil, "", err
+		}
+
+		sess, err := session.NewSession(config)
+		if err != nil {
+			return nil, "", fmt.Errorf("new aws session: %w", err)
+		}
+
+		output, err := s3.New(sess).GetObjectWithContext(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Range:  aws.String(fmt.Sprintf("bytes=%d-", offset)),
+		})
+		if err != nil {
+			var awsErr awserr.Error
+			if errors.As(err, &awsErr) && (awsErr.Code() == s3.ErrCodeNoSuchKey || awsErr.Code() == "NotFound") {
+				return nil, "", fmt.Errorf("open %q: %w", objectURL, dstore.ErrNotFound)
+			}
+
+			return nil, "", err
+		}
+
+		return checkVersion(output.Body, aws.StringValue(output.ETag), version)
+	}
+
+	reader, store, filename, err := dstore.OpenObject(ctx, objectURL)
+	if err != nil {
+		if errors.Is(err, dstore.ErrNotFound) {
+			return nil, "", fmt.Errorf("open %q: %w", objectURL, dstore.ErrNotFound)
+		}
+
+		return nil, "", err
+	}
+
+	currentVersion := ""
+	if attributes, err := store.ObjectAttributes(ctx, filename); err == nil {
+		currentVersion = fileVersion(attributes.Size, attributes.LastModified)
+	}
+
+	if reader, currentVersion, err = checkVersion(reader, currentVersion, version); err != nil {
+		return nil, "", err
+	}
+
+	reader, err = discardPrefix(reader, offset)
+	return reader, currentVersion, err
+}
+
+// checkVersion closes `reader` and returns `errObjectChanged` if `version` is set and differs from `currentVersion`
+func checkVersion(reader io.ReadCloser, currentVersion, version string) (io.ReadCloser, string, error) {
+	if version != "" && currentVersion != version {
+		reader.Close()
+		return nil, "", fmt.Errorf("version %q, expected %q: %w", currentVersion, version, errObjectChanged)
+	}
+
+	return reader, currentVersion, nil
+}
+
+func fileVersion(size int64, modTime time.Time) string {
+	return fmt.Sprintf("%d-%d", size, modTime.UnixNano())
+}
+
+func openLocalFileRange(path string, offset int64, version string) (io.ReadCloser, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", fmt.Errorf("open %q: %w", path, dstore.ErrNotFound)
+		}
+
+		return nil, "", err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, "", fmt.Errorf("stat %q: %w", path, err)
+	}
+
+	reader, currentVersion, err := checkVersion(file, fileVersion(info.Size(), info.ModTime()), version)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, "", fmt.Errorf("seek to %d: %w", offset, err)
+	}
+
+	return reader, currentVersion, nil
+}
+
+func openHTTPRange(ctx context.Context, objectURL string, offset int64, version string) (io.ReadCloser, string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, objectURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("new request: %w", err)
+	}
+
+	if offset > 0 {
+		request.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+
+		// The server ignores the range and sends the whole object if it changed
+		if version != "" && !strings.HasPrefix(version, "W/") {
+			request.Header.Set("If-Range", version)
+		}
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, "", err
+	}
+
+	etag := response.Header.Get("ETag")
+	switch response.StatusCode {
+	case http.StatusPartialContent:
+		return checkVersion(response.Body, etag, version)
+
+	case http.StatusOK:
+		reader, currentVersion, err := checkVersion(response.Body, etag, version)
+		if err != nil {
+			return nil, "", err
+		}
+
+		// Server doesn't support range requests, we must skip the bytes ourself
+		reader, err = discardPrefix(reader, offset)
+		return reader, currentVersion, err
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		// We are already at the end of the object
+		response.Body.Close()
+		return checkVersion(io.NopCloser(strings.NewReader("")), etag, version)
+
+	case http.StatusNotFound:
+		response.Body.Close()
+		return nil, "", fmt.Errorf("open %q: %w", objectURL, dstore.ErrNotFound)
+
+	default:
+		response.Body.Close()
+		return nil, "", fmt.Errorf("unexpected HTTP status %q while fetching %q", response.Status, objectURL)
+	}
+}
+
+type onCloseReadCloser struct {
+	io.ReadCloser
+	onClose func() error
+}
+
+func (r *onCloseReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	if closeErr := r.onClose(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+func discardPrefix(reader io.ReadCloser, offset int64) (io.ReadCloser, error) {
+	if offset <= 0 {
+		return reader, nil
+	}
+
+	if _, err := io.CopyN(io.Discard, reader, offset); err != nil {
+		reader.Close()
+		return nil, fmt.Errorf("skip first %d bytes: %w", offset, err)
+	}
+
+	return reader, nil
+}
+
+// readObject reads the full content of the object at `objectURL`, see [openObjectRange] for the
+// supported URLs.
+func readObject(ctx context.Context, objectURL string) ([]byte, error) {
+	reader, err := openObjectRange(ctx, objectURL, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// appendToURLPath appends `suffix` to the path element of `objectURL`, keeping query parameters
+// (used for example by `s3://` URLs) in place.
+func appendToURLPath(objectURL string, suffix string) string {
+	if !strings.Contains(objectURL, "://") {
+		return objectURL + suffix
+	}
+
+	parsed, err := url.Parse(objectURL)
+	if err != nil {
+		return objectURL + suffix
+	}
+
+	parsed.Path += suffix
+	return parsed.String()
+}
+
+// downloadResumable downloads the object at `objectURL` into `destination`. The content is first
+// written to `<destination>.partial` and renamed to `destination` once fully downloaded. If a partial
+// file already exists (from a previous attempt or a previous run), the download is resumed from where it
+// stopped using range reads instead of restarting from zero. The version of the object (see
+// [openObjectVersionRange]) is kept in `<destination>.partial.version`, the download restarting from zero
+// if the object changed since the partial file was started.
+//
+// If `destination` already exists, it's assumed to be complete and nothing is downloaded.
+func downloadResumable(ctx context.Context, objectURL string, destination string, logger *zap.Logger) error {
+	if _, err := os.Stat(destination); err == nil {
+		logger.Info("bootstrap archive already fully downloaded, re-using it", zap.String("path", destination))
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destination), os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create staging directory: %w", err)
+	}
+
+	partial := destination + ".partial"
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open staging file: %w", err)
+	}
+	defer file.Close()
+
+	versionFile := partial + ".version"
+	version := ""
+	if content, err := os.ReadFile(versionFile); err == nil {
+		version = string(content)
+	}
+
+	failedAttempts := 0
+	for {
+		info, err := file.Stat()
+		if err != nil {
+			return fmt.Errorf("unable to stat staging file: %w", err)
+		}
+
+		offset := info.Size()
+		if offset == 0 {
+			version = ""
+		}
+
+		written, err := downloadFrom(ctx, objectURL, offset, version, file, func(currentVersion string) error {
+			if offset > 0 && version == "" && currentVersion != "" {
+				// A partial file of unknown version cannot be safely completed
+				return errObjectChanged
+			}
+
+			if offset == 0 && currentVersion != "" {
+				version = currentVersion
+				if err := os.WriteFile(versionFile, []byte(currentVersion), 0644); err != nil {
+					return fmt.Errorf("unable to write staging file version: %w", err)
+				}
+			}
+			return nil
+		}, logger)
+		if errors.Is(err, errObjectChanged) {
+			logger.Warn("bootstrap archive changed since its download started, restarting download from zero", zap.String("downloaded", humanize.Bytes(uint64(offset))), zap.Error(err))
+			if err := file.Truncate(0); err != nil {
+				return fmt.Errorf("unable to truncate staging file: %w", err)
+			}
+			continue
+		}
+
+		if err == nil {
+			break
+		}
+
+		if errors.Is(err, dstore.ErrNotFound) || ctx.Err() != nil {
+			return err
+		}
+
+		if written > 0 {
+			failedAttempts = 0
+		}
+
+		failedAttempts++
+		if failedAttempts >= bootstrapDownloadMaxAttempts {
+			return fmt.Errorf("download failed after %d attempts without progress: %w", failedAttempts, err)
+		}
+
+		logger.Warn("bootstrap archive download interrupted, resuming",
+			zap.String("downloaded", humanize.Bytes(uint64(offset+written))),
+			zap.Int("failed_attempts", failedAttempts),
+			zap.Error(err),
+		)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(bootstrapDownloadRetryDelay):
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("unable to close staging file: %w", err)
+	}
+
+	if err := os.Rename(partial, destination); err != nil {
+		return fmt.Errorf("unable to rename staging file: %w", err)
+	}
+
+	os.Remove(versionFile)
+	return nil
+}
+
+// downloadFrom appends the object from `offset` to `out`, `onOpen` being called with the version of the object
+// before any byte is written
+func downloadFrom(ctx context.Context, objectURL string, offset int64, version string, out io.Writer, onOpen func(currentVersion string) error, logger *zap.Logger) (written int64, err error) {
+	reader, currentVersion, err := openObjectVersionRange(ctx, objectURL, offset, version)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	if err := onOpen(currentVersion); err != nil {
+		return 0, err
+	}
+
+	if offset > 0 {
+		logger.Info("resuming bootstrap archive download", zap.String("already_downloaded", humanize.Bytes(uint64(offset))))
+	} else {
+		logger.Info("downloading bootstrap archive", zap.String("url", objectURL))
+	}
+
+	counter := &countingReader{reader: reader}
+	stopProgress := logProgress(logger, "downloading bootstrap archive", counter)
+	defer stopProgress()
+
+	return io.Copy(out, counter)
+}
+
+// logProgress periodically logs the amount of bytes that went through `counter` until
+// the returned `stop` function is called.
+func logProgress(logger *zap.Logger, message string, counter *countingReader) (stop func()) {
+	done := make(chan struct{})
+	start := time.Now()
+
+	go func() {
+		ticker := time.NewTicker(bootstrapProgressInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				processed := counter.count.Load()
+				logger.Info(message,
+					zap.String("processed", humanize.Bytes(processed)),
+					zap.String("rate", humanize.Bytes(uint64(float64(processed)/time.Since(start).Seconds()))+"/s"),
+					zap.Duration("elapsed", time.Since(start).Round(time.Second)),
+				)
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+type countingReader struct {
+	reader io.Reader
+	count  atomic.Uint64
+}
+
+func (r *countingReader) Read(p []byte) (n int, err error) {
+	n, err = r.reader.Read(p)
+	r.count.Add(uint64(n))
+	return
+}
@@ -0,0 +1,115 @@
+package firecore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const genesisJSONBootstrapScheme = "genesis+json://"
+
+func NewGenesisReaderNodeBootstrapper(
+	url string,
+	dataDir string,
+	logger *zap.Logger,
+) *GenesisNodeBootstrapper {
+	return &GenesisNodeBootstrapper{
+		url:     url,
+		dataDir: dataDir,
+		logger:  logger,
+	}
+}
+
+// GenesisNodeBootstrapper copies a genesis file into the node data directory. The URL is of
+// the form `genesis+json://<file url>[#dest=<relative path>]` where `<file url>` is any URL
+// supported by the bootstrapper (local path, `file://`, `gs://`, `s3://`, `http(s)://`, etc.)
+// and `<relative path>` is the path, relative to the node data directory, where the genesis file
+// is written, defaults to `genesis.json`.
+type GenesisNodeBootstrapper struct {
+	url     string
+	dataDir string
+	logger  *zap.Logger
+}
+
+func (b *GenesisNodeBootstrapper) isBootstrapped() bool {
+	return isBootstrapped(b.dataDir, b.logger)
+}
+
+func (b *GenesisNodeBootstrapper) Bootstrap() error {
+	if b.isBootstrapped() {
+		return nil
+	}
+
+	b.logger.Info("bootstrapping native node chain data from genesis file", zap.String("bootstrap_data_url", b.url))
+
+	genesisURL, destination, err := b.parseURL()
+	if err != nil {
+		return err
+	}
+
+	reader, err := openObjectRange(context.Background(), genesisURL, 0)
+	if err != nil {
+		return fmt.Errorf("cannot get genesis file %q: %w", genesisURL, err)
+	}
+	defer reader.Close()
+
+	if err := os.MkdirAll(filepath.Dir(destination), os.ModePerm); err != nil {
+		return fmt.Errorf("unable to create genesis file directory: %w", err)
+	}
+
+	// Written to a temporary file first so that a failed copy doesn't leave the data directory in a bootstrapped state,
+	// temporary files being ignored by isBootstrapped
+	tempDestination := destination + bootstrapTempSuffix
+	file, err := os.Create(tempDestination)
+	if err != nil {
+		return fmt.Errorf("unable to create genesis file: %w", err)
+	}
+
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		os.Remove(tempDestination)
+		return fmt.Errorf("unable to copy genesis file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		os.Remove(tempDestination)
+		return fmt.Errorf("unable to close genesis file: %w", err)
+	}
+
+	if err := os.Rename(tempDestination, destination); err != nil {
+		os.Remove(tempDestination)
+		return fmt.Errorf("unable to rename genesis file: %w", err)
+	}
+
+	b.logger.Info("genesis file copied into node data directory", zap.String("path", destination))
+	return nil
+}
+
+func (b *GenesisNodeBootstrapper) parseURL() (genesisURL string, destination string, err error) {
+	genesisURL, fragment, _ := strings.Cut(strings.TrimPrefix(b.url, genesisJSONBootstrapScheme), "#")
+	if genesisURL == "" {
+		return "", "", fmt.Errorf("genesis bootstrap URL %q has no genesis file location", b.url)
+	}
+
+	values, err := url.ParseQuery(fragment)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid URL fragment %q: %w", fragment, err)
+	}
+
+	relative := values.Get("dest")
+	if relative == "" {
+		relative = "genesis.json"
+	}
+
+	if !filepath.IsLocal(relative) {
+		return "", "", fmt.Errorf("genesis destination %q must be a relative path within the node data directory", relative)
+	}
+
+	return genesisURL, filepath.Join(b.dataDir, relative), nil
+}
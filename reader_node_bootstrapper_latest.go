@@ -0,0 +1,230 @@
+package firecore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/streamingfast/dstore"
+	"go.uber.org/zap"
+)
+
+const latestSnapshotBootstrapScheme = "latest+"
+
+// snapshotManifestFilename is the optional manifest file listing the snapshots available under a
+// snapshot store prefix along with their block number, used instead of parsing snapshot names when present.
+const snapshotManifestFilename = "manifest.json"
+
+// NewLatestSnapshotReaderNodeBootstrapper creates a bootstrapper that discovers the most recent snapshot
+// archive available under the store URL of `url` (of the form `latest+<store-url>`) whose block number is not
+// above `maxBlockNum` (0 meaning no bound) and then extracts it into `dataDir` using a [TarballNodeBootstrapper]
+// configured with `opts`.
+//
+// The chosen snapshot is logged and recorded as JSON in `recordPath` once extracted successfully.
+func NewLatestSnapshotReaderNodeBootstrapper(
+	url string,
+	dataDir string,
+	maxBlockNum uint64,
+	recordPath string,
+	logger *zap.Logger,
+	opts ...TarballBootstrapperOption,
+) *LatestSnapshotNodeBootstrapper {
+	return &LatestSnapshotNodeBootstrapper{
+		url:         url,
+		dataDir:     dataDir,
+		maxBlockNum: maxBlockNum,
+		recordPath:  recordPath,
+		opts:        opts,
+		logger:      logger,
+	}
+}
+
+type LatestSnapshotNodeBootstrapper struct {
+	url         string
+	dataDir     string
+	maxBlockNum uint64
+	recordPath  string
+	opts        []TarballBootstrapperOption
+	logger      *zap.Logger
+}
+
+// SnapshotRecord is what is recorded on disk about the snapshot used to bootstrap the node.
+type SnapshotRecord struct {
+	URL            string    `json:"url"`
+	BlockNum       uint64    `json:"block_num"`
+	BootstrappedAt time.Time `json:"bootstrapped_at"`
+}
+
+type snapshotManifest struct {
+	Snapshots []struct {
+		Name     string `json:"name"`
+		BlockNum uint64 `json:"block_num"`
+	} `json:"snapshots"`
+}
+
+type snapshotCandidate struct {
+	name     string
+	blockNum uint64
+}
+
+func (b *LatestSnapshotNodeBootstrapper) isBootstrapped() bool {
+	return isBootstrapped(b.dataDir, b.logger)
+}
+
+func (b *LatestSnapshotNodeBootstrapper) Bootstrap() error {
+	if b.isBootstrapped() {
+		return nil
+	}
+
+	storeURL := strings.TrimPrefix(b.url, latestSnapshotBootstrapScheme)
+	b.logger.Info("discovering latest snapshot to bootstrap native node chain data", zap.String("store_url", storeURL), zap.Uint64("max_block_num", b.maxBlockNum))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	store, err := dstore.NewSimpleStore(strings.TrimSuffix(storeURL, "/"))
+	if err != nil {
+		return fmt.Errorf("unable to create snapshot store %q: %w", storeURL, err)
+	}
+
+	candidates, err := listSnapshotCandidates(ctx, store)
+	if err != nil {
+		return fmt.Errorf("list snapshots: %w", err)
+	}
+
+	chosen, found := pickLatestSnapshot(candidates, b.maxBlockNum)
+	if !found {
+		return fmt.Errorf("no snapshot found in %q at or below block #%d (out of %d snapshot(s))", storeURL, b.maxBlockNum, len(candidates))
+	}
+
+	snapshotURL := appendToURLPath(strings.TrimSuffix(storeURL, "/"), "/"+chosen.name)
+	b.logger.Info("latest snapshot selected for bootstrap", zap.String("snapshot_url", snapshotURL), zap.Uint64("block_num", chosen.blockNum), zap.Int("candidates", len(candidates)))
+
+	if err := NewTarballReaderNodeBootstrapper(snapshotURL, b.dataDir, b.logger, b.opts...).Bootstrap(); err != nil {
+		return fmt.Errorf("bootstrap from snapshot %q: %w", snapshotURL, err)
+	}
+
+	if b.recordPath != "" {
+		if err := writeSnapshotRecord(b.recordPath, SnapshotRecord{URL: snapshotURL, BlockNum: chosen.blockNum, BootstrappedAt: time.Now()}); err != nil {
+			// The node is bootstrapped at this point, failing to record is not critical
+			b.logger.Warn("unable to record bootstrap snapshot", zap.String("path", b.recordPath), zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// listSnapshotCandidates returns the snapshots found in `store` along with their block number. If a
+// manifest file is present at the root of the store, it's used as the source of truth, otherwise each
+// tar archive found in the store is considered, the block number being parsed from its name.
+func listSnapshotCandidates(ctx context.Context, store dstore.Store) ([]snapshotCandidate, error) {
+	manifestReader, err := store.OpenObject(ctx, snapshotManifestFilename)
+	if err == nil {
+		defer manifestReader.Close()
+
+		var manifest snapshotManifest
+		if err := json.NewDecoder(manifestReader).Decode(&manifest); err != nil {
+			return nil, fmt.Errorf("decode snapshot manifest: %w", err)
+		}
+
+		candidates := make([]snapshotCandidate, len(manifest.Snapshots))
+		for i, snapshot := range manifest.Snapshots {
+			candidates[i] = snapshotCandidate{name: snapshot.Name, blockNum: snapshot.BlockNum}
+		}
+
+		return candidates, nil
+	}
+
+	if !errors.Is(err, dstore.ErrNotFound) {
+		return nil, fmt.Errorf("open snapshot manifest: %w", err)
+	}
+
+	var candidates []snapshotCandidate
+	err = store.Walk(ctx, "", func(filename string) error {
+		if !isTarballURL(filename) {
+			return nil
+		}
+
+		blockNum, ok := parseSnapshotBlockNum(filename)
+		if !ok {
+			return nil
+		}
+
+		candidates = append(candidates, snapshotCandidate{name: filename, blockNum: blockNum})
+		return nil
+	})
+
+	return candidates, err
+}
+
+// parseSnapshotBlockNum extracts the block number from a snapshot file name, it's the longest (the last one
+// on ties) group of digits delimited by '-', '_' or '.' like in 'mainnet-0012345600.tar.zst',
+// '12345600_snapshot.tar.gz' or 'geth-1.13-snapshot-18000000.tar.zst', so that version numbers are skipped.
+func parseSnapshotBlockNum(filename string) (uint64, bool) {
+	fields := strings.FieldsFunc(path.Base(filename), func(r rune) bool {
+		return r == '-' || r == '_' || r == '.'
+	})
+
+	match := ""
+	for _, field := range fields {
+		if len(field) >= len(match) && isDigits(field) {
+			match = field
+		}
+	}
+	if match == "" {
+		return 0, false
+	}
+
+	blockNum, err := strconv.ParseUint(match, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return blockNum, true
+}
+
+func isDigits(in string) bool {
+	for _, r := range in {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// pickLatestSnapshot returns the candidate with the highest block number that is not above
+// `maxBlockNum`, a `maxBlockNum` of 0 meaning no bound.
+func pickLatestSnapshot(candidates []snapshotCandidate, maxBlockNum uint64) (out snapshotCandidate, found bool) {
+	for _, candidate := range candidates {
+		if maxBlockNum != 0 && candidate.blockNum > maxBlockNum {
+			continue
+		}
+
+		if !found || candidate.blockNum > out.blockNum {
+			out = candidate
+			found = true
+		}
+	}
+
+	return
+}
+
+func writeSnapshotRecord(recordPath string, record SnapshotRecord) error {
+	content, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(recordPath), os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(recordPath, content, 0644)
+}
@@ -0,0 +1,118 @@
+package firecore
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_parseSnapshotBlockNum(t *testing.T) {
+	tests := []struct {
+		filename      string
+		expected      uint64
+		expectedFound bool
+	}{
+		{"mainnet-0012345600.tar.zst", 12345600, true},
+		{"12345600_snapshot.tar.gz", 12345600, true},
+		{"prefix/snapshot.100.tar", 100, true},
+		{"v2-mainnet-200.tar.lz4", 200, true},
+		{"geth-1.13-snapshot-18000000.tar.zst", 18000000, true},
+		{"18000000-geth-1.13.tar.zst", 18000000, true},
+		{"snapshot-1-2.tar.zst", 2, true},
+		{"snapshot.tar.zst", 0, false},
+		{"abc123.tar.zst", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			actual, found := parseSnapshotBlockNum(tt.filename)
+			assert.Equal(t, tt.expectedFound, found)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func Test_pickLatestSnapshot(t *testing.T) {
+	candidates := []snapshotCandidate{
+		{"snap-100.tar.zst", 100},
+		{"snap-300.tar.zst", 300},
+		{"snap-200.tar.zst", 200},
+	}
+
+	tests := []struct {
+		name          string
+		maxBlockNum   uint64
+		expected      string
+		expectedFound bool
+	}{
+		{"no bound", 0, "snap-300.tar.zst", true},
+		{"bound exactly on snapshot", 200, "snap-200.tar.zst", true},
+		{"bound between snapshots", 299, "snap-200.tar.zst", true},
+		{"bound above all", 1000, "snap-300.tar.zst", true},
+		{"bound below all", 99, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, found := pickLatestSnapshot(candidates, tt.maxBlockNum)
+			assert.Equal(t, tt.expectedFound, found)
+			assert.Equal(t, tt.expected, actual.name)
+		})
+	}
+}
+
+func TestLatestSnapshotNodeBootstrapper_Bootstrap(t *testing.T) {
+	tests := []struct {
+		name        string
+		manifest    *snapshotManifest
+		maxBlockNum uint64
+		expected    string
+	}{
+		{"from names", nil, 250, "200"},
+		{"from names no bound", nil, 0, "300"},
+		{"from manifest", &snapshotManifest{Snapshots: []struct {
+			Name     string `json:"name"`
+			BlockNum uint64 `json:"block_num"`
+		}{{"snap-100.tar.zst", 150}, {"snap-200.tar.zst", 400}}}, 250, "100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			storeDir := filepath.Join(root, "snapshots")
+			dataDir := filepath.Join(root, "data")
+			recordPath := filepath.Join(root, "bootstrap-snapshot.json")
+			require.NoError(t, os.MkdirAll(storeDir, 0o755))
+
+			for _, blockNum := range []string{"100", "200", "300"} {
+				archive := newTestTarball(t, []tarEntry{{tar.Header{Name: "block", Typeflag: tar.TypeReg, Mode: 0o644}, blockNum}}, "zstd")
+				require.NoError(t, os.WriteFile(filepath.Join(storeDir, "snap-"+blockNum+".tar.zst"), archive, 0o644))
+			}
+
+			if tt.manifest != nil {
+				content, err := json.Marshal(tt.manifest)
+				require.NoError(t, err)
+				require.NoError(t, os.WriteFile(filepath.Join(storeDir, snapshotManifestFilename), content, 0o644))
+			}
+
+			bootstrapper := NewLatestSnapshotReaderNodeBootstrapper(latestSnapshotBootstrapScheme+storeDir, dataDir, tt.maxBlockNum, recordPath, zap.NewNop())
+			require.NoError(t, bootstrapper.Bootstrap())
+
+			content, err := os.ReadFile(filepath.Join(dataDir, "block"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, string(content))
+
+			var record SnapshotRecord
+			recordContent, err := os.ReadFile(recordPath)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(recordContent, &record))
+			assert.Equal(t, filepath.Join(storeDir, "snap-"+tt.expected+".tar.zst"), record.URL)
+		})
+	}
+}
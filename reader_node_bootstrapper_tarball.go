@@ -2,33 +2,92 @@ package firecore
 
 import (
 	"archive/tar"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/dustin/go-humanize"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
 	"github.com/streamingfast/dstore"
 	"go.uber.org/zap"
 )
 
+// bootstrapProgressInterval is the interval at which download and extraction progress is logged
+var bootstrapProgressInterval = 30 * time.Second
+
+type TarballBootstrapperOption func(b *TarballNodeBootstrapper)
+
+// TarballBootstrapperWithStagingDir makes the bootstrapper download the archive in `stagingDir` before
+// extracting it instead of streaming it directly. The download is resumed from where it stopped if it's
+// interrupted, which is strongly suggested for large archives.
+func TarballBootstrapperWithStagingDir(stagingDir string) TarballBootstrapperOption {
+	return func(b *TarballNodeBootstrapper) {
+		b.stagingDir = stagingDir
+	}
+}
+
+// NewTarballReaderNodeBootstrapper creates a bootstrapper extracting the tar archive found at `url` into
+// `dataDir`. The compression of the archive is inferred from the URL's suffix, see [tarballCompression]
+// for supported suffixes.
 func NewTarballReaderNodeBootstrapper(
 	url string,
 	dataDir string,
 	logger *zap.Logger,
+	opts ...TarballBootstrapperOption,
 ) *TarballNodeBootstrapper {
-	return &TarballNodeBootstrapper{
-		url:     url,
-		dataDir: dataDir,
-		logger:  logger,
+	archivePath, _, _ := strings.Cut(url, "#")
+	compression, _ := tarballCompression(archivePath)
+
+	b := &TarballNodeBootstrapper{
+		url:         url,
+		dataDir:     dataDir,
+		compression: compression,
+		logger:      logger,
+	}
+
+	for _, opt := range opts {
+		opt(b)
 	}
+
+	return b
 }
 
 type TarballNodeBootstrapper struct {
-	url     string
-	dataDir string
-	logger  *zap.Logger
+	url         string
+	dataDir     string
+	stagingDir  string
+	compression string
+	logger      *zap.Logger
+}
+
+// tarballCompression returns the compression algorithm used for the tar archive at `path` based on its suffix
+// and `false` if the path doesn't look like a supported tar archive. The returned compression is one of
+// `zstd`, `gzip`, `lz4` or the empty string for an uncompressed archive.
+func tarballCompression(path string) (compression string, ok bool) {
+	switch {
+	case strings.HasSuffix(path, "tar.zst") || strings.HasSuffix(path, "tar.zstd"):
+		return "zstd", true
+	case strings.HasSuffix(path, "tar.gz") || strings.HasSuffix(path, ".tgz"):
+		return "gzip", true
+	case strings.HasSuffix(path, "tar.lz4"):
+		return "lz4", true
+	case strings.HasSuffix(path, ".tar"):
+		return "", true
+	}
+
+	return "", false
 }
 
 func (b *TarballNodeBootstrapper) isBootstrapped() bool {
@@ -40,21 +99,234 @@ func (b *TarballNodeBootstrapper) Bootstrap() error {
 		return nil
 	}
 
-	b.logger.Info("bootstrapping native node chain data from pre-built archive", zap.String("bootstrap_data_url", b.url))
+	b.logger.Info("bootstrapping native node chain data from pre-built archive",
+		zap.String("bootstrap_data_url", b.url),
+		zap.String("compression", b.compression),
+		zap.String("staging_dir", b.stagingDir),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	archiveURL, expectedChecksum, err := b.resolveChecksum(ctx)
+	if err != nil {
+		return fmt.Errorf("resolve archive checksum: %w", err)
+	}
+
+	if b.stagingDir != "" {
+		return b.bootstrapFromStagedArchive(archiveURL, expectedChecksum)
+	}
+
+	return b.bootstrapFromStream(archiveURL, expectedChecksum)
+}
 
+func (b *TarballNodeBootstrapper) bootstrapFromStream(archiveURL string, expectedChecksum string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
 	defer cancel()
 
-	reader, _, _, err := dstore.OpenObject(ctx, b.url, dstore.Compression("zstd"))
+	reader, err := openObjectRange(ctx, archiveURL, 0)
 	if err != nil {
 		return fmt.Errorf("cannot get snapshot from gstore: %w", err)
 	}
 	defer reader.Close()
 
-	b.createChainData(reader)
+	hasher := sha256.New()
+	decompressed, err := b.decompress(io.TeeReader(reader, hasher))
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+
+	if err := b.createChainData(decompressed); err != nil {
+		b.cleanDataDir()
+		return fmt.Errorf("extract archive: %w", err)
+	}
+
+	if expectedChecksum != "" {
+		// Drain what is left (tar padding, trailing frames) so that the hasher has seen the full archive
+		if _, err := io.Copy(io.Discard, decompressed); err != nil {
+			b.cleanDataDir()
+			return fmt.Errorf("read archive remaining bytes: %w", err)
+		}
+
+		if _, err := io.Copy(hasher, reader); err != nil {
+			b.cleanDataDir()
+			return fmt.Errorf("read archive remaining bytes: %w", err)
+		}
+
+		if err := verifyChecksum(hasher, expectedChecksum); err != nil {
+			b.cleanDataDir()
+			return err
+		}
+	}
+
 	return nil
 }
 
+func (b *TarballNodeBootstrapper) bootstrapFromStagedArchive(archiveURL string, expectedChecksum string) error {
+	stagedPath := filepath.Join(b.stagingDir, archiveBaseName(archiveURL))
+
+	if err := downloadResumable(context.Background(), archiveURL, stagedPath, b.logger); err != nil {
+		return fmt.Errorf("download archive: %w", err)
+	}
+
+	file, err := os.Open(stagedPath)
+	if err != nil {
+		return fmt.Errorf("unable to open staged archive: %w", err)
+	}
+	defer file.Close()
+
+	// With a staged archive, we can verify the checksum before even starting the extraction
+	if expectedChecksum != "" {
+		b.logger.Info("verifying staged bootstrap archive checksum", zap.String("path", stagedPath))
+
+		hasher := sha256.New()
+		if _, err := io.Copy(hasher, file); err != nil {
+			return fmt.Errorf("unable to read staged archive: %w", err)
+		}
+
+		if err := verifyChecksum(hasher, expectedChecksum); err != nil {
+			// The staged archive is corrupted, we must download it again on next attempt
+			file.Close()
+			if err := os.Remove(stagedPath); err != nil {
+				b.logger.Warn("unable to remove corrupted staged archive", zap.String("path", stagedPath), zap.Error(err))
+			}
+
+			return err
+		}
+
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("unable to rewind staged archive: %w", err)
+		}
+	}
+
+	decompressed, err := b.decompress(file)
+	if err != nil {
+		return err
+	}
+	defer decompressed.Close()
+
+	if err := b.createChainData(decompressed); err != nil {
+		b.cleanDataDir()
+		return fmt.Errorf("extract archive: %w", err)
+	}
+
+	if err := os.Remove(stagedPath); err != nil {
+		b.logger.Warn("unable to remove staged archive", zap.String("path", stagedPath), zap.Error(err))
+	}
+
+	return nil
+}
+
+func (b *TarballNodeBootstrapper) decompress(reader io.Reader) (io.ReadCloser, error) {
+	switch b.compression {
+	case "zstd":
+		decoder, err := zstd.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create zstd reader: %w", err)
+		}
+
+		return decoder.IOReadCloser(), nil
+
+	case "gzip":
+		decoder, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create gzip reader: %w", err)
+		}
+
+		return decoder, nil
+
+	case "lz4":
+		return io.NopCloser(lz4.NewReader(reader)), nil
+
+	case "":
+		return io.NopCloser(reader), nil
+	}
+
+	return nil, fmt.Errorf("unsupported archive compression %q", b.compression)
+}
+
+// archiveBaseName returns the file name of the archive pointed to by `archiveURL`
+func archiveBaseName(archiveURL string) string {
+	if parsed, err := url.Parse(archiveURL); err == nil && parsed.Path != "" {
+		return path.Base(parsed.Path)
+	}
+
+	return filepath.Base(archiveURL)
+}
+
+// resolveChecksum returns the archive URL stripped of its checksum fragment as well as the expected
+// SHA-256 checksum of the archive, if any. The checksum is taken from the URL fragment in the form
+// `#sha256=<hex>` and if absent, from a sidecar file `<url>.sha256` when it exists.
+func (b *TarballNodeBootstrapper) resolveChecksum(ctx context.Context) (archiveURL string, checksum string, err error) {
+	archiveURL = b.url
+	if i := strings.LastIndex(archiveURL, "#"); i != -1 {
+		fragment := archiveURL[i+1:]
+		archiveURL = archiveURL[:i]
+
+		values, err := url.ParseQuery(fragment)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid URL fragment %q: %w", fragment, err)
+		}
+
+		if checksum = values.Get("sha256"); checksum != "" {
+			return archiveURL, strings.ToLower(checksum), nil
+		}
+	}
+
+	sidecarURL := appendToURLPath(archiveURL, ".sha256")
+	content, err := readObject(ctx, sidecarURL)
+	if err != nil {
+		if errors.Is(err, dstore.ErrNotFound) {
+			b.logger.Warn("no checksum provided for bootstrap archive, integrity will not be verified", zap.String("archive_url", archiveURL))
+			return archiveURL, "", nil
+		}
+
+		return "", "", fmt.Errorf("read checksum sidecar file: %w", err)
+	}
+
+	// The sidecar file follows 'sha256sum' output format, e.g. '<hex>  <filename>'
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return "", "", fmt.Errorf("checksum sidecar file %q is empty", sidecarURL)
+	}
+
+	b.logger.Info("found checksum sidecar file for bootstrap archive", zap.String("checksum", fields[0]))
+	return archiveURL, strings.ToLower(fields[0]), nil
+}
+
+// verifyChecksum compares the checksum computed by `hasher` against `expected`. An empty `expected`
+// skips the verification.
+func verifyChecksum(hasher hash.Hash, expected string) error {
+	if expected == "" {
+		return nil
+	}
+
+	actual := hex.EncodeToString(hasher.Sum(nil))
+	if actual != expected {
+		return fmt.Errorf("archive checksum mismatch, expected sha256 %s but got %s", expected, actual)
+	}
+
+	return nil
+}
+
+// cleanDataDir removes anything extracted so far so that a partially extracted archive
+// is not considered as bootstrapped on next start. The directory itself is kept as it
+// might be a mount point.
+func (b *TarballNodeBootstrapper) cleanDataDir() {
+	entries, err := os.ReadDir(b.dataDir)
+	if err != nil {
+		b.logger.Warn("unable to list node data directory after failed bootstrap", zap.String("data_dir", b.dataDir), zap.Error(err))
+		return
+	}
+
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(b.dataDir, entry.Name())); err != nil {
+			b.logger.Warn("unable to clean node data directory after failed bootstrap", zap.String("data_dir", b.dataDir), zap.Error(err))
+		}
+	}
+}
+
 func (b *TarballNodeBootstrapper) createChainData(reader io.Reader) error {
 	err := os.MkdirAll(b.dataDir, os.ModePerm)
 	if err != nil {
@@ -62,29 +334,80 @@ func (b *TarballNodeBootstrapper) createChainData(reader io.Reader) error {
 	}
 
 	b.logger.Info("extracting bootstrapping data into node data directory", zap.String("data_dir", b.dataDir))
-	tr := tar.NewReader(reader)
+
+	counter := &countingReader{reader: reader}
+	stopProgress := logProgress(b.logger, "extracting bootstrapping data", counter)
+	defer stopProgress()
+
+	// directory modes are applied once all the entries are extracted, a read-only directory would prevent
+	// extracting its children otherwise
+	var directories []extractedDirectory
+
+	tr := tar.NewReader(counter)
 	for {
 		header, err := tr.Next()
 		if err != nil {
 			if err == io.EOF {
-				return nil
+				break
 			}
 
 			return err
 		}
 
-		path := filepath.Join(b.dataDir, header.Name)
-		b.logger.Debug("about to write content of entry", zap.String("name", header.Name), zap.String("path", path), zap.Bool("is_dir", header.FileInfo().IsDir()))
-		if header.FileInfo().IsDir() {
-			err = os.MkdirAll(path, os.ModePerm)
-			if err != nil {
-				return fmt.Errorf("unable to create directory: %w", err)
-			}
+		if err := b.extractEntry(tr, header, &directories); err != nil {
+			return fmt.Errorf("entry %q: %w", header.Name, err)
+		}
+	}
 
-			continue
+	// children first, so that restricting a directory never prevents changing the mode of its sub-directories
+	for i := len(directories) - 1; i >= 0; i-- {
+		if err := os.Chmod(directories[i].path, directories[i].mode); err != nil {
+			return fmt.Errorf("directory %q mode: %w", directories[i].path, err)
+		}
+	}
+
+	b.logger.Info("bootstrapping data extracted", zap.String("extracted", humanize.Bytes(counter.count.Load())))
+	return nil
+}
+
+type extractedDirectory struct {
+	path string
+	mode os.FileMode
+}
+
+func (b *TarballNodeBootstrapper) extractEntry(tr *tar.Reader, header *tar.Header, directories *[]extractedDirectory) error {
+	path, err := b.safePath(header.Name)
+	if err != nil {
+		return err
+	}
+
+	mode := header.FileInfo().Mode().Perm()
+
+	b.logger.Debug("about to write content of entry", zap.String("name", header.Name), zap.String("path", path), zap.Stringer("mode", mode), zap.Uint8("type", header.Typeflag))
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err := removeSymlink(path); err != nil {
+			return err
 		}
 
-		file, err := os.Create(path)
+		if err := os.MkdirAll(path, os.ModePerm); err != nil {
+			return fmt.Errorf("unable to create directory: %w", err)
+		}
+
+		*directories = append(*directories, extractedDirectory{path: path, mode: mode})
+		return nil
+
+	case tar.TypeReg:
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return fmt.Errorf("unable to create parent directory: %w", err)
+		}
+
+		// a symlink extracted earlier under the same name must be replaced, not written through
+		if err := removeSymlink(path); err != nil {
+			return err
+		}
+
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
 		if err != nil {
 			return fmt.Errorf("unable to create file: %w", err)
 		}
@@ -93,6 +416,190 @@ func (b *TarballNodeBootstrapper) createChainData(reader io.Reader) error {
 			file.Close()
 			return err
 		}
-		file.Close()
+
+		if err := file.Close(); err != nil {
+			return err
+		}
+
+		// The file mode passed to OpenFile is subject to umask, so we enforce it
+		return os.Chmod(path, mode)
+
+	case tar.TypeSymlink:
+		if filepath.IsAbs(header.Linkname) {
+			return fmt.Errorf("symlink target %q is absolute", header.Linkname)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return fmt.Errorf("unable to create parent directory: %w", err)
+		}
+
+		// resolved on disk, a lexical check is fooled by symlinks extracted earlier ('a -> .' then 'b -> a/../escaped')
+		within, err := b.resolvesWithinDataDir(filepath.Dir(path), header.Linkname)
+		if err != nil {
+			return fmt.Errorf("resolve symlink target %q: %w", header.Linkname, err)
+		}
+		if !within {
+			return fmt.Errorf("symlink target %q escapes data directory", header.Linkname)
+		}
+
+		if err := removeSymlink(path); err != nil {
+			return err
+		}
+
+		return os.Symlink(header.Linkname, path)
+
+	case tar.TypeLink:
+		target, err := b.safePath(header.Linkname)
+		if err != nil {
+			return fmt.Errorf("hard link target: %w", err)
+		}
+
+		// a hard link to a symlink is a symlink whose relative target is resolved from another directory
+		if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("hard link target %q is a symlink", header.Linkname)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return fmt.Errorf("unable to create parent directory: %w", err)
+		}
+
+		if err := removeSymlink(path); err != nil {
+			return err
+		}
+
+		return os.Link(target, path)
+
+	default:
+		b.logger.Warn("skipping unsupported archive entry type", zap.String("name", header.Name), zap.Uint8("type", header.Typeflag))
+		return nil
+	}
+}
+
+// safePath resolves `name` relative to the data directory and ensures it cannot escape it, either
+// lexically (absolute paths, '..' elements) or through a symlink extracted earlier from the archive.
+func (b *TarballNodeBootstrapper) safePath(name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		// IsLocal rejects the root entry '.' as well as '', which are harmless
+		if cleaned := filepath.Clean(name); cleaned != "." {
+			return "", fmt.Errorf("path %q escapes data directory", name)
+		}
+	}
+
+	relative := filepath.Clean(name)
+	current := b.dataDir
+	for _, element := range strings.Split(filepath.Dir(relative), string(filepath.Separator)) {
+		if element == "." {
+			continue
+		}
+
+		current = filepath.Join(current, element)
+		info, err := os.Lstat(current)
+		if err != nil {
+			if os.IsNotExist(err) {
+				break
+			}
+
+			return "", err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("path %q traverses symlink %q", name, current)
+		}
+	}
+
+	return filepath.Join(b.dataDir, relative), nil
+}
+
+// resolvesWithinDataDir returns true if the symlink target `linkname` of a symlink in `dir` resolves within the data
+// directory, following the symlinks already on disk as the kernel does
+func (b *TarballNodeBootstrapper) resolvesWithinDataDir(dir, linkname string) (bool, error) {
+	dataDir, err := filepath.EvalSymlinks(b.dataDir)
+	if err != nil {
+		return false, err
+	}
+
+	resolvedDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false, err
+	}
+
+	target, err := resolveSymlinkTarget(resolvedDir, linkname, 0)
+	if err != nil {
+		return false, err
+	}
+
+	relative, err := filepath.Rel(dataDir, target)
+	if err != nil {
+		return false, nil
+	}
+
+	return filepath.IsLocal(relative) || relative == ".", nil
+}
+
+// maxSymlinkDepth bounds the symlinks followed resolving a symlink target, like the kernel's limit
+const maxSymlinkDepth = 40
+
+// resolveSymlinkTarget resolves `linkname` from the directory `dir` element by element, following the symlinks on disk
+// (dangling ones included), the elements that do not exist being resolved lexically
+func resolveSymlinkTarget(dir, linkname string, depth int) (string, error) {
+	if depth > maxSymlinkDepth {
+		return "", fmt.Errorf("too many levels of symlinks")
+	}
+
+	if filepath.IsAbs(linkname) {
+		dir = string(filepath.Separator)
+	}
+
+	current := dir
+	for _, element := range strings.Split(linkname, "/") {
+		switch element {
+		case "", ".":
+			continue
+		case "..":
+			current = filepath.Dir(current)
+			continue
+		}
+
+		current = filepath.Join(current, element)
+		info, err := os.Lstat(current)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(current)
+			if err != nil {
+				return "", err
+			}
+
+			if current, err = resolveSymlinkTarget(filepath.Dir(current), target, depth+1); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return current, nil
+}
+
+// removeSymlink removes `path` if it's a symlink, so that it's replaced instead of being followed
+func removeSymlink(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink == 0 {
+		return nil
 	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("unable to replace symlink %q: %w", path, err)
+	}
+	return nil
 }
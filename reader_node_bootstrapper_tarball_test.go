@@ -0,0 +1,384 @@
+package firecore
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type tarEntry struct {
+	header  tar.Header
+	content string
+}
+
+func TestTarballNodeBootstrapper_Bootstrap(t *testing.T) {
+	validEntries := []tarEntry{
+		{tar.Header{Name: "chain/", Typeflag: tar.TypeDir, Mode: 0o750}, ""},
+		{tar.Header{Name: "chain/db", Typeflag: tar.TypeReg, Mode: 0o600}, "database"},
+		{tar.Header{Name: "chain/run.sh", Typeflag: tar.TypeReg, Mode: 0o755}, "#!/bin/bash"},
+		{tar.Header{Name: "current", Typeflag: tar.TypeSymlink, Linkname: "chain/db"}, ""},
+	}
+
+	tests := []struct {
+		name           string
+		entries        []tarEntry
+		urlSuffix      func(checksum string) string
+		sidecar        func(checksum string) string
+		expectedErrMsg string
+	}{
+		{"valid archive without checksum", validEntries, nil, nil, ""},
+		{"valid archive with checksum in url", validEntries, func(c string) string { return "#sha256=" + c }, nil, ""},
+		{"valid archive with checksum in sidecar", validEntries, nil, func(c string) string { return c + "  archive.tar.zst\n" }, ""},
+		{"checksum mismatch in url", validEntries, func(c string) string { return "#sha256=deadbeef" }, nil, "checksum mismatch"},
+		{"checksum mismatch in sidecar", validEntries, nil, func(c string) string { return "deadbeef" }, "checksum mismatch"},
+		{
+			"path traversal",
+			[]tarEntry{{tar.Header{Name: "../escaped", Typeflag: tar.TypeReg, Mode: 0o644}, "bad"}},
+			nil, nil, "escapes data directory",
+		},
+		{
+			"absolute path",
+			[]tarEntry{{tar.Header{Name: "/tmp/escaped", Typeflag: tar.TypeReg, Mode: 0o644}, "bad"}},
+			nil, nil, "escapes data directory",
+		},
+		{
+			"symlink escaping",
+			[]tarEntry{{tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "../../etc"}, ""}},
+			nil, nil, "symlink target \"../../etc\" escapes data directory",
+		},
+		{
+			"symlink absolute",
+			[]tarEntry{{tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "/etc"}, ""}},
+			nil, nil, "symlink target \"/etc\" is absolute",
+		},
+		{
+			"write through symlink",
+			[]tarEntry{
+				{tar.Header{Name: "dir", Typeflag: tar.TypeSymlink, Linkname: "."}, ""},
+				{tar.Header{Name: "dir/file", Typeflag: tar.TypeReg, Mode: 0o644}, "bad"},
+			},
+			nil, nil, "traverses symlink",
+		},
+		{
+			"write through symlink resolved on disk",
+			[]tarEntry{
+				{tar.Header{Name: "a", Typeflag: tar.TypeSymlink, Linkname: "."}, ""},
+				{tar.Header{Name: "b", Typeflag: tar.TypeSymlink, Linkname: "a/../escaped"}, ""},
+				{tar.Header{Name: "b", Typeflag: tar.TypeReg, Mode: 0o644}, "pwned"},
+			},
+			nil, nil, "symlink target \"a/../escaped\" escapes data directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dataDir := filepath.Join(root, "data")
+
+			archive := newTestTarball(t, tt.entries, "zstd")
+			archivePath := filepath.Join(root, "archive.tar.zst")
+			require.NoError(t, os.WriteFile(archivePath, archive, 0o644))
+
+			checksum := sha256.Sum256(archive)
+			hexChecksum := hex.EncodeToString(checksum[:])
+
+			url := archivePath
+			if tt.urlSuffix != nil {
+				url += tt.urlSuffix(hexChecksum)
+			}
+
+			if tt.sidecar != nil {
+				require.NoError(t, os.WriteFile(archivePath+".sha256", []byte(tt.sidecar(hexChecksum)), 0o644))
+			}
+
+			err := NewTarballReaderNodeBootstrapper(url, dataDir, zap.NewNop()).Bootstrap()
+			if tt.expectedErrMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedErrMsg)
+				assert.False(t, isBootstrapped(dataDir, zap.NewNop()), "failed bootstrap should leave data dir empty")
+				assert.NoFileExists(t, filepath.Join(root, "escaped"))
+				return
+			}
+
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(filepath.Join(dataDir, "current"))
+			require.NoError(t, err)
+			assert.Equal(t, "database", string(content))
+
+			assertFileMode(t, filepath.Join(dataDir, "chain"), 0o750)
+			assertFileMode(t, filepath.Join(dataDir, "chain", "db"), 0o600)
+			assertFileMode(t, filepath.Join(dataDir, "chain", "run.sh"), 0o755)
+		})
+	}
+}
+
+func TestTarballNodeBootstrapper_ReadOnlyDirectory(t *testing.T) {
+	root := t.TempDir()
+	dataDir := filepath.Join(root, "data")
+	t.Cleanup(func() { os.Chmod(filepath.Join(dataDir, "chain"), 0o755) })
+
+	archive := newTestTarball(t, []tarEntry{
+		{tar.Header{Name: "chain/", Typeflag: tar.TypeDir, Mode: 0o555}, ""},
+		{tar.Header{Name: "chain/db", Typeflag: tar.TypeReg, Mode: 0o600}, "database"},
+	}, "zstd")
+	archivePath := filepath.Join(root, "archive.tar.zst")
+	require.NoError(t, os.WriteFile(archivePath, archive, 0o644))
+
+	require.NoError(t, NewTarballReaderNodeBootstrapper(archivePath, dataDir, zap.NewNop()).Bootstrap())
+
+	content, err := os.ReadFile(filepath.Join(dataDir, "chain", "db"))
+	require.NoError(t, err)
+	assert.Equal(t, "database", string(content))
+	assertFileMode(t, filepath.Join(dataDir, "chain"), 0o555)
+}
+
+func assertFileMode(t *testing.T, path string, expected os.FileMode) {
+	t.Helper()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, expected, info.Mode().Perm(), "mode of %q", path)
+}
+
+func TestTarballNodeBootstrapper_Formats(t *testing.T) {
+	entries := []tarEntry{
+		{tar.Header{Name: "chain/db", Typeflag: tar.TypeReg, Mode: 0o644}, "database"},
+	}
+
+	for _, suffix := range []string{"tar.zst", "tar.zstd", "tar.gz", "tar.lz4", "tar"} {
+		t.Run(suffix, func(t *testing.T) {
+			root := t.TempDir()
+			dataDir := filepath.Join(root, "data")
+
+			compression, ok := tarballCompression("archive." + suffix)
+			require.True(t, ok)
+
+			archivePath := filepath.Join(root, "archive."+suffix)
+			require.NoError(t, os.WriteFile(archivePath, newTestTarball(t, entries, compression), 0o644))
+
+			require.NoError(t, NewTarballReaderNodeBootstrapper(archivePath, dataDir, zap.NewNop()).Bootstrap())
+
+			content, err := os.ReadFile(filepath.Join(dataDir, "chain", "db"))
+			require.NoError(t, err)
+			assert.Equal(t, "database", string(content))
+		})
+	}
+}
+
+func TestTarballNodeBootstrapper_StagedResumableDownload(t *testing.T) {
+	defer func(delay time.Duration) { bootstrapDownloadRetryDelay = delay }(bootstrapDownloadRetryDelay)
+	bootstrapDownloadRetryDelay = 0
+
+	entries := []tarEntry{
+		{tar.Header{Name: "chain/db", Typeflag: tar.TypeReg, Mode: 0o644}, strings.Repeat("database", 1024)},
+	}
+	archive := newTestTarball(t, entries, "gzip")
+	checksum := sha256.Sum256(archive)
+
+	var requestedRanges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedRanges = append(requestedRanges, r.Header.Get("Range"))
+
+		if len(requestedRanges) == 1 {
+			// First request is cut in the middle, simulating a network failure
+			w.Header().Set("Content-Length", strconv.Itoa(len(archive)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(archive[:len(archive)/2])
+			return
+		}
+
+		http.ServeContent(w, r, "archive.tar.gz", time.Time{}, bytes.NewReader(archive))
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	dataDir := filepath.Join(root, "data")
+	stagingDir := filepath.Join(root, "staging")
+
+	url := server.URL + "/archive.tar.gz#sha256=" + hex.EncodeToString(checksum[:])
+	bootstrapper := NewTarballReaderNodeBootstrapper(url, dataDir, zap.NewNop(), TarballBootstrapperWithStagingDir(stagingDir))
+	require.NoError(t, bootstrapper.Bootstrap())
+
+	content, err := os.ReadFile(filepath.Join(dataDir, "chain", "db"))
+	require.NoError(t, err)
+	assert.Equal(t, entries[0].content, string(content))
+
+	require.Len(t, requestedRanges, 2)
+	assert.Equal(t, "", requestedRanges[0])
+	assert.Equal(t, fmt.Sprintf("bytes=%d-", len(archive)/2), requestedRanges[1])
+
+	assert.NoFileExists(t, filepath.Join(stagingDir, "archive.tar.gz"))
+	assert.NoFileExists(t, filepath.Join(stagingDir, "archive.tar.gz.partial"))
+}
+
+func TestTarballNodeBootstrapper_StagedDownloadRestartsWhenArchiveChanges(t *testing.T) {
+	defer func(delay time.Duration) { bootstrapDownloadRetryDelay = delay }(bootstrapDownloadRetryDelay)
+	bootstrapDownloadRetryDelay = 0
+
+	oldArchive := newTestTarball(t, []tarEntry{
+		{tar.Header{Name: "chain/db", Typeflag: tar.TypeReg, Mode: 0o644}, strings.Repeat("old database", 1024)},
+	}, "gzip")
+	newEntries := []tarEntry{
+		{tar.Header{Name: "chain/db", Typeflag: tar.TypeReg, Mode: 0o644}, strings.Repeat("new database", 1024)},
+	}
+	newArchive := newTestTarball(t, newEntries, "gzip")
+
+	var requestedRanges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".sha256") {
+			http.NotFound(w, r)
+			return
+		}
+		requestedRanges = append(requestedRanges, r.Header.Get("Range"))
+
+		if len(requestedRanges) == 1 {
+			// First request is cut in the middle, the archive being replaced before the download resumes
+			w.Header().Set("ETag", `"old"`)
+			w.Header().Set("Content-Length", strconv.Itoa(len(oldArchive)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(oldArchive[:len(oldArchive)/2])
+			return
+		}
+
+		w.Header().Set("ETag", `"new"`)
+		http.ServeContent(w, r, "archive.tar.gz", time.Time{}, bytes.NewReader(newArchive))
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	dataDir := filepath.Join(root, "data")
+	stagingDir := filepath.Join(root, "staging")
+
+	bootstrapper := NewTarballReaderNodeBootstrapper(server.URL+"/archive.tar.gz", dataDir, zap.NewNop(), TarballBootstrapperWithStagingDir(stagingDir))
+	require.NoError(t, bootstrapper.Bootstrap())
+
+	content, err := os.ReadFile(filepath.Join(dataDir, "chain", "db"))
+	require.NoError(t, err)
+	assert.Equal(t, newEntries[0].content, string(content))
+
+	// the resumed request sends the whole new archive as its ETag does not match, the download restarts from zero
+	require.Len(t, requestedRanges, 3)
+	assert.Equal(t, fmt.Sprintf("bytes=%d-", len(oldArchive)/2), requestedRanges[1])
+	assert.Equal(t, "", requestedRanges[2])
+	assert.NoFileExists(t, filepath.Join(stagingDir, "archive.tar.gz.partial.version"))
+}
+
+func TestGenesisNodeBootstrapper_Bootstrap(t *testing.T) {
+	tests := []struct {
+		name           string
+		fragment       string
+		expectedPath   string
+		expectedErrMsg string
+	}{
+		{"default destination", "", "genesis.json", ""},
+		{"custom destination", "#dest=config/genesis.json", "config/genesis.json", ""},
+		{"escaping destination", "#dest=../genesis.json", "", "must be a relative path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dataDir := filepath.Join(root, "data")
+
+			genesisPath := filepath.Join(root, "source.json")
+			require.NoError(t, os.WriteFile(genesisPath, []byte(`{"chainId":1}`), 0o644))
+
+			err := NewGenesisReaderNodeBootstrapper(genesisJSONBootstrapScheme+genesisPath+tt.fragment, dataDir, zap.NewNop()).Bootstrap()
+			if tt.expectedErrMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedErrMsg)
+				return
+			}
+
+			require.NoError(t, err)
+
+			content, err := os.ReadFile(filepath.Join(dataDir, tt.expectedPath))
+			require.NoError(t, err)
+			assert.Equal(t, `{"chainId":1}`, string(content))
+		})
+	}
+}
+
+func TestGenesisNodeBootstrapper_LeftoverTempFile(t *testing.T) {
+	root := t.TempDir()
+	dataDir := filepath.Join(root, "data")
+
+	// left by an interrupted bootstrap
+	require.NoError(t, os.MkdirAll(dataDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "genesis.json"+bootstrapTempSuffix), []byte(`{"chain`), 0o644))
+	assert.False(t, isBootstrapped(dataDir, zap.NewNop()))
+
+	genesisPath := filepath.Join(root, "source.json")
+	require.NoError(t, os.WriteFile(genesisPath, []byte(`{"chainId":1}`), 0o644))
+
+	require.NoError(t, NewGenesisReaderNodeBootstrapper(genesisJSONBootstrapScheme+genesisPath, dataDir, zap.NewNop()).Bootstrap())
+
+	content, err := os.ReadFile(filepath.Join(dataDir, "genesis.json"))
+	require.NoError(t, err)
+	assert.Equal(t, `{"chainId":1}`, string(content))
+	assert.NoFileExists(t, filepath.Join(dataDir, "genesis.json"+bootstrapTempSuffix))
+}
+
+func newTestTarball(t *testing.T, entries []tarEntry, compression string) []byte {
+	t.Helper()
+
+	buffer := bytes.NewBuffer(nil)
+
+	var compressor io.WriteCloser
+	switch compression {
+	case "zstd":
+		encoder, err := zstd.NewWriter(buffer)
+		require.NoError(t, err)
+		compressor = encoder
+	case "gzip":
+		compressor = gzip.NewWriter(buffer)
+	case "lz4":
+		compressor = lz4.NewWriter(buffer)
+	case "":
+		compressor = nopWriteCloser{buffer}
+	default:
+		require.FailNow(t, "unknown compression", compression)
+	}
+
+	tw := tar.NewWriter(compressor)
+	for _, entry := range entries {
+		header := entry.header
+		header.Size = int64(len(entry.content))
+
+		require.NoError(t, tw.WriteHeader(&header))
+		if entry.content != "" {
+			_, err := tw.Write([]byte(entry.content))
+			require.NoError(t, err)
+		}
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, compressor.Close())
+
+	return buffer.Bytes()
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -21,7 +21,7 @@ import (
 
 	"github.com/streamingfast/bstream"
 	"github.com/streamingfast/dmetrics"
-	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/encryptedstore"
 	"github.com/streamingfast/firehose-core/relayer"
 	"github.com/streamingfast/firehose-core/relayer/metrics"
 	"github.com/streamingfast/shutter"
@@ -66,7 +66,7 @@ func New(config *Config) *App {
 func (a *App) Run() error {
 	dmetrics.Register(metrics.MetricSet)
 
-	oneBlocksStore, err := dstore.NewDBinStore(a.config.OneBlocksURL)
+	oneBlocksStore, err := encryptedstore.NewDBinStore(a.config.OneBlocksURL)
 	if err != nil {
 		return fmt.Errorf("getting block store: %w", err)
 	}
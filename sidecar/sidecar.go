@@ -0,0 +1,376 @@
+// Package sidecar implements the per-bundle block index written next to merged blocks bundles. A sidecar lists
+// the blocks of a bundle along with their byte range in the (uncompressed) bundle, enabling single block
+// fetches that decode only the requested block and block ID lookups without reading the bundle. Bundles are a single
+// compressed stream: a single block fetch still reads and decompresses the bundle up to the block.
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/streamingfast/bstream"
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/streamingfast/dbin"
+	"github.com/streamingfast/dstore"
+)
+
+// Entry describes a block of a merged blocks bundle
+type Entry struct {
+	Num       uint64    `json:"num"`
+	ID        string    `json:"id"`
+	ParentNum uint64    `json:"parent_num"`
+	ParentID  string    `json:"parent_id"`
+	LibNum    uint64    `json:"lib_num"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// Offset is the position of the block message, length prefix included, in the uncompressed bundle
+	Offset uint64 `json:"offset"`
+	// Length is the size of the block message, length prefix included
+	Length uint64 `json:"length"`
+	// Checksum is the hex encoded SHA-256 of the block message, length prefix included, empty for sidecars written
+	// before checksums were recorded
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// Sidecar is the block index of a merged blocks bundle
+type Sidecar struct {
+	BaseBlockNum uint64 `json:"base_block_num"`
+	// HeaderLength is the size of the dbin header of the bundle
+	HeaderLength uint64 `json:"header_length"`
+	// Checksum is the hex encoded SHA-256 of the uncompressed bundle, empty for sidecars written before checksums
+	// were recorded
+	Checksum string   `json:"checksum,omitempty"`
+	Blocks   []*Entry `json:"blocks"`
+}
+
+// Find returns the entry of block `num`, matching `id` if not empty, nil if the bundle does not contain it
+func (s *Sidecar) Find(num uint64, id string) *Entry {
+	for _, entry := range s.Blocks {
+		if entry.Num == num && (id == "" || entry.ID == id) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// FindByID returns the entry of the block with `id`, nil if the bundle does not contain it
+func (s *Sidecar) FindByID(id string) *Entry {
+	for _, entry := range s.Blocks {
+		if entry.ID == id {
+			return entry
+		}
+	}
+	return nil
+}
+
+// Last returns the entry of the last block of the bundle, nil if the bundle is empty
+func (s *Sidecar) Last() *Entry {
+	if len(s.Blocks) == 0 {
+		return nil
+	}
+	return s.Blocks[len(s.Blocks)-1]
+}
+
+// NewStore returns the store of sidecars at `storeURL`, sidecars being named after the base block of their bundle
+func NewStore(storeURL string) (dstore.Store, error) {
+	store, err := dstore.NewStore(storeURL, "json.zst", "zstd", true)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create sidecar store at %q: %w", storeURL, err)
+	}
+	return store, nil
+}
+
+func Filename(baseBlockNum uint64) string {
+	return fmt.Sprintf("%010d", baseBlockNum)
+}
+
+// Build reads the uncompressed merged blocks bundle from `reader` and returns its sidecar, checksums included.
+// Blocks are decoded as block metadata, their payload is skipped.
+func Build(baseBlockNum uint64, reader io.Reader) (*Sidecar, error) {
+	bundleHash, blockHash := sha256.New(), sha256.New()
+	counter := &countingReader{Reader: io.TeeReader(reader, io.MultiWriter(bundleHash, blockHash))}
+	blockReader, err := bstream.NewDBinBlockReader(counter)
+	if err != nil {
+		return nil, fmt.Errorf("new block reader: %w", err)
+	}
+
+	out := &Sidecar{BaseBlockNum: baseBlockNum, HeaderLength: counter.count}
+	for {
+		offset := counter.count
+		blockHash.Reset()
+		meta, err := blockReader.ReadAsBlockMeta()
+		if err != nil {
+			if err == io.EOF {
+				out.Checksum = checksum(bundleHash)
+				return out, nil
+			}
+			return nil, fmt.Errorf("read block at offset %d: %w", offset, err)
+		}
+
+		entry := &Entry{
+			Num:       meta.Number,
+			ID:        meta.Id,
+			ParentNum: meta.ParentNum,
+			ParentID:  meta.ParentId,
+			LibNum:    meta.LibNum,
+			Offset:    offset,
+			Length:    counter.count - offset,
+			Checksum:  checksum(blockHash),
+		}
+		if meta.Timestamp != nil {
+			entry.Timestamp = meta.Timestamp.AsTime()
+		}
+
+		out.Blocks = append(out.Blocks, entry)
+	}
+}
+
+// ErrBuildFailed is returned by WriteBundle when the bundle was written but its sidecar could not be built
+var ErrBuildFailed = errors.New("sidecar build failed")
+
+// WriteBundle writes the uncompressed bundle read from `bundle` to the merged blocks store, building its sidecar from
+// the bytes being written. The sidecar itself is not written, see Write.
+func WriteBundle(ctx context.Context, mergedBlocksStore dstore.Store, baseBlockNum uint64, bundle io.Reader) (*Sidecar, error) {
+	pipeReader, pipeWriter := io.Pipe()
+
+	var out *Sidecar
+	var buildErr error
+	built := make(chan struct{})
+	go func() {
+		defer close(built)
+		out, buildErr = Build(baseBlockNum, pipeReader)
+
+		// drain the pipe so the bundle write never blocks on a failed build
+		io.Copy(io.Discard, pipeReader)
+	}()
+
+	err := mergedBlocksStore.WriteObject(ctx, Filename(baseBlockNum), io.TeeReader(bundle, pipeWriter))
+	pipeWriter.CloseWithError(err)
+	<-built
+
+	if err != nil {
+		return nil, err
+	}
+
+	if buildErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBuildFailed, buildErr)
+	}
+
+	return out, nil
+}
+
+// WriteBlocks encodes `blocks` as the merged blocks bundle at `baseBlockNum`, writing it to the merged blocks store
+// along with its sidecar when `sidecarStore` is not nil. When the sidecar cannot be built, the sidecar of a previous
+// write of the bundle, which would not match it, is deleted and an error wrapping ErrBuildFailed is returned, the bundle
+// itself being written.
+func WriteBlocks(ctx context.Context, mergedBlocksStore, sidecarStore dstore.Store, baseBlockNum uint64, blocks []*pbbstream.Block) error {
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		pipeWriter.CloseWithError(encodeBlocks(pipeWriter, blocks))
+	}()
+	// unblocks the encoder when the bundle write stops reading early
+	defer pipeReader.Close()
+
+	if sidecarStore == nil {
+		return mergedBlocksStore.WriteObject(ctx, Filename(baseBlockNum), pipeReader)
+	}
+
+	bundleSidecar, err := WriteBundle(ctx, mergedBlocksStore, baseBlockNum, pipeReader)
+	if err != nil {
+		if !errors.Is(err, ErrBuildFailed) {
+			return err
+		}
+
+		if deleteErr := Delete(ctx, sidecarStore, baseBlockNum); deleteErr != nil {
+			return fmt.Errorf("%v, deleting previous sidecar: %w", err, deleteErr)
+		}
+		return err
+	}
+
+	if err := Write(ctx, sidecarStore, bundleSidecar); err != nil {
+		if deleteErr := Delete(ctx, sidecarStore, baseBlockNum); deleteErr != nil {
+			return fmt.Errorf("%v, deleting previous sidecar: %w", err, deleteErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+func encodeBlocks(writer io.Writer, blocks []*pbbstream.Block) error {
+	blockWriter, err := bstream.NewDBinBlockWriter(writer)
+	if err != nil {
+		return fmt.Errorf("write block factory: %w", err)
+	}
+
+	for _, blk := range blocks {
+		if err := blockWriter.Write(blk); err != nil {
+			return fmt.Errorf("write block #%d: %w", blk.Number, err)
+		}
+	}
+
+	return nil
+}
+
+// BuildFromBundle reads the bundle at `baseBlockNum` from the merged blocks store and returns its sidecar
+func BuildFromBundle(ctx context.Context, mergedBlocksStore dstore.Store, baseBlockNum uint64) (*Sidecar, error) {
+	reader, err := mergedBlocksStore.OpenObject(ctx, Filename(baseBlockNum))
+	if err != nil {
+		return nil, fmt.Errorf("open bundle #%d: %w", baseBlockNum, err)
+	}
+	defer reader.Close()
+
+	return Build(baseBlockNum, reader)
+}
+
+// Write stores `sidecar`, replacing any existing one for the same bundle
+func Write(ctx context.Context, store dstore.Store, sidecar *Sidecar) error {
+	content, err := json.Marshal(sidecar)
+	if err != nil {
+		return fmt.Errorf("marshal sidecar: %w", err)
+	}
+
+	if err := store.WriteObject(ctx, Filename(sidecar.BaseBlockNum), bytes.NewReader(content)); err != nil {
+		return fmt.Errorf("write sidecar #%d: %w", sidecar.BaseBlockNum, err)
+	}
+	return nil
+}
+
+// Delete removes the sidecar of the bundle at `baseBlockNum`, if any
+func Delete(ctx context.Context, store dstore.Store, baseBlockNum uint64) error {
+	exists, err := store.FileExists(ctx, Filename(baseBlockNum))
+	if err != nil {
+		return fmt.Errorf("check sidecar #%d: %w", baseBlockNum, err)
+	}
+
+	if !exists {
+		return nil
+	}
+
+	if err := store.DeleteObject(ctx, Filename(baseBlockNum)); err != nil {
+		return fmt.Errorf("delete sidecar #%d: %w", baseBlockNum, err)
+	}
+	return nil
+}
+
+// Read returns the sidecar of the bundle at `baseBlockNum`, dstore.ErrNotFound if there is none
+func Read(ctx context.Context, store dstore.Store, baseBlockNum uint64) (*Sidecar, error) {
+	reader, err := store.OpenObject(ctx, Filename(baseBlockNum))
+	if err != nil {
+		return nil, fmt.Errorf("open sidecar #%d: %w", baseBlockNum, err)
+	}
+	defer reader.Close()
+
+	out := &Sidecar{}
+	if err := json.NewDecoder(reader).Decode(out); err != nil {
+		return nil, fmt.Errorf("decode sidecar #%d: %w", baseBlockNum, err)
+	}
+	return out, nil
+}
+
+// FetchBlock reads the block described by `entry` from the bundle of `sidecar`, decoding only this block and
+// verifying its checksum when the sidecar has one. The bundle is read and decompressed up to the end of the block, the
+// blocks before it being skipped without being decoded.
+func FetchBlock(ctx context.Context, mergedBlocksStore dstore.Store, sidecar *Sidecar, entry *Entry) (*pbbstream.Block, error) {
+	// the whole bundle is not read, only the block checksum can be verified
+	mergedBlocksStore = unverifiedStore(mergedBlocksStore)
+
+	reader, err := mergedBlocksStore.OpenObject(ctx, Filename(sidecar.BaseBlockNum))
+	if err != nil {
+		return nil, fmt.Errorf("open bundle #%d: %w", sidecar.BaseBlockNum, err)
+	}
+	defer reader.Close()
+
+	header, err := dbin.NewReader(reader).ReadHeader()
+	if err != nil {
+		return nil, fmt.Errorf("read bundle #%d header: %w", sidecar.BaseBlockNum, err)
+	}
+
+	if uint64(len(header.RawBytes)) != sidecar.HeaderLength || entry.Offset < sidecar.HeaderLength {
+		return nil, fmt.Errorf("bundle #%d does not match its sidecar", sidecar.BaseBlockNum)
+	}
+
+	if _, err := io.CopyN(io.Discard, reader, int64(entry.Offset-sidecar.HeaderLength)); err != nil {
+		return nil, fmt.Errorf("seek to block #%d in bundle #%d: %w", entry.Num, sidecar.BaseBlockNum, err)
+	}
+
+	blockHash := sha256.New()
+	blockBytes := io.TeeReader(io.LimitReader(reader, int64(entry.Length)), blockHash)
+
+	// re-prefixing the header lets the block reader handle legacy blocks like it does for full bundles
+	blockReader, err := bstream.NewDBinBlockReader(io.MultiReader(bytes.NewReader(header.RawBytes), blockBytes))
+	if err != nil {
+		return nil, fmt.Errorf("new block reader: %w", err)
+	}
+
+	block, err := blockReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read block #%d in bundle #%d: %w", entry.Num, sidecar.BaseBlockNum, err)
+	}
+
+	if entry.Checksum != "" && checksum(blockHash) != entry.Checksum {
+		return nil, fmt.Errorf("block #%d in bundle #%d: %w", entry.Num, sidecar.BaseBlockNum, ErrChecksumMismatch)
+	}
+
+	if block.Number != entry.Num || block.Id != entry.ID {
+		return nil, fmt.Errorf("bundle #%d contains block #%d (%s) at offset %d, expected #%d (%s) from its sidecar", sidecar.BaseBlockNum, block.Number, block.Id, entry.Offset, entry.Num, entry.ID)
+	}
+
+	return block, nil
+}
+
+// unverifiedStore returns the store wrapped by the VerifyingStore found unwrapping `store`, `store` itself if there is
+// none. The stores wrapping the VerifyingStore are skipped along with it.
+func unverifiedStore(store dstore.Store) dstore.Store {
+	for current := store; ; {
+		if verifying, ok := current.(*VerifyingStore); ok {
+			return verifying.Store
+		}
+
+		unwrapper, ok := current.(Unwrapper)
+		if !ok {
+			return store
+		}
+		current = unwrapper.Unwrap()
+	}
+}
+
+// FetchBlockFromMergedBlocksStore is the sidecar based equivalent of bstream.FetchBlockFromMergedBlocksStore,
+// returning the block `num` (with `id` if not empty) using the sidecar of its bundle. It returns dstore.ErrNotFound
+// when the sidecar does not exist or does not list the block.
+func FetchBlockFromMergedBlocksStore(ctx context.Context, num uint64, id string, bundleSize uint64, mergedBlocksStore, sidecarStore dstore.Store) (*pbbstream.Block, error) {
+	sidecar, err := Read(ctx, sidecarStore, num-num%bundleSize)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := sidecar.Find(num, id)
+	if entry == nil {
+		return nil, fmt.Errorf("block #%d not listed in sidecar #%d: %w", num, sidecar.BaseBlockNum, dstore.ErrNotFound)
+	}
+
+	return FetchBlock(ctx, mergedBlocksStore, sidecar, entry)
+}
+
+func checksum(h hash.Hash) string {
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type countingReader struct {
+	io.Reader
+	count uint64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.count += uint64(n)
+	return n, err
+}
@@ -0,0 +1,163 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/streamingfast/bstream"
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestBuild(t *testing.T) {
+	bundle := testBundle(t, 100, 103)
+
+	sidecar, err := Build(100, bytes.NewReader(bundle))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(100), sidecar.BaseBlockNum)
+	require.Len(t, sidecar.Blocks, 4)
+
+	sum := sha256.Sum256(bundle)
+	assert.Equal(t, hex.EncodeToString(sum[:]), sidecar.Checksum)
+
+	offset := sidecar.HeaderLength
+	for i, entry := range sidecar.Blocks {
+		num := uint64(100 + i)
+		assert.Equal(t, num, entry.Num)
+		assert.Equal(t, testBlockID(num), entry.ID)
+		assert.Equal(t, testBlockID(num-1), entry.ParentID)
+		assert.Equal(t, num-1, entry.ParentNum)
+		assert.Equal(t, testBlockTime(num), entry.Timestamp)
+		assert.Equal(t, offset, entry.Offset)
+
+		blockSum := sha256.Sum256(bundle[entry.Offset : entry.Offset+entry.Length])
+		assert.Equal(t, hex.EncodeToString(blockSum[:]), entry.Checksum)
+
+		offset += entry.Length
+	}
+	assert.Equal(t, uint64(len(bundle)), offset)
+
+	assert.Equal(t, uint64(102), sidecar.FindByID(testBlockID(102)).Num)
+	assert.Nil(t, sidecar.FindByID("unknown"))
+	assert.Equal(t, uint64(103), sidecar.Last().Num)
+}
+
+func TestFetchBlockFromMergedBlocksStore(t *testing.T) {
+	ctx := context.Background()
+
+	mergedBlocksStore, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, mergedBlocksStore.WriteObject(ctx, "0000000100", bytes.NewReader(testBundle(t, 100, 199))))
+
+	sidecarStore, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = FetchBlockFromMergedBlocksStore(ctx, 150, "", 100, mergedBlocksStore, sidecarStore)
+	assert.ErrorIs(t, err, dstore.ErrNotFound)
+
+	sidecar, err := BuildFromBundle(ctx, mergedBlocksStore, 100)
+	require.NoError(t, err)
+	require.NoError(t, Write(ctx, sidecarStore, sidecar))
+
+	block, err := FetchBlockFromMergedBlocksStore(ctx, 150, "", 100, mergedBlocksStore, sidecarStore)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(150), block.Number)
+	assert.Equal(t, testBlockID(150), block.Id)
+
+	payload := &wrapperspb.StringValue{}
+	require.NoError(t, block.Payload.UnmarshalTo(payload))
+	assert.Equal(t, "payload 150", payload.Value)
+
+	block, err = FetchBlockFromMergedBlocksStore(ctx, 199, testBlockID(199), 100, mergedBlocksStore, sidecarStore)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(199), block.Number)
+
+	_, err = FetchBlockFromMergedBlocksStore(ctx, 150, "unknown", 100, mergedBlocksStore, sidecarStore)
+	assert.ErrorIs(t, err, dstore.ErrNotFound)
+
+	sidecar.Find(150, "").Checksum = "corrupted"
+	require.NoError(t, Write(ctx, sidecarStore, sidecar))
+
+	_, err = FetchBlockFromMergedBlocksStore(ctx, 150, "", 100, mergedBlocksStore, sidecarStore)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+}
+
+// wrappingStore is a merged blocks store wrapper unknown to this package
+type wrappingStore struct {
+	dstore.Store
+}
+
+func (s *wrappingStore) Unwrap() dstore.Store {
+	return s.Store
+}
+
+func TestFetchBlock_SkipsBundleVerification(t *testing.T) {
+	ctx := context.Background()
+
+	mergedBlocksStore, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	sidecarStore, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	sidecar, err := WriteBundle(ctx, mergedBlocksStore, 100, bytes.NewReader(testBundle(t, 100, 199)))
+	require.NoError(t, err)
+
+	// the bundle does not match its checksum anymore, its blocks still match theirs
+	sidecar.Checksum = "corrupted"
+	require.NoError(t, Write(ctx, sidecarStore, sidecar))
+
+	store := &wrappingStore{Store: NewVerifyingStore(mergedBlocksStore, sidecarStore, VerifyModeWarnAndRefetch, zap.NewNop())}
+
+	_, err = store.OpenObject(ctx, "0000000100")
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	block, err := FetchBlockFromMergedBlocksStore(ctx, 150, "", 100, store, sidecarStore)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(150), block.Number)
+}
+
+func testBundle(t *testing.T, from, to uint64) []byte {
+	t.Helper()
+
+	out := new(bytes.Buffer)
+	writer, err := bstream.NewDBinBlockWriter(out)
+	require.NoError(t, err)
+
+	for num := from; num <= to; num++ {
+		payload, err := anypb.New(wrapperspb.String(fmt.Sprintf("payload %d", num)))
+		require.NoError(t, err)
+
+		require.NoError(t, writer.Write(&pbbstream.Block{
+			Number:    num,
+			Id:        testBlockID(num),
+			ParentNum: num - 1,
+			ParentId:  testBlockID(num - 1),
+			Timestamp: timestamppb.New(testBlockTime(num)),
+			LibNum:    num - 1,
+			Payload:   payload,
+		}))
+	}
+
+	return out.Bytes()
+}
+
+func testBlockID(num uint64) string {
+	return fmt.Sprintf("%016da", num)
+}
+
+func testBlockTime(num uint64) time.Time {
+	return time.Unix(1_700_000_000+int64(num), 0).UTC()
+}
@@ -0,0 +1,244 @@
+package sidecar
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/streamingfast/dstore"
+	"go.uber.org/zap"
+)
+
+// ErrChecksumMismatch is returned when a bundle or a block does not match the checksum recorded in its sidecar
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// VerifyMode decides what happens when a bundle read does not match the checksum of its sidecar
+type VerifyMode string
+
+const (
+	// VerifyModeFail fails the read
+	VerifyModeFail VerifyMode = "fail"
+	// VerifyModeWarnAndRefetch logs a warning and reads the bundle again, failing once all the reads mismatched
+	VerifyModeWarnAndRefetch VerifyMode = "warn-and-refetch"
+	// VerifyModeNone does not verify bundles
+	VerifyModeNone VerifyMode = "none"
+)
+
+// refetchAttempts is the number of reads of a bundle in VerifyModeWarnAndRefetch
+const refetchAttempts = 3
+
+func ParseVerifyMode(in string) (VerifyMode, error) {
+	switch mode := VerifyMode(in); mode {
+	case VerifyModeFail, VerifyModeWarnAndRefetch, VerifyModeNone:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid checksum verification mode %q, accepted values are %q, %q and %q", in, VerifyModeFail, VerifyModeWarnAndRefetch, VerifyModeNone)
+	}
+}
+
+// Verify checks the uncompressed bundle `content` against the checksum of `sidecar`, bundles whose sidecar has no
+// checksum always pass
+func Verify(sidecar *Sidecar, content []byte) error {
+	if sidecar.Checksum == "" {
+		return nil
+	}
+
+	sum := sha256.Sum256(content)
+	if hex.EncodeToString(sum[:]) != sidecar.Checksum {
+		return fmt.Errorf("bundle #%d: %w", sidecar.BaseBlockNum, ErrChecksumMismatch)
+	}
+	return nil
+}
+
+// Unwrapper is implemented by merged blocks stores wrapping a single other store, letting [FetchBlock] find a
+// [VerifyingStore] behind them
+type Unwrapper interface {
+	Unwrap() dstore.Store
+}
+
+// Evicter is implemented by merged blocks stores caching bundles, a [VerifyingStore] evicting from the stores it
+// wraps the bundles not matching their checksum so that they are downloaded again on the next read
+type Evicter interface {
+	Evict(name string)
+}
+
+// VerifyingStore is a merged blocks store verifying each bundle it reads against the checksum of its sidecar.
+// Bundles without a sidecar (or whose sidecar has no checksum) are read as is. Bundles are hashed while they are
+// read, a bundle not matching its checksum failing with ErrChecksumMismatch once it was read to the end: a reader
+// stopping before the end of a bundle is never told. A mismatching bundle is evicted from the caches (see [Evicter])
+// found unwrapping the wrapped store. In VerifyModeWarnAndRefetch, bundles are first copied to a
+// temporary file and verified, then read from it, so that a mismatching bundle can be read again.
+type VerifyingStore struct {
+	dstore.Store
+
+	sidecarStore dstore.Store
+	mode         VerifyMode
+	logger       *zap.Logger
+}
+
+func NewVerifyingStore(mergedBlocksStore, sidecarStore dstore.Store, mode VerifyMode, logger *zap.Logger) *VerifyingStore {
+	return &VerifyingStore{
+		Store:        mergedBlocksStore,
+		sidecarStore: sidecarStore,
+		mode:         mode,
+		logger:       logger,
+	}
+}
+
+func (s *VerifyingStore) Unwrap() dstore.Store {
+	return s.Store
+}
+
+func (s *VerifyingStore) OpenObject(ctx context.Context, name string) (io.ReadCloser, error) {
+	baseBlockNum, err := strconv.ParseUint(name, 10, 64)
+	if err != nil || s.mode == VerifyModeNone {
+		return s.Store.OpenObject(ctx, name)
+	}
+
+	sidecar, err := Read(ctx, s.sidecarStore, baseBlockNum)
+	if err != nil {
+		if !errors.Is(err, dstore.ErrNotFound) {
+			s.logger.Warn("unable to read sidecar, reading bundle without verifying it", zap.Uint64("base_block_num", baseBlockNum), zap.Error(err))
+		}
+		return s.Store.OpenObject(ctx, name)
+	}
+
+	if sidecar.Checksum == "" {
+		return s.Store.OpenObject(ctx, name)
+	}
+
+	if s.mode == VerifyModeWarnAndRefetch {
+		return s.openVerified(ctx, name, sidecar)
+	}
+
+	reader, err := s.Store.OpenObject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.verifyingReader(reader, name, sidecar), nil
+}
+
+func (s *VerifyingStore) verifyingReader(reader io.ReadCloser, name string, sidecar *Sidecar) *verifyingReader {
+	return &verifyingReader{
+		ReadCloser: reader,
+		sidecar:    sidecar,
+		hash:       sha256.New(),
+		onMismatch: func() { s.evict(name) },
+	}
+}
+
+// evict removes bundle `name` from the caches found unwrapping the wrapped store
+func (s *VerifyingStore) evict(name string) {
+	for current := s.Store; current != nil; {
+		if evicter, ok := current.(Evicter); ok {
+			s.logger.Info("evicting cached bundle not matching its sidecar checksum", zap.String("name", name))
+			evicter.Evict(name)
+		}
+
+		unwrapper, ok := current.(Unwrapper)
+		if !ok {
+			return
+		}
+		current = unwrapper.Unwrap()
+	}
+}
+
+// openVerified copies the bundle to a temporary file, reading it again while it does not match its checksum, up to
+// refetchAttempts times
+func (s *VerifyingStore) openVerified(ctx context.Context, name string, sidecar *Sidecar) (io.ReadCloser, error) {
+	for attempt := 1; ; attempt++ {
+		file, err := s.copyVerified(ctx, name, sidecar)
+		if err == nil {
+			return file, nil
+		}
+
+		if !errors.Is(err, ErrChecksumMismatch) || attempt >= refetchAttempts {
+			return nil, err
+		}
+
+		s.logger.Warn("merged blocks bundle does not match its sidecar checksum, reading it again", zap.Uint64("base_block_num", sidecar.BaseBlockNum), zap.Int("attempt", attempt))
+	}
+}
+
+func (s *VerifyingStore) copyVerified(ctx context.Context, name string, sidecar *Sidecar) (out io.ReadCloser, err error) {
+	reader, err := s.Store.OpenObject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	file, err := os.CreateTemp("", "merged-blocks-"+name+"-*")
+	if err != nil {
+		return nil, fmt.Errorf("create temporary bundle file: %w", err)
+	}
+
+	temporary := &temporaryFile{File: file}
+	defer func() {
+		if err != nil {
+			temporary.Close()
+		}
+	}()
+
+	if _, err := io.Copy(file, s.verifyingReader(reader, name, sidecar)); err != nil {
+		return nil, err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("rewind temporary bundle file: %w", err)
+	}
+
+	return temporary, nil
+}
+
+// verifyingReader hashes the bundle being read, returning ErrChecksumMismatch instead of io.EOF (after calling
+// `onMismatch`) when it does not match the checksum of its sidecar
+type verifyingReader struct {
+	io.ReadCloser
+
+	sidecar    *Sidecar
+	hash       hash.Hash
+	onMismatch func()
+}
+
+func (r *verifyingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+
+	if err == io.EOF && checksum(r.hash) != r.sidecar.Checksum {
+		r.onMismatch()
+		return n, fmt.Errorf("bundle #%d: %w", r.sidecar.BaseBlockNum, ErrChecksumMismatch)
+	}
+	return n, err
+}
+
+// temporaryFile is deleted once closed
+type temporaryFile struct {
+	*os.File
+}
+
+func (f *temporaryFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.File.Name())
+	return err
+}
+
+// Clone clones the wrapped store if it's clonable, the clone verifying bundles the same way
+func (s *VerifyingStore) Clone(ctx context.Context, opts ...dstore.Option) (dstore.Store, error) {
+	clonable, ok := s.Store.(dstore.Clonable)
+	if !ok {
+		return s, nil
+	}
+
+	store, err := clonable.Clone(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewVerifyingStore(store, s.sidecarStore, s.mode, s.logger), nil
+}
@@ -0,0 +1,157 @@
+package sidecar
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/blockscache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestVerifyingStore(t *testing.T) {
+	ctx := context.Background()
+	bundle := testBundle(t, 100, 199)
+	corrupted := append([]byte(nil), bundle...)
+	corrupted[len(corrupted)-1] ^= 0xff
+
+	sidecarStore, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	mergedBlocksStore, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	sidecar, err := WriteBundle(ctx, mergedBlocksStore, 100, bytes.NewReader(bundle))
+	require.NoError(t, err)
+	require.NoError(t, Write(ctx, sidecarStore, sidecar))
+
+	readAll := func(store dstore.Store, name string) ([]byte, error) {
+		reader, err := store.OpenObject(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	}
+
+	t.Run("matching bundle", func(t *testing.T) {
+		content, err := readAll(NewVerifyingStore(mergedBlocksStore, sidecarStore, VerifyModeFail, zap.NewNop()), "0000000100")
+		require.NoError(t, err)
+		assert.Equal(t, bundle, content)
+	})
+
+	t.Run("bundle without sidecar", func(t *testing.T) {
+		require.NoError(t, mergedBlocksStore.WriteObject(ctx, "0000000200", bytes.NewReader(corrupted)))
+
+		content, err := readAll(NewVerifyingStore(mergedBlocksStore, sidecarStore, VerifyModeFail, zap.NewNop()), "0000000200")
+		require.NoError(t, err)
+		assert.Equal(t, corrupted, content)
+	})
+
+	// serves the corrupted bundle on the first `corruptedReads` reads
+	flakyStore := func(corruptedReads int) dstore.Store {
+		store := dstore.NewMockStore(nil)
+		reads := 0
+		store.OpenObjectFunc = func(_ context.Context, _ string) (io.ReadCloser, error) {
+			reads++
+			if reads <= corruptedReads {
+				return io.NopCloser(bytes.NewReader(corrupted)), nil
+			}
+			return io.NopCloser(bytes.NewReader(bundle)), nil
+		}
+		return store
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		_, err := readAll(NewVerifyingStore(flakyStore(1), sidecarStore, VerifyModeFail, zap.NewNop()), "0000000100")
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+		// the bundle is streamed, the mismatch is only reported once it was read to the end
+		reader, err := NewVerifyingStore(flakyStore(1), sidecarStore, VerifyModeFail, zap.NewNop()).OpenObject(ctx, "0000000100")
+		require.NoError(t, err)
+		defer reader.Close()
+
+		start := make([]byte, 16)
+		_, err = io.ReadFull(reader, start)
+		require.NoError(t, err)
+		assert.Equal(t, corrupted[:16], start)
+	})
+
+	t.Run("warn and refetch", func(t *testing.T) {
+		content, err := readAll(NewVerifyingStore(flakyStore(2), sidecarStore, VerifyModeWarnAndRefetch, zap.NewNop()), "0000000100")
+		require.NoError(t, err)
+		assert.Equal(t, bundle, content)
+
+		_, err = readAll(NewVerifyingStore(flakyStore(refetchAttempts), sidecarStore, VerifyModeWarnAndRefetch, zap.NewNop()), "0000000100")
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		content, err := readAll(NewVerifyingStore(flakyStore(1), sidecarStore, VerifyModeNone, zap.NewNop()), "0000000100")
+		require.NoError(t, err)
+		assert.Equal(t, corrupted, content)
+	})
+}
+
+func TestVerifyingStore_EvictsCachedBundles(t *testing.T) {
+	ctx := context.Background()
+	bundle := testBundle(t, 100, 199)
+	corrupted := append([]byte(nil), bundle...)
+	corrupted[len(corrupted)-1] ^= 0xff
+
+	sidecarStore, err := NewStore(t.TempDir())
+	require.NoError(t, err)
+
+	sidecar, err := Build(100, bytes.NewReader(bundle))
+	require.NoError(t, err)
+	require.NoError(t, Write(ctx, sidecarStore, sidecar))
+
+	for _, mode := range []VerifyMode{VerifyModeFail, VerifyModeWarnAndRefetch} {
+		t.Run(string(mode), func(t *testing.T) {
+			// serves the corrupted bundle on the first read only
+			remoteStore := dstore.NewMockStore(nil)
+			reads := 0
+			remoteStore.OpenObjectFunc = func(_ context.Context, _ string) (io.ReadCloser, error) {
+				reads++
+				if reads == 1 {
+					return io.NopCloser(bytes.NewReader(corrupted)), nil
+				}
+				return io.NopCloser(bytes.NewReader(bundle)), nil
+			}
+
+			cacheStore, err := blockscache.NewStore(ctx, remoteStore, t.TempDir(), nil, 1<<20, 1<<20, zap.NewNop())
+			require.NoError(t, err)
+
+			store := NewVerifyingStore(cacheStore, sidecarStore, mode, zap.NewNop())
+
+			readAll := func() ([]byte, error) {
+				reader, err := store.OpenObject(ctx, "0000000100")
+				if err != nil {
+					return nil, err
+				}
+				defer reader.Close()
+				return io.ReadAll(reader)
+			}
+
+			if mode == VerifyModeFail {
+				_, err := readAll()
+				assert.ErrorIs(t, err, ErrChecksumMismatch)
+			}
+
+			// the corrupted bundle was evicted from the cache, it's downloaded again
+			content, err := readAll()
+			require.NoError(t, err)
+			assert.Equal(t, bundle, content)
+			assert.Equal(t, 2, reads)
+
+			content, err = readAll()
+			require.NoError(t, err)
+			assert.Equal(t, bundle, content)
+			assert.Equal(t, 2, reads, "served from the cache")
+		})
+	}
+}
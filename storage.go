@@ -8,7 +8,11 @@ import (
 	"strings"
 
 	"github.com/spf13/viper"
+	"github.com/streamingfast/bstream"
 	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/encryptedstore"
+	"github.com/streamingfast/firehose-core/sidecar"
+	"github.com/streamingfast/firehose-core/types"
 	"go.uber.org/zap"
 )
 
@@ -52,6 +56,41 @@ func GetCommonStoresURLs(dataDir string) (mergedBlocksStoreURL, oneBlocksStoreUR
 	return
 }
 
+// GetMergedBlocksSidecarStoreURL returns the URL of the merged blocks sidecar store, empty if sidecars are disabled
+func GetMergedBlocksSidecarStoreURL(dataDir string) string {
+	return MustReplaceDataDir(dataDir, viperExpandedEnvGetString("common-merged-blocks-sidecar-store-url"))
+}
+
+// GetForkedBlocksArchiveStoreURL returns the URL of the forked blocks archive store, empty if forked blocks are not
+// archived
+func GetForkedBlocksArchiveStoreURL(dataDir string) string {
+	return MustReplaceDataDir(dataDir, viperExpandedEnvGetString("common-forked-blocks-archive-store-url"))
+}
+
+// GetMergedBlocksStoreTierURLs returns the merged blocks store tiers read after the merged blocks store, see
+// [tieredstore.ParseTierURL]
+func GetMergedBlocksStoreTierURLs(dataDir string) (out []string) {
+	for _, tierURL := range viper.GetStringSlice("common-merged-blocks-store-tiers") {
+		out = append(out, MustReplaceDataDir(dataDir, tierURL))
+	}
+	return out
+}
+
+// GetBlocksCacheDir returns the local directory of the merged blocks disk cache, empty if the cache is disabled
+func GetBlocksCacheDir(dataDir string) (string, error) {
+	if !viper.GetBool("common-blocks-cache-enabled") {
+		return "", nil
+	}
+
+	cacheURL := MustReplaceDataDir(dataDir, viperExpandedEnvGetString("common-blocks-cache-dir"))
+	dir := strings.TrimPrefix(cacheURL, "file://")
+	if strings.Contains(dir, "://") {
+		return "", fmt.Errorf("blocks cache directory %q must be on local disk", cacheURL)
+	}
+
+	return dir, nil
+}
+
 func GetIndexStore(dataDir string) (indexStore dstore.Store, possibleIndexSizes []uint64, err error) {
 	indexStoreURL := MustReplaceDataDir(dataDir, viperExpandedEnvGetString("common-index-store-url"))
 
@@ -89,8 +128,94 @@ func GetIndexStore(dataDir string) (indexStore dstore.Store, possibleIndexSizes
 	return
 }
 
+// GetMergedBlocksBundleSize returns the bundle size of the merged blocks store, honoring the
+// `common-merged-blocks-bundle-size` flag, see [types.ResolveBundleSize].
+func GetMergedBlocksBundleSize(ctx context.Context, mergedBlocksStore dstore.Store) (uint64, error) {
+	return types.ResolveBundleSize(ctx, mergedBlocksStore, viper.GetUint64("common-merged-blocks-bundle-size"))
+}
+
+// CheckSubstreamsMergedBlocksStore returns an error if the bundle size of the merged blocks store at `storeURL` is not
+// [types.DefaultBundleSize]. Substreams creates its own merged blocks store from the URL, reading it directly with
+// bstream, so unlike Firehose and the index builder it cannot be given a store serving 100 blocks bundles.
+func CheckSubstreamsMergedBlocksStore(ctx context.Context, storeURL string) error {
+	store, err := encryptedstore.NewDBinStore(storeURL)
+	if err != nil {
+		return fmt.Errorf("unable to create merged blocks store at path %q: %w", storeURL, err)
+	}
+
+	bundleSize, err := GetMergedBlocksBundleSize(ctx, store)
+	if err != nil {
+		return fmt.Errorf("merged blocks bundle size: %w", err)
+	}
+
+	if bundleSize != types.DefaultBundleSize {
+		return fmt.Errorf("merged blocks store %q has a bundle size of %d, substreams only supports bundles of %d blocks", storeURL, bundleSize, types.DefaultBundleSize)
+	}
+
+	return nil
+}
+
+// LastMergedBlockNum returns the base block of the last merged bundle at or above `startBlockNum`, `startBlockNum`
+// if there is none, see [LastMergedBlockNumForBundleSize]
 func LastMergedBlockNum(ctx context.Context, startBlockNum uint64, store dstore.Store, logger *zap.Logger) uint64 {
-	value, err := searchBlockNum(startBlockNum, func(u uint64) (bool, error) {
+	return LastMergedBlockNumForBundleSize(ctx, startBlockNum, types.DefaultBundleSize, store, logger)
+}
+
+// LastMergedBlockNumForBundleSize is [LastMergedBlockNum] for a store whose bundle size is `bundleSize`. The head
+// pointer maintained by the merger is consulted first, the store being probed when it's missing or stale.
+func LastMergedBlockNumForBundleSize(ctx context.Context, startBlockNum uint64, bundleSize uint64, store dstore.Store, logger *zap.Logger) uint64 {
+	baseBlockNum, _ := lastMergedBundle(ctx, startBlockNum, bundleSize, store, logger)
+	return baseBlockNum
+}
+
+// LastMergedBlockRef returns the last block of the last merged bundle at or above `startBlockNum`, read from the
+// head pointer of the merged blocks store when it's up to date, from the sidecar of the bundle otherwise instead of
+// decoding it. It returns dstore.ErrNotFound if there is no such bundle or if the bundle has no sidecar, a nil
+// `sidecarStore` meaning that no bundle has one.
+func LastMergedBlockRef(ctx context.Context, startBlockNum uint64, bundleSize uint64, mergedBlocksStore, sidecarStore dstore.Store, logger *zap.Logger) (bstream.BlockRef, error) {
+	baseBlockNum, head := lastMergedBundle(ctx, types.RoundToBundleStartBlock(startBlockNum, bundleSize), bundleSize, mergedBlocksStore, logger)
+	if head != nil && head.LIBID != "" {
+		return bstream.NewBlockRef(head.LIBID, head.LIBNum), nil
+	}
+
+	if sidecarStore == nil {
+		return nil, fmt.Errorf("no sidecar store to read bundle #%d: %w", baseBlockNum, dstore.ErrNotFound)
+	}
+
+	bundleSidecar, err := sidecar.Read(ctx, sidecarStore, baseBlockNum)
+	if err != nil {
+		return nil, err
+	}
+
+	last := bundleSidecar.Last()
+	if last == nil {
+		return nil, fmt.Errorf("sidecar #%d lists no block: %w", baseBlockNum, dstore.ErrNotFound)
+	}
+
+	return bstream.NewBlockRef(last.ID, last.Num), nil
+}
+
+// lastMergedBundle returns the base block of the last merged bundle at or above `startBlockNum` along with the head
+// pointer of the store if it points to this bundle. The head pointer is trusted when the bundle following it does
+// not exist, the store is probed from the bundle it points to otherwise.
+func lastMergedBundle(ctx context.Context, startBlockNum uint64, bundleSize uint64, store dstore.Store, logger *zap.Logger) (uint64, *types.MergedBlocksStoreHead) {
+	head, err := types.ReadMergedBlocksStoreHead(ctx, store)
+	if err != nil {
+		logger.Warn("unable to read merged blocks store head, probing the store", zap.Error(err))
+	}
+
+	if head != nil && head.LastBundleBaseBlockNum >= startBlockNum {
+		next := head.LastBundleBaseBlockNum + bundleSize
+		found, err := store.FileExists(ctx, fmt.Sprintf("%010d", next))
+		if err == nil && !found {
+			return head.LastBundleBaseBlockNum, head
+		}
+
+		logger.Debug("merged blocks store head is stale, probing the store from it", zap.Uint64("head_base_block_num", head.LastBundleBaseBlockNum))
+		startBlockNum = head.LastBundleBaseBlockNum
+	}
+
+	value, err := searchBlockNum(startBlockNum, bundleSize, func(u uint64) (bool, error) {
 		filepath := fmt.Sprintf("%010d", u)
 		found, err := store.FileExists(ctx, filepath)
 		if err != nil {
@@ -100,14 +225,13 @@ func LastMergedBlockNum(ctx context.Context, startBlockNum uint64, store dstore.
 	})
 	if err != nil {
 		logger.Warn("failed to resolve block", zap.Error(err))
-		return startBlockNum
+		return startBlockNum, nil
 	}
-	return value
-
+	return value, nil
 }
 
-func searchBlockNum(startBlockNum uint64, f func(uint64) (bool, error)) (uint64, error) {
-	blockNum, err := blockNumIter(startBlockNum, 10_000_000_000, 1_000_000_000, f)
+func searchBlockNum(startBlockNum uint64, bundleSize uint64, f func(uint64) (bool, error)) (uint64, error) {
+	blockNum, err := blockNumIter(startBlockNum, 10_000_000_000, 1_000_000_000, bundleSize, f)
 	if err != nil {
 		return 0, err
 	}
@@ -117,7 +241,7 @@ func searchBlockNum(startBlockNum uint64, f func(uint64) (bool, error)) (uint64,
 	return blockNum, nil
 }
 
-func blockNumIter(startBlockNum, exclusiveEndBlockNum, interval uint64, f func(uint64) (bool, error)) (uint64, error) {
+func blockNumIter(startBlockNum, exclusiveEndBlockNum, interval, bundleSize uint64, f func(uint64) (bool, error)) (uint64, error) {
 	i := exclusiveEndBlockNum
 	for i >= startBlockNum {
 		i -= interval
@@ -126,10 +250,10 @@ func blockNumIter(startBlockNum, exclusiveEndBlockNum, interval uint64, f func(u
 			return 0, fmt.Errorf("failed to match blcok num %d: %w", i, err)
 		}
 		if match {
-			if interval == 100 {
+			if interval <= bundleSize {
 				return i, nil
 			}
-			return blockNumIter(i, i+interval, interval/10, f)
+			return blockNumIter(i, i+interval, interval/10, bundleSize, f)
 		}
 	}
 	return startBlockNum, nil
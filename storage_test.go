@@ -1,10 +1,15 @@
 package firecore
 
 import (
+	"bytes"
+	"context"
 	"testing"
 
+	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 func Test_searchBlockNum(t *testing.T) {
@@ -24,7 +29,7 @@ func Test_searchBlockNum(t *testing.T) {
 	for _, tt := range tests {
 		t.Run("", func(t *testing.T) {
 			dstoreOpt := 0
-			v, err := searchBlockNum(tt.startBlockNum, func(i uint64) (bool, error) {
+			v, err := searchBlockNum(tt.startBlockNum, 100, func(i uint64) (bool, error) {
 				dstoreOpt++
 				if tt.lastBlockNum == nil {
 					return false, nil
@@ -44,6 +49,40 @@ func Test_searchBlockNum(t *testing.T) {
 	}
 }
 
+func TestLastMergedBlockRef_HeadPointer(t *testing.T) {
+	ctx := context.Background()
+	store, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	for _, name := range []string{"0000000000", "0000000100", "0000000200"} {
+		require.NoError(t, store.WriteObject(ctx, name, bytes.NewReader(nil)))
+	}
+
+	// without head pointer nor sidecar, the last bundle is found by probing
+	assert.Equal(t, uint64(200), LastMergedBlockNumForBundleSize(ctx, 0, 100, store, zap.NewNop()))
+	_, err = LastMergedBlockRef(ctx, 0, 100, store, nil, zap.NewNop())
+	assert.ErrorIs(t, err, dstore.ErrNotFound)
+
+	headStore, err := types.NewMergedBlocksStoreHeadStore(ctx, store)
+	require.NoError(t, err)
+
+	// a stale head pointer is only used as the starting point of the probing
+	require.NoError(t, types.WriteMergedBlocksStoreHead(ctx, headStore, &types.MergedBlocksStoreHead{LastBundleBaseBlockNum: 100, LIBNum: 199, LIBID: "199a"}))
+	assert.Equal(t, uint64(200), LastMergedBlockNumForBundleSize(ctx, 0, 100, store, zap.NewNop()))
+	_, err = LastMergedBlockRef(ctx, 0, 100, store, nil, zap.NewNop())
+	assert.ErrorIs(t, err, dstore.ErrNotFound)
+
+	require.NoError(t, types.WriteMergedBlocksStoreHead(ctx, headStore, &types.MergedBlocksStoreHead{LastBundleBaseBlockNum: 200, LIBNum: 299, LIBID: "299a"}))
+	assert.Equal(t, uint64(200), LastMergedBlockNumForBundleSize(ctx, 0, 100, store, zap.NewNop()))
+	ref, err := LastMergedBlockRef(ctx, 0, 100, store, nil, zap.NewNop())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(299), ref.Num())
+	assert.Equal(t, "299a", ref.ID())
+
+	// a head pointer below the start block is ignored
+	assert.Equal(t, uint64(300), LastMergedBlockNumForBundleSize(ctx, 300, 100, store, zap.NewNop()))
+}
+
 func uptr(v uint64) *uint64 {
 	return &v
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/streamingfast/dmetering"
 
 	"github.com/streamingfast/firehose-core/metering"
+	"github.com/streamingfast/firehose-core/streamablestore"
 
 	"github.com/streamingfast/bstream"
 	"github.com/streamingfast/bstream/hub"
@@ -29,14 +30,16 @@ type StreamFactory struct {
 	transformRegistry *transform.Registry
 }
 
+// NewStreamFactory returns a factory of streams reading `mergedBlocksStore`, whose bundles hold `bundleSize` blocks
 func NewStreamFactory(
 	mergedBlocksStore dstore.Store,
+	bundleSize uint64,
 	forkedBlocksStore dstore.Store,
 	hub *hub.ForkableHub,
 	transformRegistry *transform.Registry,
 ) *StreamFactory {
 	return &StreamFactory{
-		mergedBlocksStore: mergedBlocksStore,
+		mergedBlocksStore: streamablestore.NewStore(mergedBlocksStore, bundleSize),
 		forkedBlocksStore: forkedBlocksStore,
 		hub:               hub,
 		transformRegistry: transformRegistry,
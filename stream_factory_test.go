@@ -0,0 +1,62 @@
+package firecore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/streamingfast/bstream"
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/streamingfast/bstream/stream"
+	"github.com/streamingfast/dstore"
+	pbfirehose "github.com/streamingfast/pbgo/sf/firehose/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestStreamFactory_NonDefaultBundleSize(t *testing.T) {
+	store, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	for base := uint64(0); base < 3000; base += 1000 {
+		buffer := new(bytes.Buffer)
+		writer, err := bstream.NewDBinBlockWriter(buffer)
+		require.NoError(t, err)
+
+		for num := base; num < base+1000; num++ {
+			require.NoError(t, writer.Write(&pbbstream.Block{
+				Number:    num,
+				Id:        fmt.Sprintf("%08da", num),
+				ParentId:  fmt.Sprintf("%08da", num-1),
+				ParentNum: num - 1,
+				LibNum:    num - 1,
+				Timestamp: timestamppb.Now(),
+				Payload:   &anypb.Any{TypeUrl: "type.googleapis.com/sf.test.Block"},
+			}))
+		}
+
+		require.NoError(t, store.WriteObject(context.Background(), fmt.Sprintf("%010d", base), buffer))
+	}
+
+	var streamed []uint64
+	handler := bstream.HandlerFunc(func(block *pbbstream.Block, _ interface{}) error {
+		streamed = append(streamed, block.Number)
+		return nil
+	})
+
+	factory := NewStreamFactory(store, 1000, nil, nil, nil)
+	str, err := factory.New(context.Background(), handler, &pbfirehose.Request{StartBlockNum: 950, StopBlockNum: 2049, FinalBlocksOnly: true}, zap.NewNop())
+	require.NoError(t, err)
+
+	err = str.Run(context.Background())
+	require.True(t, err == nil || errors.Is(err, stream.ErrStopBlockReached), "unexpected error: %v", err)
+
+	require.Len(t, streamed, 1100)
+	assert.EqualValues(t, 950, streamed[0])
+	assert.EqualValues(t, 2049, streamed[len(streamed)-1])
+}
@@ -0,0 +1,300 @@
+// Package streamablestore presents a merged blocks store of any bundle size as a store of 100 blocks bundles, the only
+// bundle size block streaming (bstream's file source and single block fetches) reads.
+package streamablestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/streamingfast/bstream"
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/types"
+)
+
+// Store serves each 100 blocks bundle from the bundles of the wrapped store holding its blocks: the start of a bigger
+// bundle or a sequence of smaller ones. Objects that are not bundles are read from the wrapped store, which also
+// receives all the other operations (writes, walks).
+//
+// Bundles bigger than 100 blocks are decoded once for all of their 100 blocks bundles, the last
+// [maxCachedSourceBundles] decoded bundles being kept in memory.
+type Store struct {
+	dstore.Store
+
+	bundleSize uint64
+
+	cacheLock sync.Mutex
+	// cached holds the most recently used source bundle last
+	cached []*sourceBundle
+}
+
+// maxCachedSourceBundles bounds the decoded bundles kept in memory, more than one so that streams reading the next
+// bundle ahead of time do not evict the one still being read
+const maxCachedSourceBundles = 2
+
+// sourceBundle is a decoded bundle of the wrapped store, `blocks` and `err` being set once `loaded` is closed
+type sourceBundle struct {
+	baseBlockNum uint64
+	loaded       chan struct{}
+	blocks       []*pbbstream.Block
+	err          error
+}
+
+// NewStore returns `store` itself when its bundle size is [types.DefaultBundleSize], a [Store] otherwise
+func NewStore(store dstore.Store, bundleSize uint64) dstore.Store {
+	if bundleSize == types.DefaultBundleSize {
+		return store
+	}
+
+	return &Store{Store: store, bundleSize: bundleSize}
+}
+
+// sourceBundles returns the base blocks of the bundles of the wrapped store holding the blocks of the 100 blocks bundle
+// starting at `baseBlockNum`, bundle sizes being powers of 10
+func (s *Store) sourceBundles(baseBlockNum uint64) []uint64 {
+	if s.bundleSize > types.DefaultBundleSize {
+		return []uint64{baseBlockNum - baseBlockNum%s.bundleSize}
+	}
+
+	var out []uint64
+	for base := baseBlockNum; base < baseBlockNum+types.DefaultBundleSize; base += s.bundleSize {
+		out = append(out, base)
+	}
+	return out
+}
+
+func (s *Store) OpenObject(ctx context.Context, name string) (io.ReadCloser, error) {
+	baseBlockNum, err := strconv.ParseUint(name, 10, 64)
+	if err != nil {
+		return s.Store.OpenObject(ctx, name)
+	}
+
+	bundles := s.sourceBundles(baseBlockNum)
+	if len(bundles) > 1 {
+		// bundles are written in order, the 100 blocks bundle is complete once its last source bundle exists
+		exists, err := s.Store.FileExists(ctx, bundleFilename(bundles[len(bundles)-1]))
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, fmt.Errorf("bundle %s is not complete: %w", name, dstore.ErrNotFound)
+		}
+	}
+
+	if s.bundleSize > types.DefaultBundleSize {
+		source, err := s.sourceBundle(ctx, bundles[0])
+		if err != nil {
+			return nil, err
+		}
+
+		reader, writer := io.Pipe()
+		go func() {
+			writer.CloseWithError(writeBlocks(writer, source.blocks, baseBlockNum, baseBlockNum+types.DefaultBundleSize))
+		}()
+
+		return reader, nil
+	}
+
+	first, err := s.Store.OpenObject(ctx, bundleFilename(bundles[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(s.copyBlocks(ctx, writer, first, bundles[1:], baseBlockNum))
+	}()
+
+	return reader, nil
+}
+
+// sourceBundle returns the decoded bundle at `baseBlockNum` of the wrapped store, reading it unless it's cached or
+// being read. The bundle is read regardless of `ctx` being canceled as concurrent callers wait for it, a failed read
+// is not cached.
+func (s *Store) sourceBundle(ctx context.Context, baseBlockNum uint64) (*sourceBundle, error) {
+	s.cacheLock.Lock()
+	for i, cached := range s.cached {
+		if cached.baseBlockNum == baseBlockNum {
+			s.cached = append(append(s.cached[:i:i], s.cached[i+1:]...), cached)
+			s.cacheLock.Unlock()
+
+			return cached.wait(ctx)
+		}
+	}
+
+	source := &sourceBundle{baseBlockNum: baseBlockNum, loaded: make(chan struct{})}
+	s.cached = append(s.cached, source)
+	if len(s.cached) > maxCachedSourceBundles {
+		s.cached = s.cached[len(s.cached)-maxCachedSourceBundles:]
+	}
+	s.cacheLock.Unlock()
+
+	go func() {
+		source.blocks, source.err = s.readBundle(context.WithoutCancel(ctx), baseBlockNum)
+		if source.err != nil {
+			s.evict(source)
+		}
+		close(source.loaded)
+	}()
+
+	return source.wait(ctx)
+}
+
+func (s *Store) readBundle(ctx context.Context, baseBlockNum uint64) (out []*pbbstream.Block, err error) {
+	reader, err := s.Store.OpenObject(ctx, bundleFilename(baseBlockNum))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	blockReader, err := bstream.NewDBinBlockReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("new block reader: %w", err)
+	}
+
+	for {
+		block, err := blockReader.Read()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read bundle %s: %w", bundleFilename(baseBlockNum), err)
+		}
+
+		out = append(out, block)
+	}
+}
+
+func (s *Store) evict(source *sourceBundle) {
+	s.cacheLock.Lock()
+	defer s.cacheLock.Unlock()
+
+	for i, cached := range s.cached {
+		if cached == source {
+			s.cached = append(s.cached[:i:i], s.cached[i+1:]...)
+			return
+		}
+	}
+}
+
+func (b *sourceBundle) wait(ctx context.Context) (*sourceBundle, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-b.loaded:
+		if b.err != nil {
+			return nil, b.err
+		}
+		return b, nil
+	}
+}
+
+// writeBlocks writes the blocks of `blocks` in the range [start, stop[ to `out`
+func writeBlocks(out io.Writer, blocks []*pbbstream.Block, start, stop uint64) error {
+	writer, err := bstream.NewDBinBlockWriter(out)
+	if err != nil {
+		return fmt.Errorf("new block writer: %w", err)
+	}
+
+	for _, block := range blocks {
+		if block.Number >= start && block.Number < stop {
+			if err := writer.Write(block); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// copyBlocks writes the blocks of the 100 blocks bundle starting at `baseBlockNum` to `out`, read from `first` then
+// from the bundles `next`
+func (s *Store) copyBlocks(ctx context.Context, out io.Writer, first io.ReadCloser, next []uint64, baseBlockNum uint64) error {
+	source := first
+	writer, err := bstream.NewDBinBlockWriter(out)
+	if err != nil {
+		source.Close()
+		return fmt.Errorf("new block writer: %w", err)
+	}
+
+	for {
+		done, err := copyBundleBlocks(writer, source, baseBlockNum, baseBlockNum+types.DefaultBundleSize)
+		source.Close()
+		if err != nil || done || len(next) == 0 {
+			return err
+		}
+
+		source, err = s.Store.OpenObject(ctx, bundleFilename(next[0]))
+		if err != nil {
+			return err
+		}
+		next = next[1:]
+	}
+}
+
+// copyBundleBlocks writes the blocks of the bundle read from `source` in the range [start, stop[, returning true once a
+// block past the range was read as bundles hold blocks in ascending order
+func copyBundleBlocks(writer *bstream.DBinBlockWriter, source io.Reader, start, stop uint64) (done bool, err error) {
+	reader, err := bstream.NewDBinBlockReader(source)
+	if err != nil {
+		return false, fmt.Errorf("new block reader: %w", err)
+	}
+
+	for {
+		block, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+
+		if block.Number >= stop {
+			return true, nil
+		}
+
+		if block.Number >= start {
+			if err := writer.Write(block); err != nil {
+				return false, err
+			}
+		}
+	}
+}
+
+// Unwrap returns the wrapped store, whose bundles have the store's bundle size
+func (s *Store) Unwrap() dstore.Store {
+	return s.Store
+}
+
+func (s *Store) FileExists(ctx context.Context, base string) (bool, error) {
+	baseBlockNum, err := strconv.ParseUint(base, 10, 64)
+	if err != nil {
+		return s.Store.FileExists(ctx, base)
+	}
+
+	bundles := s.sourceBundles(baseBlockNum)
+	return s.Store.FileExists(ctx, bundleFilename(bundles[len(bundles)-1]))
+}
+
+// Clone clones the wrapped store if it's clonable, the clone serving 100 blocks bundles too
+func (s *Store) Clone(ctx context.Context, opts ...dstore.Option) (dstore.Store, error) {
+	clonable, ok := s.Store.(dstore.Clonable)
+	if !ok {
+		return s, nil
+	}
+
+	store, err := clonable.Clone(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStore(store, s.bundleSize), nil
+}
+
+func bundleFilename(baseBlockNum uint64) string {
+	return fmt.Sprintf("%010d", baseBlockNum)
+}
@@ -0,0 +1,156 @@
+package streamablestore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/streamingfast/bstream"
+	pbbstream "github.com/streamingfast/bstream/pb/sf/bstream/v1"
+	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func writeBundles(t *testing.T, store dstore.Store, bundleSize, start, stop uint64) {
+	t.Helper()
+
+	for base := start; base < stop; base += bundleSize {
+		buffer := new(bytes.Buffer)
+		writer, err := bstream.NewDBinBlockWriter(buffer)
+		require.NoError(t, err)
+
+		for num := base; num < base+bundleSize; num++ {
+			require.NoError(t, writer.Write(&pbbstream.Block{
+				Number:    num,
+				Id:        fmt.Sprintf("%08da", num),
+				ParentId:  fmt.Sprintf("%08da", num-1),
+				ParentNum: num - 1,
+				Timestamp: timestamppb.Now(),
+				Payload:   &anypb.Any{TypeUrl: "type.googleapis.com/sf.test.Block"},
+			}))
+		}
+
+		require.NoError(t, store.WriteObject(context.Background(), fmt.Sprintf("%010d", base), buffer))
+	}
+}
+
+func readBlockNums(t *testing.T, store dstore.Store, name string) (out []uint64) {
+	t.Helper()
+
+	reader, err := store.OpenObject(context.Background(), name)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	blockReader, err := bstream.NewDBinBlockReader(reader)
+	require.NoError(t, err)
+
+	for {
+		block, err := blockReader.Read()
+		if err == io.EOF {
+			return
+		}
+		require.NoError(t, err)
+
+		out = append(out, block.Number)
+	}
+}
+
+func blockNums(start, stop uint64) (out []uint64) {
+	for num := start; num < stop; num++ {
+		out = append(out, num)
+	}
+	return
+}
+
+func TestStore_ServesStreamBundles(t *testing.T) {
+	for _, bundleSize := range []uint64{10, 1000} {
+		t.Run(fmt.Sprintf("bundle size %d", bundleSize), func(t *testing.T) {
+			source, err := dstore.NewDBinStore(t.TempDir())
+			require.NoError(t, err)
+			writeBundles(t, source, bundleSize, 0, 2000)
+
+			store := NewStore(source, bundleSize)
+
+			assert.Equal(t, blockNums(0, 100), readBlockNums(t, store, "0000000000"))
+			assert.Equal(t, blockNums(1100, 1200), readBlockNums(t, store, "0000001100"))
+			assert.Equal(t, blockNums(1900, 2000), readBlockNums(t, store, "0000001900"))
+
+			exists, err := store.FileExists(context.Background(), "0000001900")
+			require.NoError(t, err)
+			assert.True(t, exists)
+
+			exists, err = store.FileExists(context.Background(), "0000002000")
+			require.NoError(t, err)
+			assert.False(t, exists)
+
+			_, err = store.OpenObject(context.Background(), "0000002000")
+			assert.ErrorIs(t, err, dstore.ErrNotFound)
+		})
+	}
+}
+
+type countingStore struct {
+	dstore.Store
+
+	opens atomic.Int64
+}
+
+func (s *countingStore) OpenObject(ctx context.Context, name string) (io.ReadCloser, error) {
+	s.opens.Add(1)
+	return s.Store.OpenObject(ctx, name)
+}
+
+func TestStore_ReadsBiggerBundlesOnce(t *testing.T) {
+	local, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+	writeBundles(t, local, 1000, 0, 3000)
+
+	source := &countingStore{Store: local}
+	store := NewStore(source, 1000)
+
+	for base := uint64(1000); base < 2000; base += 100 {
+		assert.Equal(t, blockNums(base, base+100), readBlockNums(t, store, fmt.Sprintf("%010d", base)))
+	}
+	assert.Equal(t, int64(1), source.opens.Load())
+
+	// the previous bundle stays cached while the next one is read
+	assert.Equal(t, blockNums(2000, 2100), readBlockNums(t, store, "0000002000"))
+	assert.Equal(t, blockNums(1900, 2000), readBlockNums(t, store, "0000001900"))
+	assert.Equal(t, int64(2), source.opens.Load())
+
+	// a failed read is not cached
+	_, err = store.OpenObject(context.Background(), "0000003000")
+	assert.ErrorIs(t, err, dstore.ErrNotFound)
+	writeBundles(t, local, 1000, 3000, 4000)
+	assert.Equal(t, blockNums(3000, 3100), readBlockNums(t, store, "0000003000"))
+}
+
+func TestStore_IncompleteStreamBundle(t *testing.T) {
+	source, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+	writeBundles(t, source, 10, 0, 150)
+
+	store := NewStore(source, 10)
+
+	exists, err := store.FileExists(context.Background(), "0000000100")
+	require.NoError(t, err)
+	assert.False(t, exists, "bundles 150 to 190 are missing")
+
+	_, err = store.OpenObject(context.Background(), "0000000100")
+	assert.ErrorIs(t, err, dstore.ErrNotFound)
+}
+
+func TestNewStore_DefaultBundleSize(t *testing.T) {
+	source, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	assert.Same(t, source, NewStore(source, types.DefaultBundleSize))
+	assert.IsType(t, &Store{}, NewStore(source, 1000))
+}
@@ -0,0 +1,167 @@
+// Package tieredstore reads merged blocks bundles from an ordered list of merged blocks stores, for example recent
+// history on fast disks and old history in cold archive storage.
+package tieredstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/metering"
+	"github.com/streamingfast/firehose-core/types"
+)
+
+// Tier is a merged blocks store holding the bundles of `Range`, unbounded if its start is 0 and it has no stop block
+type Tier struct {
+	// Name identifies the tier in the metering events
+	Name  string
+	Store dstore.Store
+	// Range is the range of blocks of the bundles the tier holds, its stop block being exclusive
+	Range types.BlockRange
+}
+
+// serves returns true if the bundle starting at `baseBlockNum` has blocks in the range of the tier
+func (t Tier) serves(baseBlockNum, bundleSize uint64) bool {
+	if baseBlockNum+bundleSize <= uint64(t.Range.Start) {
+		return false
+	}
+
+	return t.Range.Stop == nil || baseBlockNum < *t.Range.Stop
+}
+
+// Store reads each bundle from the first tier holding it, trying the tiers in order and falling through when the bundle
+// is not found. Other objects are read from the first tier, which also receives all the other operations (writes,
+// walks).
+type Store struct {
+	dstore.Store
+
+	tiers      []Tier
+	bundleSize uint64
+}
+
+func NewStore(bundleSize uint64, tiers ...Tier) (*Store, error) {
+	if len(tiers) == 0 {
+		return nil, fmt.Errorf("a tiered merged blocks store needs at least one tier")
+	}
+
+	seen := map[string]bool{}
+	for _, tier := range tiers {
+		if tier.Range.Start < 0 {
+			return nil, fmt.Errorf("tier %q: range %s cannot start at a relative block", tier.Name, tier.Range)
+		}
+
+		if seen[tier.Name] {
+			return nil, fmt.Errorf("tier %q is defined twice", tier.Name)
+		}
+		seen[tier.Name] = true
+	}
+
+	return &Store{
+		Store:      tiers[0].Store,
+		tiers:      tiers,
+		bundleSize: bundleSize,
+	}, nil
+}
+
+func (s *Store) OpenObject(ctx context.Context, name string) (io.ReadCloser, error) {
+	baseBlockNum, err := strconv.ParseUint(name, 10, 64)
+	if err != nil {
+		return s.Store.OpenObject(ctx, name)
+	}
+
+	err = fmt.Errorf("no tier holds bundle %s: %w", name, dstore.ErrNotFound)
+	for _, tier := range s.tiers {
+		if !tier.serves(baseBlockNum, s.bundleSize) {
+			continue
+		}
+
+		var reader io.ReadCloser
+		reader, err = tier.Store.OpenObject(metering.WithMergedBlocksTier(ctx, tier.Name), name)
+		if err == nil {
+			return reader, nil
+		}
+
+		if !errors.Is(err, dstore.ErrNotFound) {
+			return nil, fmt.Errorf("tier %q: %w", tier.Name, err)
+		}
+	}
+
+	return nil, err
+}
+
+func (s *Store) FileExists(ctx context.Context, base string) (bool, error) {
+	baseBlockNum, err := strconv.ParseUint(base, 10, 64)
+	if err != nil {
+		return s.Store.FileExists(ctx, base)
+	}
+
+	for _, tier := range s.tiers {
+		if !tier.serves(baseBlockNum, s.bundleSize) {
+			continue
+		}
+
+		exists, err := tier.Store.FileExists(ctx, base)
+		if err != nil {
+			return false, fmt.Errorf("tier %q: %w", tier.Name, err)
+		}
+
+		if exists {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// Clone clones each tier that is clonable with `opts`
+func (s *Store) Clone(ctx context.Context, opts ...dstore.Option) (dstore.Store, error) {
+	tiers := make([]Tier, len(s.tiers))
+	for i, tier := range s.tiers {
+		tiers[i] = tier
+		if clonable, ok := tier.Store.(dstore.Clonable); ok {
+			store, err := clonable.Clone(ctx, opts...)
+			if err != nil {
+				return nil, fmt.Errorf("clone tier %q: %w", tier.Name, err)
+			}
+			tiers[i].Store = store
+		}
+	}
+
+	return NewStore(s.bundleSize, tiers...)
+}
+
+// ParseTierURL splits a tier definition, a merged blocks store URL optionally followed by a fragment giving the name
+// of the tier and the range of blocks it holds, like 'gs://bucket/merged-blocks#name=cold&range=0:15000000'. The name
+// defaults to `defaultName` and the range to all blocks.
+func ParseTierURL(in string, defaultName string) (storeURL string, name string, blockRange types.BlockRange, err error) {
+	storeURL, fragment, _ := strings.Cut(in, "#")
+	if storeURL == "" {
+		return "", "", blockRange, fmt.Errorf("merged blocks store tier %q has no store URL", in)
+	}
+
+	values, err := url.ParseQuery(fragment)
+	if err != nil {
+		return "", "", blockRange, fmt.Errorf("invalid merged blocks store tier fragment %q: %w", fragment, err)
+	}
+
+	name = values.Get("name")
+	if name == "" {
+		name = defaultName
+	}
+
+	blockRange, err = types.ParseBlockRangeDefault(values.Get("range"), 0, types.NewOpenRange(0))
+	if err != nil {
+		return "", "", blockRange, fmt.Errorf("merged blocks store tier %q range: %w", name, err)
+	}
+
+	if blockRange.Start < 0 {
+		return "", "", blockRange, fmt.Errorf("merged blocks store tier %q range %s cannot start at a relative block", name, blockRange)
+	}
+
+	return storeURL, name, blockRange, nil
+}
@@ -0,0 +1,145 @@
+package tieredstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/streamingfast/dmetering"
+	"github.com/streamingfast/dstore"
+	"github.com/streamingfast/firehose-core/metering"
+	"github.com/streamingfast/firehose-core/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func newTierStore(t *testing.T, names ...string) dstore.Store {
+	t.Helper()
+
+	store, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	for _, name := range names {
+		require.NoError(t, store.WriteObject(context.Background(), name, bytes.NewReader([]byte("bundle "+name))))
+	}
+	return store
+}
+
+func readAll(t *testing.T, store dstore.Store, name string) string {
+	t.Helper()
+
+	reader, err := store.OpenObject(context.Background(), name)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func TestStore_FallsThroughTiers(t *testing.T) {
+	hot := newTierStore(t, "0000000200", ".head.json")
+	cold := newTierStore(t, "0000000100", "0000000200", ".head.json")
+
+	store, err := NewStore(100,
+		Tier{Name: "hot", Store: hot, Range: types.NewOpenRange(0)},
+		Tier{Name: "cold", Store: cold, Range: types.NewOpenRange(0)},
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "bundle 0000000100", readAll(t, store, "0000000100"))
+	assert.Equal(t, "bundle 0000000200", readAll(t, store, "0000000200"))
+
+	require.NoError(t, hot.DeleteObject(context.Background(), ".head.json"))
+	_, err = store.OpenObject(context.Background(), ".head.json")
+	assert.ErrorIs(t, err, dstore.ErrNotFound, "other objects are only read from the first tier")
+
+	_, err = store.OpenObject(context.Background(), "0000000300")
+	assert.ErrorIs(t, err, dstore.ErrNotFound)
+
+	exists, err := store.FileExists(context.Background(), "0000000100")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = store.FileExists(context.Background(), "0000000300")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestStore_SkipsTiersOutOfRange(t *testing.T) {
+	hot := newTierStore(t, "0000000100", "0000000200")
+	cold := newTierStore(t, "0000000100", "0000000200")
+	require.NoError(t, hot.DeleteObject(context.Background(), "0000000100"))
+
+	store, err := NewStore(100,
+		Tier{Name: "hot", Store: hot, Range: types.NewOpenRange(200)},
+		Tier{Name: "cold", Store: cold, Range: types.NewClosedRange(0, 200)},
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, "bundle 0000000100", readAll(t, store, "0000000100"))
+	assert.Equal(t, "bundle 0000000200", readAll(t, store, "0000000200"))
+
+	require.NoError(t, hot.DeleteObject(context.Background(), "0000000200"))
+	_, err = store.OpenObject(context.Background(), "0000000200")
+	assert.ErrorIs(t, err, dstore.ErrNotFound, "cold tier only holds bundles below block 200")
+}
+
+func TestStore_MetersReadsPerTier(t *testing.T) {
+	hot := newTierStore(t, "0000000200")
+	cold := newTierStore(t, "0000000100")
+
+	tiered, err := NewStore(100,
+		Tier{Name: "hot", Store: hot, Range: types.NewOpenRange(0)},
+		Tier{Name: "cold", Store: cold, Range: types.NewOpenRange(0)},
+	)
+	require.NoError(t, err)
+
+	meter := dmetering.NewBytesMeter()
+	store, err := tiered.Clone(context.Background(), metering.WithBlockBytesReadMeteringOptions(meter, zap.NewNop())...)
+	require.NoError(t, err)
+
+	readAll(t, store, "0000000100")
+	coldBytes := meter.GetCount(metering.MeterFileCompressedReadTierBytesPrefix + "cold")
+	assert.NotZero(t, coldBytes)
+	assert.Zero(t, meter.GetCount(metering.MeterFileCompressedReadTierBytesPrefix+"hot"))
+
+	readAll(t, store, "0000000200")
+	hotBytes := meter.GetCount(metering.MeterFileCompressedReadTierBytesPrefix + "hot")
+	assert.NotZero(t, hotBytes)
+	assert.Equal(t, coldBytes+hotBytes, meter.GetCount(metering.MeterFileCompressedReadBytes))
+}
+
+func TestParseTierURL(t *testing.T) {
+	tests := []struct {
+		in            string
+		wantStoreURL  string
+		wantName      string
+		wantRange     types.BlockRange
+		wantErrString string
+	}{
+		{"gs://bucket/merged", "gs://bucket/merged", "tier-1", types.NewOpenRange(0), ""},
+		{"gs://bucket/merged#name=cold", "gs://bucket/merged", "cold", types.NewOpenRange(0), ""},
+		{"gs://bucket/merged#name=cold&range=0:15000000", "gs://bucket/merged", "cold", types.NewClosedRange(0, 15000000), ""},
+		{"/data/merged#range=15000000:", "/data/merged", "tier-1", types.NewOpenRange(15000000), ""},
+		{"#name=cold", "", "", types.BlockRange{}, `merged blocks store tier "#name=cold" has no store URL`},
+		{"gs://bucket/merged#range=-100:", "", "", types.BlockRange{}, `merged blocks store tier "tier-1" range [HEAD - 100, +∞] cannot start at a relative block`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			storeURL, name, blockRange, err := ParseTierURL(tt.in, "tier-1")
+			if tt.wantErrString != "" {
+				require.EqualError(t, err, tt.wantErrString)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStoreURL, storeURL)
+			assert.Equal(t, tt.wantName, name)
+			assert.Equal(t, tt.wantRange, blockRange)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package types
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/streamingfast/dstore"
+)
+
+// DefaultBundleSize is the number of blocks in a merged blocks bundle of a store not defining it in its metadata
+const DefaultBundleSize uint64 = 100
+
+// MergedBlocksStoreMetadataFilename is the name of the object holding [MergedBlocksStoreMetadata] at the root of
+// a merged blocks store. It starts with a dot so that it sorts before bundles when walking from a block number.
+const MergedBlocksStoreMetadataFilename = ".metadata"
+
+// MergedBlocksStoreMetadata holds the properties of a merged blocks store that all its readers and writers
+// must agree on
+type MergedBlocksStoreMetadata struct {
+	BundleSize uint64 `json:"bundle_size"`
+}
+
+// ValidateBundleSize checks that the bundle size is a power of 10, at least 10, so that bundle boundaries align
+// with index sizes and the decimal search for the last merged bundle
+func ValidateBundleSize(bundleSize uint64) error {
+	if bundleSize < 10 {
+		return fmt.Errorf("invalid bundle size %d: must be at least 10", bundleSize)
+	}
+
+	for size := bundleSize; size > 1; size /= 10 {
+		if size%10 != 0 {
+			return fmt.Errorf("invalid bundle size %d: must be a power of 10", bundleSize)
+		}
+	}
+
+	return nil
+}
+
+// ReadMergedBlocksStoreMetadata returns the metadata of the merged blocks store, nil if the store has none
+func ReadMergedBlocksStoreMetadata(ctx context.Context, store dstore.Store) (*MergedBlocksStoreMetadata, error) {
+	reader, err := store.OpenObject(ctx, MergedBlocksStoreMetadataFilename)
+	if err != nil {
+		if errors.Is(err, dstore.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("open merged blocks store metadata: %w", err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read merged blocks store metadata: %w", err)
+	}
+
+	metadata := &MergedBlocksStoreMetadata{}
+	if err := json.Unmarshal(content, metadata); err != nil {
+		return nil, fmt.Errorf("unmarshal merged blocks store metadata: %w", err)
+	}
+
+	if err := ValidateBundleSize(metadata.BundleSize); err != nil {
+		return nil, fmt.Errorf("merged blocks store metadata: %w", err)
+	}
+
+	return metadata, nil
+}
+
+func WriteMergedBlocksStoreMetadata(ctx context.Context, store dstore.Store, metadata *MergedBlocksStoreMetadata) error {
+	content, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("marshal merged blocks store metadata: %w", err)
+	}
+
+	if err := store.WriteObject(ctx, MergedBlocksStoreMetadataFilename, bytes.NewReader(content)); err != nil {
+		return fmt.Errorf("write merged blocks store metadata: %w", err)
+	}
+
+	return nil
+}
+
+// ResolveBundleSize returns the bundle size of the merged blocks store from its metadata. When the store has
+// none, `configured` is returned, [DefaultBundleSize] if it's 0. It's an error for `configured` to be non-zero
+// and different from the bundle size of the store.
+func ResolveBundleSize(ctx context.Context, store dstore.Store, configured uint64) (uint64, error) {
+	metadata, err := ReadMergedBlocksStoreMetadata(ctx, store)
+	if err != nil {
+		return 0, err
+	}
+
+	if metadata == nil {
+		if configured == 0 {
+			return DefaultBundleSize, nil
+		}
+
+		return configured, ValidateBundleSize(configured)
+	}
+
+	if configured != 0 && configured != metadata.BundleSize {
+		return 0, fmt.Errorf("configured bundle size %d does not match bundle size %d of the merged blocks store", configured, metadata.BundleSize)
+	}
+
+	return metadata.BundleSize, nil
+}
+
+// EnsureBundleSize resolves the bundle size like [ResolveBundleSize] and writes it to the store's metadata if the
+// store has none and a non-default bundle size is configured, used by the merged blocks writers. A store without
+// metadata but already containing bundles holds [DefaultBundleSize] bundles and cannot change its bundle size.
+func EnsureBundleSize(ctx context.Context, store dstore.Store, configured uint64) (uint64, error) {
+	metadata, err := ReadMergedBlocksStoreMetadata(ctx, store)
+	if err != nil {
+		return 0, err
+	}
+
+	if metadata != nil || configured == 0 || configured == DefaultBundleSize {
+		return ResolveBundleSize(ctx, store, configured)
+	}
+
+	if err := ValidateBundleSize(configured); err != nil {
+		return 0, err
+	}
+
+	hasBundles := false
+	if err := store.Walk(ctx, "", func(filename string) error {
+		if IsMergedBlocksStoreMetadataObject(filename) {
+			return nil
+		}
+
+		hasBundles = true
+		return dstore.StopIteration
+	}); err != nil {
+		return 0, fmt.Errorf("list merged blocks store: %w", err)
+	}
+
+	if hasBundles {
+		return 0, fmt.Errorf("configured bundle size %d does not match bundle size %d of the merged blocks store which already contains bundles but no metadata", configured, DefaultBundleSize)
+	}
+
+	if err := WriteMergedBlocksStoreMetadata(ctx, store, &MergedBlocksStoreMetadata{BundleSize: configured}); err != nil {
+		return 0, err
+	}
+
+	return configured, nil
+}
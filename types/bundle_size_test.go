@@ -0,0 +1,111 @@
+package types
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/streamingfast/dstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateBundleSize(t *testing.T) {
+	tests := []struct {
+		bundleSize uint64
+		expectErr  bool
+	}{
+		{0, true},
+		{1, true},
+		{10, false},
+		{100, false},
+		{1000, false},
+		{250, true},
+		{110, true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateBundleSize(tt.bundleSize)
+		if tt.expectErr {
+			assert.Error(t, err, "bundle size %d", tt.bundleSize)
+		} else {
+			assert.NoError(t, err, "bundle size %d", tt.bundleSize)
+		}
+	}
+}
+
+func TestResolveBundleSize(t *testing.T) {
+	ctx := context.Background()
+
+	store := newTestMergedBlocksStore(t)
+	size, err := ResolveBundleSize(ctx, store, 0)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultBundleSize, size)
+
+	size, err = ResolveBundleSize(ctx, store, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1000), size)
+
+	require.NoError(t, WriteMergedBlocksStoreMetadata(ctx, store, &MergedBlocksStoreMetadata{BundleSize: 1000}))
+
+	size, err = ResolveBundleSize(ctx, store, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1000), size)
+
+	_, err = ResolveBundleSize(ctx, store, 100)
+	assert.Error(t, err)
+}
+
+func TestEnsureBundleSize(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("new store", func(t *testing.T) {
+		store := newTestMergedBlocksStore(t)
+
+		size, err := EnsureBundleSize(ctx, store, 1000)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1000), size)
+
+		metadata, err := ReadMergedBlocksStoreMetadata(ctx, store)
+		require.NoError(t, err)
+		require.NotNil(t, metadata)
+		assert.Equal(t, uint64(1000), metadata.BundleSize)
+
+		size, err = EnsureBundleSize(ctx, store, 0)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1000), size)
+	})
+
+	t.Run("default size writes no metadata", func(t *testing.T) {
+		store := newTestMergedBlocksStore(t)
+
+		size, err := EnsureBundleSize(ctx, store, 0)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultBundleSize, size)
+
+		metadata, err := ReadMergedBlocksStoreMetadata(ctx, store)
+		require.NoError(t, err)
+		assert.Nil(t, metadata)
+	})
+
+	t.Run("existing bundles without metadata", func(t *testing.T) {
+		store := newTestMergedBlocksStore(t)
+		require.NoError(t, store.WriteObject(ctx, "0000000100", strings.NewReader("bundle")))
+
+		_, err := EnsureBundleSize(ctx, store, 1000)
+		assert.Error(t, err)
+
+		size, err := EnsureBundleSize(ctx, store, 100)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultBundleSize, size)
+	})
+}
+
+func newTestMergedBlocksStore(t *testing.T) dstore.Store {
+	t.Helper()
+
+	store, err := dstore.NewDBinStore(t.TempDir())
+	require.NoError(t, err)
+
+	return store
+}
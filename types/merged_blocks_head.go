@@ -0,0 +1,95 @@
+package types
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/streamingfast/dstore"
+)
+
+// MergedBlocksStoreHeadFilename is the name of the object holding [MergedBlocksStoreHead] at the root of a merged
+// blocks store. Like [MergedBlocksStoreMetadataFilename], it starts with a dot so that it sorts before bundles.
+const MergedBlocksStoreHeadFilename = ".head.json"
+
+// MergedBlocksStoreHead points to the last bundle of a merged blocks store, maintained by the merger and the reader
+// node catch-up bundles so that readers find the head of the store without probing it. It's only a hint: other writers
+// (tools, mirrors) do not update it and it's written after its bundle, readers must check that the next bundle does
+// not exist.
+type MergedBlocksStoreHead struct {
+	LastBundleBaseBlockNum uint64 `json:"last_bundle_base_block_num"`
+	// LIBNum and LIBID are the last block of the last bundle
+	LIBNum    uint64    `json:"lib_num"`
+	LIBID     string    `json:"lib_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// IsMergedBlocksStoreMetadataObject returns true for the objects of a merged blocks store that are not bundles
+func IsMergedBlocksStoreMetadataObject(filename string) bool {
+	return filename == MergedBlocksStoreMetadataFilename || filename == MergedBlocksStoreHeadFilename
+}
+
+// ReadMergedBlocksStoreHead returns the head pointer of the merged blocks store, nil if the store has none
+func ReadMergedBlocksStoreHead(ctx context.Context, store dstore.Store) (*MergedBlocksStoreHead, error) {
+	reader, err := store.OpenObject(ctx, MergedBlocksStoreHeadFilename)
+	if err != nil {
+		if errors.Is(err, dstore.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("open merged blocks store head: %w", err)
+	}
+	defer reader.Close()
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("read merged blocks store head: %w", err)
+	}
+
+	head := &MergedBlocksStoreHead{}
+	if err := json.Unmarshal(content, head); err != nil {
+		return nil, fmt.Errorf("unmarshal merged blocks store head: %w", err)
+	}
+
+	return head, nil
+}
+
+// WriteMergedBlocksStoreHead replaces the head pointer of the merged blocks store, `store` must allow overwrites
+// (see [NewMergedBlocksStoreHeadStore])
+func WriteMergedBlocksStoreHead(ctx context.Context, store dstore.Store, head *MergedBlocksStoreHead) error {
+	content, err := json.Marshal(head)
+	if err != nil {
+		return fmt.Errorf("marshal merged blocks store head: %w", err)
+	}
+
+	if err := store.WriteObject(ctx, MergedBlocksStoreHeadFilename, bytes.NewReader(content)); err != nil {
+		return fmt.Errorf("write merged blocks store head: %w", err)
+	}
+
+	return nil
+}
+
+// NewMergedBlocksStoreHeadStore returns a store writing to the same location as `mergedBlocksStore` but overwriting
+// existing objects, merged blocks stores silently skipping writes of existing bundles
+func NewMergedBlocksStoreHeadStore(ctx context.Context, mergedBlocksStore dstore.Store) (dstore.Store, error) {
+	if mergedBlocksStore.Overwrite() {
+		return mergedBlocksStore, nil
+	}
+
+	clonable, ok := mergedBlocksStore.(dstore.Clonable)
+	if !ok {
+		return nil, fmt.Errorf("merged blocks store %s cannot be cloned to overwrite its head", mergedBlocksStore.BaseURL().Redacted())
+	}
+
+	store, err := clonable.Clone(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("clone merged blocks store: %w", err)
+	}
+	store.SetOverwrite(true)
+
+	return store, nil
+}